@@ -0,0 +1,356 @@
+/*
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 The Nodevin Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+// Package bundle writes and reads the archives of `nodevin bundle`, which carry the
+// images of one or more deployments (as `docker save` would write them), a nodevin
+// executable and a manifest to hosts without internet access. Every file in a bundle
+// is listed with its SHA-256 in SHA256SUMS and verified before anything is loaded.
+package bundle
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fiftysixcrypto/nodevin/internal/utils"
+)
+
+const (
+	ManifestName  = "manifest.json"
+	ImagesName    = "images.tar"
+	ChecksumsName = "SHA256SUMS"
+
+	currentVersion = 1
+)
+
+// Manifest describes the contents of a bundle
+type Manifest struct {
+	Version        int       `json:"version"`
+	CreatedAt      time.Time `json:"created_at"`
+	NodevinVersion string    `json:"nodevin_version"`
+	// Platform of the saved images, ex: linux/amd64
+	Platform string `json:"platform"`
+	// File name of the nodevin executable in the bundle, if any
+	Executable  string                `json:"executable,omitempty"`
+	Deployments map[string]Deployment `json:"deployments"`
+}
+
+// Deployment lists the images a deployment (ex: bitcoin, bitcoin-testnet/pruned) needs
+type Deployment struct {
+	Network string `json:"network"`
+	// Keyed by service network, as in nodevin.lock (ex: bitcoin, ord)
+	Images map[string]Image `json:"images"`
+	// Chain data snapshot CIDs known for the services, keyed by service network
+	Snapshots map[string]string `json:"snapshots,omitempty"`
+}
+
+// Image is a saved image: the tag it is loaded under and its image ID
+type Image struct {
+	Image string `json:"image"`
+	ID    string `json:"id"`
+	// Registry digest the tag resolved to when the bundle was created, if known
+	Digest string `json:"digest,omitempty"`
+}
+
+// File is a file on disk to add to a bundle under Name
+type File struct {
+	Name string
+	Path string
+}
+
+// Returns the unique image tags of every deployment in the manifest, sorted
+func (m *Manifest) ImageTags() []string {
+	seen := map[string]bool{}
+	var tags []string
+	for _, deployment := range m.Deployments {
+		for _, image := range deployment.Images {
+			if !seen[image.Image] {
+				seen[image.Image] = true
+				tags = append(tags, image.Image)
+			}
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// Writes a gzipped tar bundle to path with the manifest, a SHA256SUMS of every file and the
+// files themselves, plus <path>.sha256 so the archive can be checked after copying it
+func Write(path string, manifest *Manifest, files []File) error {
+	manifest.Version = currentVersion
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode bundle manifest: %v", err)
+	}
+
+	// Hash the files up front so SHA256SUMS can be written before them
+	checksums := map[string]string{ManifestName: checksumBytes(manifestData)}
+	for _, file := range files {
+		checksum, err := checksumFile(file.Path)
+		if err != nil {
+			return err
+		}
+		checksums[file.Name] = checksum
+	}
+
+	archiveHash := sha256.New()
+	err = utils.WriteFileAtomicFunc(path, 0644, func(w io.Writer) error {
+		gz := gzip.NewWriter(io.MultiWriter(w, archiveHash))
+		tw := tar.NewWriter(gz)
+
+		err := writeBytes(tw, ManifestName, manifestData)
+		if err == nil {
+			err = writeBytes(tw, ChecksumsName, []byte(formatChecksums(checksums)))
+		}
+		for _, file := range files {
+			if err != nil {
+				break
+			}
+			err = writeFile(tw, file)
+		}
+		if err == nil {
+			err = tw.Close()
+		}
+		if err == nil {
+			err = gz.Close()
+		}
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write bundle: %v", err)
+	}
+
+	sidecar := fmt.Sprintf("%s  %s\n", hex.EncodeToString(archiveHash.Sum(nil)), filepath.Base(path))
+	if err := os.WriteFile(path+".sha256", []byte(sidecar), 0644); err != nil {
+		return fmt.Errorf("failed to write bundle checksum: %v", err)
+	}
+
+	return nil
+}
+
+// Checks a bundle against <path>.sha256 if it exists. Reports whether there was one to check.
+func VerifyArchive(path string) (bool, error) {
+	data, err := os.ReadFile(path + ".sha256")
+	if os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("failed to read bundle checksum: %v", err)
+	}
+
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return true, fmt.Errorf("malformed bundle checksum file %s.sha256", path)
+	}
+
+	actual, err := checksumFile(path)
+	if err != nil {
+		return true, err
+	}
+	if !strings.EqualFold(fields[0], actual) {
+		return true, fmt.Errorf("bundle checksum mismatch: expected %s, got %s", fields[0], actual)
+	}
+	return true, nil
+}
+
+// Extracts a bundle into dir and verifies every file against SHA256SUMS before returning its manifest
+func Extract(path string, dir string) (*Manifest, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bundle: %v", err)
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundle: %v", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	extracted := map[string]bool{}
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read bundle: %v", err)
+		}
+
+		// Bundles only hold regular files at the top level
+		if header.Typeflag != tar.TypeReg || header.Name != filepath.Base(header.Name) || header.Name == "." || header.Name == ".." {
+			return nil, fmt.Errorf("unexpected entry %q in bundle", header.Name)
+		}
+
+		if err := extractFile(tr, filepath.Join(dir, header.Name), header.FileInfo().Mode().Perm()); err != nil {
+			return nil, err
+		}
+		extracted[header.Name] = true
+	}
+
+	checksums, err := readChecksums(filepath.Join(dir, ChecksumsName))
+	if err != nil {
+		return nil, err
+	}
+
+	for name := range extracted {
+		if name != ChecksumsName && checksums[name] == "" {
+			return nil, fmt.Errorf("%s is not listed in the bundle's %s", name, ChecksumsName)
+		}
+	}
+	for name, expected := range checksums {
+		if !extracted[name] {
+			return nil, fmt.Errorf("bundle is missing %s", name)
+		}
+		actual, err := checksumFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		if actual != expected {
+			return nil, fmt.Errorf("checksum mismatch for %s in bundle: expected %s, got %s", name, expected, actual)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, ManifestName))
+	if err != nil {
+		return nil, fmt.Errorf("bundle has no manifest: %v", err)
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse bundle manifest: %v", err)
+	}
+	if manifest.Version > currentVersion {
+		return nil, fmt.Errorf("bundle has version %d, this nodevin supports up to %d", manifest.Version, currentVersion)
+	}
+	if !extracted[ImagesName] {
+		return nil, fmt.Errorf("bundle is missing %s", ImagesName)
+	}
+
+	return &manifest, nil
+}
+
+func writeBytes(tw *tar.Writer, name string, data []byte) error {
+	header := &tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), ModTime: time.Now(), Typeflag: tar.TypeReg}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+func writeFile(tw *tar.Writer, file File) error {
+	in, err := os.Open(file.Path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	header := &tar.Header{Name: file.Name, Mode: int64(info.Mode().Perm()), Size: info.Size(), ModTime: info.ModTime(), Typeflag: tar.TypeReg}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, in)
+	return err
+}
+
+func extractFile(r io.Reader, path string, mode os.FileMode) error {
+	out, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return fmt.Errorf("failed to extract bundle: %v", err)
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to extract bundle: %v", err)
+	}
+	return out.Close()
+}
+
+// Formats checksums like `shasum -a 256`, so `shasum -c SHA256SUMS` works on an extracted bundle
+func formatChecksums(checksums map[string]string) string {
+	var names []string
+	for name := range checksums {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var builder strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&builder, "%s  %s\n", checksums[name], name)
+	}
+	return builder.String()
+}
+
+func readChecksums(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("bundle has no %s: %v", ChecksumsName, err)
+	}
+	defer file.Close()
+
+	checksums := map[string]string{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 || len(fields[0]) != sha256.Size*2 {
+			return nil, fmt.Errorf("malformed %s in bundle", ChecksumsName)
+		}
+		checksums[strings.TrimPrefix(fields[1], "*")] = strings.ToLower(fields[0])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", ChecksumsName, err)
+	}
+	return checksums, nil
+}
+
+func checksumFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func checksumBytes(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
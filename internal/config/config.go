@@ -16,56 +16,458 @@
 // limitations under the License.
 */
 
+// Package config loads nodevin settings. Every setting is named after its flag and taken
+// from, in order of precedence: the command-line flag, a NODEVIN_ environment variable
+// (ex: NODEVIN_DATA_DIR), the network's section of config.yaml, the global settings of
+// config.yaml, a .env file, and the flag's default.
 package config
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/fiftysixcrypto/nodevin/internal/logger"
+	"github.com/fiftysixcrypto/nodevin/internal/utils"
 	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
 )
 
-type Config struct {
-	Port      string `mapstructure:"port"`
-	DataDir   string `mapstructure:"data-dir"`
-	ExtraArgs string `mapstructure:"extra-args"`
-	//ResourceLimit string `mapstructure:"resource_limit"`
+const (
+	FileName  = "config.yaml"
+	EnvPrefix = "NODEVIN"
+
+	// Environment variable pointing at the config file to use instead of the default locations
+	PathEnv = EnvPrefix + "_CONFIG"
+
+	// Key of the per-network sections in config.yaml
+	networksKey = "networks"
+
+	// Flag annotation naming the setting a flag defined on several commands sets, ex: --cluster sets ipfs-cluster
+	SettingAnnotation = "nodevin_setting"
+)
+
+// Setting is a single key and value of the config file, .env file or environment.
+// Keys of network sections are prefixed with the network, ex: bitcoin.version.
+type Setting struct {
+	Key   string `json:"key" yaml:"key"`
+	Value string `json:"value" yaml:"value"`
 }
 
-var AppConfig Config
+var (
+	// Config file in effect and whether it exists, set by InitConfig
+	configPath   string
+	configExists bool
+	// Network sections of the config file, applied once the command's network is known
+	networkSettings map[string]map[string]interface{}
+	// .env file read by InitConfig and its settings
+	envFileUsed     string
+	envFileSettings map[string]interface{}
+)
 
 func InitConfig() {
-	viper.SetConfigName(".env")
-	viper.SetConfigType("env")
+	// Only prefixed variables are read, so unrelated ones like VERSION or IMAGE don't override flags
+	viper.SetEnvPrefix(EnvPrefix)
+	viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
+	viper.AutomaticEnv()
+
+	// A .env file has the lowest precedence of the files
+	envFile := viper.New()
+	envFile.SetConfigName(".env")
+	envFile.SetConfigType("env")
+	for _, dir := range envFileDirs() {
+		envFile.AddConfigPath(dir)
+	}
+	if err := envFile.ReadInConfig(); err == nil {
+		envFileUsed = envFile.ConfigFileUsed()
+		envFileSettings = envFile.AllSettings()
+		if err := viper.MergeConfigMap(envFileSettings); err != nil {
+			logger.LogError(fmt.Sprintf("Warning: Could not apply %s: %v", envFileUsed, err))
+		}
+	} else if _, notFound := err.(viper.ConfigFileNotFoundError); !notFound {
+		logger.LogError(fmt.Sprintf("Warning: Could not read .env file: %v", err))
+	}
+
+	path, err := Path()
+	if err != nil {
+		logger.LogError("Warning: Could not locate config file: " + err.Error())
+		return
+	}
+	configPath = path
+
+	settings, exists, err := Load(path)
+	if err != nil {
+		logger.LogError("Warning: " + err.Error())
+		return
+	}
+	configExists = exists
+	if !exists {
+		return
+	}
+
+	global := map[string]interface{}{}
+	for key, value := range settings {
+		if key != networksKey {
+			global[key] = value
+		}
+	}
+	if err := viper.MergeConfigMap(global); err != nil {
+		logger.LogError(fmt.Sprintf("Warning: Could not apply %s: %v", path, err))
+	}
+
+	networkSettings = map[string]map[string]interface{}{}
+	if networks, ok := settings[networksKey].(map[string]interface{}); ok {
+		for network, section := range networks {
+			if section, ok := section.(map[string]interface{}); ok {
+				networkSettings[network] = section
+			}
+		}
+	}
+}
+
+// Applies the config file sections of a network, ex: bitcoin then bitcoin-testnet then
+// bitcoin-testnet/pruned, each overriding the global settings and the sections before it
+func ApplyNetworkSettings(networks ...string) {
+	applied := map[string]bool{}
+	for _, network := range networks {
+		section, exists := networkSettings[network]
+		if !exists || applied[network] {
+			continue
+		}
+		applied[network] = true
 
-	// Add project-specific configuration (current directory)
-	viper.AddConfigPath(".")
+		if err := viper.MergeConfigMap(section); err != nil {
+			logger.LogError(fmt.Sprintf("Warning: Could not apply the %s section of %s: %v", network, configPath, err))
+		}
+	}
+}
+
+// Returns the config file in effect: $NODEVIN_CONFIG if set, otherwise the first of
+// ~/.nodevin/config.yaml and /etc/nodevin/config.yaml that exists (~/.nodevin/config.yaml if neither does)
+func Path() (string, error) {
+	if path := os.Getenv(PathEnv); path != "" {
+		return filepath.Abs(path)
+	}
 
-	// Add user-specific configuration (~/.nodevin)
 	homeDir, err := os.UserHomeDir()
-	if err == nil {
-		viper.AddConfigPath(filepath.Join(homeDir, ".nodevin"))
+	if err != nil {
+		return "", fmt.Errorf("failed to get user home directory: %v", err)
+	}
+
+	userPath := filepath.Join(homeDir, ".nodevin", FileName)
+	for _, path := range []string{userPath, filepath.Join("/etc/nodevin", FileName)} {
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
 	}
+	return userPath, nil
+}
 
-	// Add global configuration (/etc/nodevin)
-	viper.AddConfigPath("/etc/nodevin")
+// Returns the .env file read at startup ("" if none)
+func EnvFileUsed() string {
+	return envFileUsed
+}
 
-	// Add executable-specific configuration
-	exePath, err := os.Executable()
-	if err == nil {
-		viper.AddConfigPath(filepath.Dir(exePath))
+// Returns the settings of the .env file read at startup
+func EnvFileSettings() []Setting {
+	return flatten("", envFileSettings)
+}
+
+// Returns the NODEVIN_ environment variables as settings, ex: NODEVIN_DATA_DIR as data-dir
+func EnvSettings() map[string]Setting {
+	settings := map[string]Setting{}
+	for _, variable := range os.Environ() {
+		name, value, _ := strings.Cut(variable, "=")
+		key, found := strings.CutPrefix(name, EnvPrefix+"_")
+		if !found || name == PathEnv || key == "" {
+			continue
+		}
+		key = strings.ReplaceAll(strings.ToLower(key), "_", "-")
+		settings[name] = Setting{Key: key, Value: value}
 	}
+	return settings
+}
 
-	// Allow overriding with environment variables
-	viper.AutomaticEnv()
+// Reads a config file. Scalars keep their text, so a version like 27.0 is not turned into 27.
+func Load(path string) (map[string]interface{}, bool, error) {
+	document, exists, err := readDocument(path)
+	if err != nil || !exists {
+		return map[string]interface{}{}, exists, err
+	}
+
+	root := documentRoot(document)
+	if root == nil {
+		return map[string]interface{}{}, true, nil
+	}
+	settings, ok := nodeValue(root).(map[string]interface{})
+	if !ok {
+		return nil, true, fmt.Errorf("config file %s must be a mapping of settings", path)
+	}
+	return settings, true, nil
+}
+
+// Returns every setting of a config file sorted by key
+func List(path string) ([]Setting, error) {
+	settings, _, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+	return flatten("", settings), nil
+}
+
+// Returns a setting of a config file, ex: data-dir or bitcoin.version
+func Get(path string, key string) (Setting, bool, error) {
+	settings, err := List(path)
+	if err != nil {
+		return Setting{}, false, err
+	}
+	for _, setting := range settings {
+		if setting.Key == key {
+			return setting, true, nil
+		}
+	}
+	return Setting{}, false, nil
+}
+
+// Sets a setting of a config file to a string or a list ([]string), creating the file if
+// needed. Comments and the order of the other settings are kept.
+func Set(path string, key string, value interface{}) error {
+	document, _, err := readDocument(path)
+	if err != nil {
+		return err
+	}
 
-	// Read the configuration file, if available
-	err = viper.ReadInConfig()
-	if err == nil {
-		logger.LogInfo(fmt.Sprintf("Successfully loaded configuration from %s", viper.ConfigFileUsed()))
+	mapping := documentRoot(document)
+	if mapping == nil {
+		mapping = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		document.Content = []*yaml.Node{mapping}
+	} else if mapping.Kind != yaml.MappingNode {
+		return fmt.Errorf("config file %s must be a mapping of settings", path)
+	}
+
+	for _, name := range settingPath(key) {
+		child := mappingValue(mapping, name)
+		if child == nil {
+			child = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: name}, child)
+		} else if child.Kind != yaml.MappingNode {
+			return fmt.Errorf("%s is not a section in %s", name, path)
+		}
+		mapping = child
+	}
+
+	name := settingName(key)
+	valueNode := &yaml.Node{}
+	switch value := value.(type) {
+	case []string:
+		valueNode.Kind, valueNode.Tag, valueNode.Style = yaml.SequenceNode, "!!seq", yaml.FlowStyle
+		for _, item := range value {
+			valueNode.Content = append(valueNode.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: item})
+		}
+	default:
+		text := fmt.Sprint(value)
+		valueNode.Kind, valueNode.Tag, valueNode.Value = yaml.ScalarNode, "!!str", text
+		if text == "true" || text == "false" {
+			valueNode.Tag = "!!bool"
+		}
+	}
+
+	if existing := mappingValue(mapping, name); existing != nil {
+		valueNode.LineComment = existing.LineComment
+		*existing = *valueNode
 	} else {
-		logger.LogError(fmt.Sprintf("Warning: Could not read .env file: %v", err))
+		mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: name}, valueNode)
+	}
+
+	return writeDocument(path, document)
+}
+
+// Removes a setting from a config file, reporting whether it was set. Sections left empty are removed too.
+func Unset(path string, key string) (bool, error) {
+	document, exists, err := readDocument(path)
+	if err != nil || !exists {
+		return false, err
+	}
+
+	mappings := []*yaml.Node{documentRoot(document)}
+	if mappings[0] == nil || mappings[0].Kind != yaml.MappingNode {
+		return false, nil
+	}
+	for _, name := range settingPath(key) {
+		child := mappingValue(mappings[len(mappings)-1], name)
+		if child == nil || child.Kind != yaml.MappingNode {
+			return false, nil
+		}
+		mappings = append(mappings, child)
+	}
+
+	if !removeMappingKey(mappings[len(mappings)-1], settingName(key)) {
+		return false, nil
+	}
+
+	// Drop the network section and networks key if nothing is left in them
+	sections := settingPath(key)
+	for i := len(mappings) - 1; i > 0 && len(mappings[i].Content) == 0; i-- {
+		removeMappingKey(mappings[i-1], sections[i-1])
+	}
+
+	return true, writeDocument(path, document)
+}
+
+// Splits a key into the sections it is in and its name, ex: bitcoin.version is version in networks/bitcoin
+func settingPath(key string) []string {
+	if network, _, found := strings.Cut(key, "."); found {
+		return []string{networksKey, network}
+	}
+	return nil
+}
+
+func settingName(key string) string {
+	if _, name, found := strings.Cut(key, "."); found {
+		return name
+	}
+	return key
+}
+
+func readDocument(path string) (*yaml.Node, bool, error) {
+	document := &yaml.Node{Kind: yaml.DocumentNode}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return document, false, nil
+	} else if err != nil {
+		return nil, false, fmt.Errorf("failed to read config file: %v", err)
+	}
+
+	if err := yaml.Unmarshal(data, document); err != nil {
+		return nil, true, fmt.Errorf("failed to parse config file %s: %v", path, err)
+	}
+	if document.Kind == 0 {
+		// An empty file
+		document.Kind = yaml.DocumentNode
+	}
+	return document, true, nil
+}
+
+// Writes a config file atomically
+func writeDocument(path string, document *yaml.Node) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %v", err)
+	}
+
+	var data bytes.Buffer
+	encoder := yaml.NewEncoder(&data)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(document); err != nil {
+		return fmt.Errorf("failed to encode config file: %v", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return fmt.Errorf("failed to encode config file: %v", err)
+	}
+
+	if err := utils.WriteFileAtomic(path, data.Bytes(), 0600); err != nil {
+		return fmt.Errorf("failed to write config file: %v", err)
+	}
+	return nil
+}
+
+func documentRoot(document *yaml.Node) *yaml.Node {
+	if len(document.Content) == 0 {
+		return nil
+	}
+	return document.Content[0]
+}
+
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func removeMappingKey(mapping *yaml.Node, key string) bool {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			mapping.Content = append(mapping.Content[:i], mapping.Content[i+2:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Converts a YAML node to maps, lists and the text of its scalars
+func nodeValue(node *yaml.Node) interface{} {
+	switch node.Kind {
+	case yaml.MappingNode:
+		values := map[string]interface{}{}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			values[strings.ToLower(node.Content[i].Value)] = nodeValue(node.Content[i+1])
+		}
+		return values
+	case yaml.SequenceNode:
+		values := make([]string, 0, len(node.Content))
+		for _, item := range node.Content {
+			values = append(values, fmt.Sprint(nodeValue(item)))
+		}
+		return values
+	case yaml.AliasNode:
+		return nodeValue(node.Alias)
+	default:
+		return node.Value
+	}
+}
+
+// Flattens settings into sorted keys, network sections as <network>.<key>
+func flatten(prefix string, settings map[string]interface{}) []Setting {
+	var flat []Setting
+	for key, value := range settings {
+		switch value := value.(type) {
+		case map[string]interface{}:
+			if prefix == "" && key == networksKey {
+				for network, section := range value {
+					if section, ok := section.(map[string]interface{}); ok {
+						flat = append(flat, flatten(network+".", section)...)
+					}
+				}
+			} else {
+				// Map settings (ex: volume-labels) are shown the way their flag takes them
+				var pairs []string
+				for nested, nestedValue := range value {
+					pairs = append(pairs, fmt.Sprintf("%s=%v", nested, nestedValue))
+				}
+				sort.Strings(pairs)
+				flat = append(flat, Setting{Key: prefix + key, Value: strings.Join(pairs, ",")})
+			}
+		case []string:
+			flat = append(flat, Setting{Key: prefix + key, Value: strings.Join(value, ",")})
+		default:
+			flat = append(flat, Setting{Key: prefix + key, Value: fmt.Sprint(value)})
+		}
+	}
+	sort.Slice(flat, func(i, j int) bool { return flat[i].Key < flat[j].Key })
+	return flat
+}
+
+func envFileDirs() []string {
+	// Project-specific configuration (current directory)
+	dirs := []string{"."}
+
+	// User-specific configuration (~/.nodevin)
+	if homeDir, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(homeDir, ".nodevin"))
+	}
+
+	// Global configuration (/etc/nodevin)
+	dirs = append(dirs, "/etc/nodevin")
+
+	// Executable-specific configuration
+	if exePath, err := os.Executable(); err == nil {
+		dirs = append(dirs, filepath.Dir(exePath))
 	}
+	return dirs
 }
@@ -0,0 +1,142 @@
+/*
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 The Nodevin Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+// Package lockfile reads and writes nodevin.lock, which pins every image of a
+// deployment to the digest its tag resolved to, so `nodevin start --locked` runs
+// exactly the same software on another host or at a later date.
+package lockfile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fiftysixcrypto/nodevin/internal/utils"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	FileName = "nodevin.lock"
+
+	// Source of images loaded from a `nodevin bundle`: their digest is the local image ID
+	// and they are deployed by tag without ever being pulled
+	SourceBundle = "bundle"
+
+	currentVersion = 1
+	header         = "# Generated by nodevin. Deploy these digests with `nodevin start <network> --locked`\n# and refresh them with `nodevin lock update [network]`.\n"
+)
+
+// Lockfile maps deployment names (ex: bitcoin, bitcoin-testnet, bitcoin/pruned) to their locked images
+type Lockfile struct {
+	Version     int                          `yaml:"version"`
+	Deployments map[string]*LockedDeployment `yaml:"deployments"`
+}
+
+// LockedDeployment holds the images of a deployment's node and sidecars, keyed by
+// the service's network (ex: bitcoin for the node, ord for the ord sidecar)
+type LockedDeployment struct {
+	Services   map[string]LockedImage `yaml:"services"`
+	ResolvedAt time.Time              `yaml:"resolved_at"`
+}
+
+// LockedImage is the tag a service was configured with and the digest it resolved to
+type LockedImage struct {
+	Image  string `yaml:"image"`
+	Digest string `yaml:"digest"`
+	Source string `yaml:"source,omitempty"`
+}
+
+// Returns the reference that pulls exactly this image, ex: fiftysix/bitcoin-core@sha256:...
+// Bundled images are referenced by their tag, which `nodevin bundle install` loaded locally.
+func (l LockedImage) Reference() string {
+	if l.Bundled() {
+		return l.Image
+	}
+	return Repository(l.Image) + "@" + l.Digest
+}
+
+// Reports whether the image was loaded from a bundle rather than pulled from a registry
+func (l LockedImage) Bundled() bool {
+	return l.Source == SourceBundle
+}
+
+// Strips the tag and digest from an image reference (a registry port is not mistaken for a tag)
+func Repository(image string) string {
+	image = strings.SplitN(image, "@", 2)[0]
+	if lastColon := strings.LastIndex(image, ":"); lastColon > strings.LastIndex(image, "/") {
+		image = image[:lastColon]
+	}
+	return image
+}
+
+// Returns the path of the lockfile: --lock-file if given, otherwise ~/.nodevin/nodevin.lock
+func Path(override string) (string, error) {
+	if override != "" {
+		return filepath.Abs(override)
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user home directory: %v", err)
+	}
+	return filepath.Join(homeDir, ".nodevin", FileName), nil
+}
+
+// Reads a lockfile, returning an empty one if it doesn't exist yet
+func Load(path string) (*Lockfile, bool, error) {
+	lock := &Lockfile{Version: currentVersion, Deployments: map[string]*LockedDeployment{}}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return lock, false, nil
+	} else if err != nil {
+		return nil, false, fmt.Errorf("failed to read lockfile: %v", err)
+	}
+
+	if err := yaml.Unmarshal(data, lock); err != nil {
+		return nil, false, fmt.Errorf("failed to parse lockfile %s: %v", path, err)
+	}
+	if lock.Version > currentVersion {
+		return nil, false, fmt.Errorf("lockfile %s has version %d, this nodevin supports up to %d", path, lock.Version, currentVersion)
+	}
+	if lock.Deployments == nil {
+		lock.Deployments = map[string]*LockedDeployment{}
+	}
+
+	return lock, true, nil
+}
+
+// Writes a lockfile atomically
+func Save(path string, lock *Lockfile) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create lockfile directory: %v", err)
+	}
+
+	lock.Version = currentVersion
+	data, err := yaml.Marshal(lock)
+	if err != nil {
+		return fmt.Errorf("failed to encode lockfile: %v", err)
+	}
+
+	if err := utils.WriteFileAtomic(path, []byte(header+string(data)), 0644); err != nil {
+		return fmt.Errorf("failed to write lockfile: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+/*
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 The Nodevin Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	FormatTable = "table"
+	FormatJSON  = "json"
+	FormatYAML  = "yaml"
+)
+
+// Returns the output format selected with --output (default: table)
+func Format() string {
+	format := strings.ToLower(viper.GetString("output"))
+	if format == "" {
+		return FormatTable
+	}
+	return format
+}
+
+// Reports whether commands should emit machine-readable output only
+func IsStructured() bool {
+	format := Format()
+	return format == FormatJSON || format == FormatYAML
+}
+
+// Validates the --output flag value
+func Validate() error {
+	switch Format() {
+	case FormatTable, FormatJSON, FormatYAML:
+		return nil
+	default:
+		return fmt.Errorf("unsupported output format: %s (expected table, json or yaml)", viper.GetString("output"))
+	}
+}
+
+// Print writes value to stdout in the selected structured format
+func Print(value interface{}) error {
+	return Write(os.Stdout, value)
+}
+
+// Write encodes value to w in the selected structured format
+func Write(w io.Writer, value interface{}) error {
+	switch Format() {
+	case FormatYAML:
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(value); err != nil {
+			return fmt.Errorf("failed to encode yaml output: %w", err)
+		}
+		return encoder.Close()
+	default:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(value); err != nil {
+			return fmt.Errorf("failed to encode json output: %w", err)
+		}
+		return nil
+	}
+}
+
+// PrintRecord writes one record of a stream (ex: a log line) to stdout, as a
+// single JSON line or a separate YAML document, so output can be consumed while following
+func PrintRecord(value interface{}) error {
+	if Format() == FormatYAML {
+		if _, err := fmt.Fprintln(os.Stdout, "---"); err != nil {
+			return err
+		}
+		return Write(os.Stdout, value)
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode json output: %w", err)
+	}
+	_, err = fmt.Fprintln(os.Stdout, string(data))
+	return err
+}
@@ -0,0 +1,276 @@
+/*
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 The Nodevin Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+// Package sizeindex keeps a persistent, incrementally updated index of
+// directory sizes so multi-hundred-GB chain directories don't have to be
+// walked file by file every time their size is shown.
+//
+// Each directory is recorded with its modification time, its subdirectories
+// and the size and modification time of every file directly inside it. A
+// directory is only listed again when its modification time changes (files
+// added, removed or renamed) or its entry is older than maxEntryAge. Files are
+// re-stat'ed on every call, since a file growing in place (ex: a blk file or
+// index.redb) does not change its directory's modification time.
+package sizeindex
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fiftysixcrypto/nodevin/internal/utils"
+)
+
+const (
+	indexFileName = "size-index.json"
+
+	// Bumped whenever the on-disk layout changes, older indexes are discarded
+	indexVersion = 2
+
+	// Entries older than this are listed again even if the directory looks unchanged
+	maxEntryAge = 24 * time.Hour
+
+	// Long walks persist what they have scanned so far at this interval, since a
+	// walk given up on by its caller is dropped when the process exits
+	saveInterval = 10 * time.Second
+)
+
+type fileEntry struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+type dirEntry struct {
+	ModTime   time.Time             `json:"mod_time"`
+	ScannedAt time.Time             `json:"scanned_at"`
+	Files     map[string]*fileEntry `json:"files,omitempty"`
+	Subdirs   []string              `json:"subdirs,omitempty"`
+}
+
+type indexFile struct {
+	Version int                  `json:"version"`
+	Dirs    map[string]*dirEntry `json:"dirs"`
+}
+
+var (
+	mu        sync.Mutex
+	loaded    bool
+	lastSaved time.Time
+	dirs      = map[string]*dirEntry{}
+)
+
+// Returns the total size of all files under path, reusing the cached index where possible
+func DirectorySize(path string) (int64, error) {
+	path = filepath.Clean(path)
+
+	mu.Lock()
+	if !loaded {
+		load()
+		loaded = true
+		lastSaved = time.Now()
+	}
+	mu.Unlock()
+
+	visited := map[string]bool{}
+	size, err := directorySize(path, visited)
+	if err != nil {
+		return 0, err
+	}
+
+	mu.Lock()
+	// Forget directories that no longer exist under path
+	prefix := path + string(filepath.Separator)
+	for dir := range dirs {
+		if (dir == path || strings.HasPrefix(dir, prefix)) && !visited[dir] {
+			delete(dirs, dir)
+		}
+	}
+	data, err := encode()
+	lastSaved = time.Now()
+	mu.Unlock()
+
+	// The index is only a cache, a failure to persist it just means a slower next call
+	if err == nil {
+		save(data)
+	}
+
+	return size, nil
+}
+
+// Filesystem access happens without holding mu so sizes of different networks can be computed in parallel
+func directorySize(dir string, visited map[string]bool) (int64, error) {
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return 0, err
+	}
+	visited[dir] = true
+
+	mu.Lock()
+	cached, exists := dirs[dir]
+	mu.Unlock()
+
+	var entry *dirEntry
+	if !exists || !cached.ModTime.Equal(info.ModTime()) || time.Since(cached.ScannedAt) > maxEntryAge {
+		entry, err = scanDirectory(dir, info.ModTime())
+		if err != nil {
+			return 0, err
+		}
+	} else {
+		entry = refreshFiles(dir, cached)
+	}
+
+	var data []byte
+	mu.Lock()
+	dirs[dir] = entry
+	if time.Since(lastSaved) > saveInterval {
+		data, err = encode()
+		if err != nil {
+			data = nil
+		}
+		lastSaved = time.Now()
+	}
+	mu.Unlock()
+
+	if data != nil {
+		save(data)
+	}
+
+	var size int64
+	for _, file := range entry.Files {
+		size += file.Size
+	}
+
+	for _, subdir := range entry.Subdirs {
+		subdirSize, err := directorySize(filepath.Join(dir, subdir), visited)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return 0, err
+		}
+		size += subdirSize
+	}
+
+	return size, nil
+}
+
+// Lists a directory, recording the size and modification time of each file in it
+func scanDirectory(dir string, modTime time.Time) (*dirEntry, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &dirEntry{ModTime: modTime, ScannedAt: time.Now()}
+
+	for _, dirEntry := range entries {
+		if dirEntry.IsDir() {
+			entry.Subdirs = append(entry.Subdirs, dirEntry.Name())
+			continue
+		}
+
+		info, err := dirEntry.Info()
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		if entry.Files == nil {
+			entry.Files = map[string]*fileEntry{}
+		}
+		entry.Files[dirEntry.Name()] = &fileEntry{Size: info.Size(), ModTime: info.ModTime()}
+	}
+
+	return entry, nil
+}
+
+// Returns a copy of entry with every file whose size or modification time changed updated
+func refreshFiles(dir string, entry *dirEntry) *dirEntry {
+	refreshed := *entry
+	refreshed.Files = make(map[string]*fileEntry, len(entry.Files))
+
+	for name, file := range entry.Files {
+		info, err := os.Lstat(filepath.Join(dir, name))
+		if err != nil {
+			// Removed since the last listing, the directory's next mtime change picks that up
+			continue
+		}
+		if info.Size() == file.Size && info.ModTime().Equal(file.ModTime) {
+			refreshed.Files[name] = file
+			continue
+		}
+		refreshed.Files[name] = &fileEntry{Size: info.Size(), ModTime: info.ModTime()}
+	}
+
+	return &refreshed
+}
+
+func indexPath() (string, error) {
+	nodevinDataDir, err := utils.GetNodevinDataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(nodevinDataDir), indexFileName), nil
+}
+
+func load() {
+	path, err := indexPath()
+	if err != nil {
+		return
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+
+	var index indexFile
+	if err := json.Unmarshal(data, &index); err != nil || index.Version != indexVersion || index.Dirs == nil {
+		// A corrupt or outdated index is simply rebuilt
+		return
+	}
+	dirs = index.Dirs
+}
+
+// Snapshots the index, callers hold mu
+func encode() ([]byte, error) {
+	data, err := json.Marshal(indexFile{Version: indexVersion, Dirs: dirs})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode size index: %v", err)
+	}
+	return data, nil
+}
+
+// Writes an encoded snapshot of the index, called without holding mu
+func save(data []byte) error {
+	path, err := indexPath()
+	if err != nil {
+		return err
+	}
+
+	if err := utils.WriteFileAtomic(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write size index: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+/*
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 The Nodevin Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+// Package stackfile reads nodevin.yaml, which declares the nodes a host should run
+// (network, variant, instance, version, sidecars, resources, ports, data directory and
+// update policy) so `nodevin up` can reconcile the host to match it.
+package stackfile
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	FileName = "nodevin.yaml"
+
+	currentVersion = 1
+
+	VariantMainnet = "mainnet"
+	VariantTestnet = "testnet"
+)
+
+// Stack is the content of a nodevin.yaml
+type Stack struct {
+	Version int    `yaml:"version"`
+	Nodes   []Node `yaml:"nodes"`
+}
+
+// Node declares one deployment: a node and its sidecars
+type Node struct {
+	Network  string `yaml:"network"`
+	Variant  string `yaml:"variant,omitempty"`
+	Instance string `yaml:"instance,omitempty"`
+	Image    string `yaml:"image,omitempty"`
+	Version  string `yaml:"version,omitempty"`
+	// Sidecars run next to the node, ex: ord with bitcoin
+	Sidecars          []Sidecar `yaml:"sidecars,omitempty"`
+	Resources         Resources `yaml:"resources,omitempty"`
+	Ports             []string  `yaml:"ports,omitempty"`
+	DataDir           string    `yaml:"data_dir,omitempty"`
+	UpdatePolicy      string    `yaml:"update_policy,omitempty"`
+	MaintenanceWindow string    `yaml:"maintenance_window,omitempty"`
+	// Any other start flag by name, ex: rpc-user: admin. Applied when the node is (re)started.
+	Flags map[string]string `yaml:"flags,omitempty"`
+}
+
+// Sidecar is written either as its name (ord) or as a mapping with an image and version
+type Sidecar struct {
+	Name    string `yaml:"name"`
+	Image   string `yaml:"image,omitempty"`
+	Version string `yaml:"version,omitempty"`
+}
+
+// Resources are the compose limits and reservations of the node container
+type Resources struct {
+	CPULimit       string `yaml:"cpu_limit,omitempty"`
+	MemLimit       string `yaml:"mem_limit,omitempty"`
+	CPUReservation string `yaml:"cpu_reservation,omitempty"`
+	MemReservation string `yaml:"mem_reservation,omitempty"`
+}
+
+func (s *Sidecar) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		s.Name = value.Value
+		return nil
+	}
+
+	type plain Sidecar
+	return value.Decode((*plain)(s))
+}
+
+// Returns the variant of a node, mainnet unless given
+func (n Node) GetVariant() string {
+	if n.Variant == "" {
+		return VariantMainnet
+	}
+	return n.Variant
+}
+
+// Returns the path of the stack file: -f/--file if given, otherwise nodevin.yaml in the working directory
+func Path(override string) (string, error) {
+	if override == "" {
+		override = FileName
+	}
+	return filepath.Abs(override)
+}
+
+// Reads and checks a stack file. Unknown keys are rejected so a typo doesn't silently drop a setting.
+func Load(path string) (*Stack, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stack file: %v", err)
+	}
+
+	stack := &Stack{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(stack); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse stack file %s: %v", path, err)
+	}
+
+	if stack.Version == 0 {
+		stack.Version = currentVersion
+	}
+	if stack.Version > currentVersion {
+		return nil, fmt.Errorf("stack file %s has version %d, this nodevin supports up to %d", path, stack.Version, currentVersion)
+	}
+
+	for i, node := range stack.Nodes {
+		if node.Network == "" {
+			return nil, fmt.Errorf("node %d in %s has no network", i+1, path)
+		}
+		if variant := node.GetVariant(); variant != VariantMainnet && variant != VariantTestnet {
+			return nil, fmt.Errorf("node %d in %s has unsupported variant %q (expected mainnet or testnet)", i+1, path, node.Variant)
+		}
+		for _, sidecar := range node.Sidecars {
+			if sidecar.Name == "" {
+				return nil, fmt.Errorf("node %d in %s has a sidecar without a name", i+1, path)
+			}
+		}
+	}
+
+	return stack, nil
+}
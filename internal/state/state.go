@@ -0,0 +1,335 @@
+/*
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 The Nodevin Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+// Package state keeps a record of every deployment nodevin has started in
+// ~/.nodevin/state.json, so stop, update, logs, info and delete can find a
+// deployment's compose file, data directories, containers and ports without
+// reconstructing them from the current flags.
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fiftysixcrypto/nodevin/internal/utils"
+)
+
+const stateFileName = "state.json"
+
+// Values of Deployment.Status
+const (
+	StatusRunning = "running"
+	StatusStopped = "stopped"
+)
+
+// Values of Credentials.Method
+const (
+	CredentialsNone     = "none"
+	CredentialsCookie   = "cookie"
+	CredentialsPassword = "password"
+)
+
+// Deployment is a compose project started by nodevin (a node and its sidecars).
+// Named instances are recorded as network/instance, ex: bitcoin/pruned. UpdatePolicy and
+// MaintenanceWindow control how `nodevin update docker` treats new images of its services.
+// Stack is the nodevin.yaml that declares the deployment, if it was started by `nodevin up`.
+type Deployment struct {
+	Name              string      `json:"name" yaml:"name"`
+	Network           string      `json:"network" yaml:"network"`
+	Instance          string      `json:"instance,omitempty" yaml:"instance,omitempty"`
+	Variant           string      `json:"variant" yaml:"variant"`
+	Status            string      `json:"status" yaml:"status"`
+	ComposeFile       string      `json:"compose_file" yaml:"compose_file"`
+	DataDir           string      `json:"data_dir" yaml:"data_dir"`
+	PortOffset        int         `json:"port_offset,omitempty" yaml:"port_offset,omitempty"`
+	Credentials       Credentials `json:"credentials" yaml:"credentials"`
+	Services          []Service   `json:"services" yaml:"services"`
+	UpdatePolicy      string      `json:"update_policy,omitempty" yaml:"update_policy,omitempty"`
+	MaintenanceWindow string      `json:"maintenance_window,omitempty" yaml:"maintenance_window,omitempty"`
+	Stack             string      `json:"stack,omitempty" yaml:"stack,omitempty"`
+	CreatedAt         time.Time   `json:"created_at" yaml:"created_at"`
+	UpdatedAt         time.Time   `json:"updated_at" yaml:"updated_at"`
+}
+
+// Service is a single node or sidecar container of a deployment
+type Service struct {
+	Name          string   `json:"name" yaml:"name"`
+	Network       string   `json:"network" yaml:"network"`
+	Role          string   `json:"role" yaml:"role"`
+	ContainerName string   `json:"container_name" yaml:"container_name"`
+	Image         string   `json:"image" yaml:"image"`
+	ImageDigest   string   `json:"image_digest,omitempty" yaml:"image_digest,omitempty"`
+	Ports         []string `json:"ports,omitempty" yaml:"ports,omitempty"`
+	DataDir       string   `json:"data_dir,omitempty" yaml:"data_dir,omitempty"`
+	// Digest the service ran before its last image update
+	PreviousImageDigest string `json:"previous_image_digest,omitempty" yaml:"previous_image_digest,omitempty"`
+}
+
+// Credentials references how RPC clients authenticate to the node. Secrets are never
+// stored: a password still comes from --rpc-pass, a cookie from the node's data directory.
+type Credentials struct {
+	Method string `json:"method" yaml:"method"`
+	User   string `json:"user,omitempty" yaml:"user,omitempty"`
+}
+
+type stateFile struct {
+	Deployments map[string]*Deployment `json:"deployments"`
+}
+
+// Guards state.json within this process; lockFile guards it across processes
+var mu sync.Mutex
+
+// Returns the path of the state file (~/.nodevin/state.json). It does not follow
+// --data-dir so deployments started with a custom data directory can always be found.
+func Path() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user home directory: %v", err)
+	}
+	return filepath.Join(homeDir, ".nodevin", stateFileName), nil
+}
+
+// Returns every recorded deployment sorted by name
+func List() ([]Deployment, error) {
+	unlock, err := lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	deployments, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]Deployment, 0, len(deployments))
+	for _, deployment := range deployments {
+		list = append(list, *deployment)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+
+	return list, nil
+}
+
+// Returns the deployment recorded under name
+func Get(name string) (Deployment, bool, error) {
+	unlock, err := lock()
+	if err != nil {
+		return Deployment{}, false, err
+	}
+	defer unlock()
+
+	deployments, err := load()
+	if err != nil {
+		return Deployment{}, false, err
+	}
+
+	deployment, exists := deployments[name]
+	if !exists {
+		return Deployment{}, false, nil
+	}
+	return *deployment, true, nil
+}
+
+// Returns the deployment and service running the given container
+func FindByContainer(containerName string) (Deployment, Service, bool, error) {
+	deployments, err := List()
+	if err != nil {
+		return Deployment{}, Service{}, false, err
+	}
+
+	for _, deployment := range deployments {
+		for _, service := range deployment.Services {
+			if service.ContainerName == containerName {
+				return deployment, service, true, nil
+			}
+		}
+	}
+	return Deployment{}, Service{}, false, nil
+}
+
+// Returns the service (node or sidecar) recorded for a network and instance, ex: ord inside a bitcoin deployment
+func FindService(network string, instance string) (Deployment, Service, bool, error) {
+	deployments, err := List()
+	if err != nil {
+		return Deployment{}, Service{}, false, err
+	}
+
+	for _, deployment := range deployments {
+		for _, service := range deployment.Services {
+			if service.Network == network && deployment.Instance == instance {
+				return deployment, service, true, nil
+			}
+		}
+	}
+	return Deployment{}, Service{}, false, nil
+}
+
+// Records a deployment, replacing any previous record with the same name but keeping its creation time
+func Put(deployment Deployment) error {
+	unlock, err := lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	deployments, err := load()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	if existing, exists := deployments[deployment.Name]; exists && !existing.CreatedAt.IsZero() {
+		deployment.CreatedAt = existing.CreatedAt
+	} else if deployment.CreatedAt.IsZero() {
+		deployment.CreatedAt = now
+	}
+	deployment.UpdatedAt = now
+
+	deployments[deployment.Name] = &deployment
+	return save(deployments)
+}
+
+// Applies fn to the deployment recorded under name. Reports false if there is none.
+func Update(name string, fn func(*Deployment)) (bool, error) {
+	unlock, err := lock()
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
+	deployments, err := load()
+	if err != nil {
+		return false, err
+	}
+
+	deployment, exists := deployments[name]
+	if !exists {
+		return false, nil
+	}
+
+	fn(deployment)
+	deployment.UpdatedAt = time.Now().UTC()
+
+	return true, save(deployments)
+}
+
+// Removes the deployment recorded under name, if any
+func Remove(name string) error {
+	unlock, err := lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	deployments, err := load()
+	if err != nil {
+		return err
+	}
+
+	if _, exists := deployments[name]; !exists {
+		return nil
+	}
+	delete(deployments, name)
+
+	return save(deployments)
+}
+
+// Takes exclusive access to the state file for a load and save, ex: while `update watch`
+// runs alongside a start in another process. The returned function releases it.
+func lock() (func(), error) {
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create nodevin directory: %v", err)
+	}
+
+	mu.Lock()
+
+	file, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		mu.Unlock()
+		return nil, fmt.Errorf("failed to open state lock file: %v", err)
+	}
+
+	if err := lockFile(file); err != nil {
+		file.Close()
+		mu.Unlock()
+		return nil, fmt.Errorf("failed to lock state file: %v", err)
+	}
+
+	return func() {
+		unlockFile(file)
+		file.Close()
+		mu.Unlock()
+	}, nil
+}
+
+func load() (map[string]*Deployment, error) {
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return map[string]*Deployment{}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read state file: %v", err)
+	}
+
+	var file stateFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse state file %s: %v", path, err)
+	}
+	if file.Deployments == nil {
+		file.Deployments = map[string]*Deployment{}
+	}
+
+	return file.Deployments, nil
+}
+
+// Writes the state file, readable by the owner only since it holds credentials
+func save(deployments map[string]*Deployment) error {
+	path, err := Path()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create nodevin directory: %v", err)
+	}
+
+	data, err := json.MarshalIndent(stateFile{Deployments: deployments}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %v", err)
+	}
+
+	if err := utils.WriteFileAtomic(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write state file: %v", err)
+	}
+	return nil
+}
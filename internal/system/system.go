@@ -0,0 +1,91 @@
+/*
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 The Nodevin Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package system
+
+import (
+	"runtime"
+	"strings"
+)
+
+// DiskStats describes the filesystem holding a path.
+type DiskStats struct {
+	TotalBytes     uint64
+	AvailableBytes uint64
+	TotalInodes    uint64 // 0 when the filesystem has no fixed inode table
+	FreeInodes     uint64
+	FSType         string
+	// Identifies the filesystem, so paths on the same one can be told apart from
+	// paths on another of the same size: st_dev, or the volume serial number on Windows
+	Device string
+}
+
+// Filesystems that are known to corrupt or badly degrade LevelDB chain databases
+var unsupportedFilesystems = map[string]string{
+	"nfs":    "network filesystem",
+	"nfs4":   "network filesystem",
+	"cifs":   "network filesystem",
+	"smb":    "network filesystem",
+	"smb2":   "network filesystem",
+	"smbfs":  "network filesystem",
+	"afpfs":  "network filesystem",
+	"webdav": "network filesystem",
+	"msdos":  "FAT filesystem",
+	"vfat":   "FAT filesystem",
+	"fat32":  "FAT filesystem",
+	"exfat":  "exFAT filesystem",
+}
+
+// Filesystems that may work but are frequently slow or unreliable for chain data
+var discouragedFilesystems = map[string]string{
+	"fuse":    "FUSE filesystem",
+	"fuseblk": "FUSE filesystem",
+	"ntfs":    "NTFS filesystem",
+}
+
+// Returns filesystem statistics for the directory at path.
+func GetDiskStats(path string) (DiskStats, error) {
+	return diskStats(path)
+}
+
+// Returns the total physical memory of the host in bytes.
+func GetTotalMemory() (uint64, error) {
+	return totalMemory()
+}
+
+// Reports whether the disk holding path is rotational (HDD) rather than solid state.
+func IsRotational(path string) (bool, error) {
+	return isRotational(path)
+}
+
+// Returns the number of logical CPUs available to nodevin.
+func GetCPUCount() int {
+	return runtime.NumCPU()
+}
+
+// Reports whether a filesystem type is unsuitable for chain data, along with the reason.
+func IsUnsupportedFilesystem(fsType string) (string, bool) {
+	reason, exists := unsupportedFilesystems[strings.ToLower(fsType)]
+	return reason, exists
+}
+
+// Reports whether a filesystem type is discouraged for chain data, along with the reason.
+func IsDiscouragedFilesystem(fsType string) (string, bool) {
+	reason, exists := discouragedFilesystems[strings.ToLower(fsType)]
+	return reason, exists
+}
@@ -0,0 +1,60 @@
+//go:build darwin
+
+/*
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 The Nodevin Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package system
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+func diskStats(path string) (DiskStats, error) {
+	var stat unix.Statfs_t
+	if err := unix.Statfs(path, &stat); err != nil {
+		return DiskStats{}, fmt.Errorf("failed to stat filesystem for %s: %w", path, err)
+	}
+
+	var fileStat unix.Stat_t
+	if err := unix.Stat(path, &fileStat); err != nil {
+		return DiskStats{}, fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+
+	return DiskStats{
+		TotalBytes:     stat.Blocks * uint64(stat.Bsize),
+		AvailableBytes: stat.Bavail * uint64(stat.Bsize),
+		TotalInodes:    stat.Files,
+		FreeInodes:     stat.Ffree,
+		FSType:         unix.ByteSliceToString(stat.Fstypename[:]),
+		Device:         fmt.Sprintf("%d", fileStat.Dev),
+	}, nil
+}
+
+func totalMemory() (uint64, error) {
+	memory, err := unix.SysctlUint64("hw.memsize")
+	if err != nil {
+		return 0, fmt.Errorf("failed to read hw.memsize: %w", err)
+	}
+	return memory, nil
+}
+
+func isRotational(path string) (bool, error) {
+	return false, fmt.Errorf("disk type detection is not supported on this platform")
+}
@@ -0,0 +1,127 @@
+//go:build linux
+
+/*
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 The Nodevin Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package system
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// Filesystem magic numbers from statfs(2)
+var linuxFilesystemTypes = map[int64]string{
+	0xEF53:     "ext4",
+	0x58465342: "xfs",
+	0x9123683E: "btrfs",
+	0x2FC12FC1: "zfs",
+	0x01021994: "tmpfs",
+	0x794C7630: "overlay",
+	0x6969:     "nfs",
+	0x517B:     "smb",
+	0xFF534D42: "cifs",
+	0xFE534D42: "smb2",
+	0x4D44:     "msdos",
+	0x2011BAB0: "exfat",
+	0x65735546: "fuse",
+	0x5346544E: "ntfs",
+	0xF2F52010: "f2fs",
+}
+
+func diskStats(path string) (DiskStats, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return DiskStats{}, fmt.Errorf("failed to stat filesystem for %s: %w", path, err)
+	}
+
+	var fileStat syscall.Stat_t
+	if err := syscall.Stat(path, &fileStat); err != nil {
+		return DiskStats{}, fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+
+	fsType, exists := linuxFilesystemTypes[int64(stat.Type)]
+	if !exists {
+		fsType = fmt.Sprintf("0x%x", stat.Type)
+	}
+
+	return DiskStats{
+		TotalBytes:     uint64(stat.Blocks) * uint64(stat.Bsize),
+		AvailableBytes: uint64(stat.Bavail) * uint64(stat.Bsize),
+		TotalInodes:    uint64(stat.Files),
+		FreeInodes:     uint64(stat.Ffree),
+		FSType:         fsType,
+		Device:         fmt.Sprintf("%d", fileStat.Dev),
+	}, nil
+}
+
+func totalMemory() (uint64, error) {
+	file, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return 0, fmt.Errorf("failed to read /proc/meminfo: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "MemTotal:") {
+			continue
+		}
+
+		var kilobytes uint64
+		if _, err := fmt.Sscanf(strings.TrimPrefix(line, "MemTotal:"), "%d", &kilobytes); err != nil {
+			return 0, fmt.Errorf("failed to parse MemTotal: %w", err)
+		}
+		return kilobytes * 1024, nil
+	}
+
+	return 0, fmt.Errorf("MemTotal not found in /proc/meminfo")
+}
+
+func isRotational(path string) (bool, error) {
+	var stat syscall.Stat_t
+	if err := syscall.Stat(path, &stat); err != nil {
+		return false, fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+
+	// Block devices are listed in sysfs by major:minor. The entry is a link into the device tree,
+	// where a partition's directory sits inside its disk's, which holds the queue.
+	deviceDir, err := filepath.EvalSymlinks(fmt.Sprintf("/sys/dev/block/%d:%d", unix.Major(uint64(stat.Dev)), unix.Minor(uint64(stat.Dev))))
+	if err != nil {
+		return false, fmt.Errorf("unable to determine disk type for %s: %w", path, err)
+	}
+
+	for _, queueFile := range []string{
+		filepath.Join(deviceDir, "queue", "rotational"),
+		filepath.Join(filepath.Dir(deviceDir), "queue", "rotational"),
+	} {
+		data, err := os.ReadFile(queueFile)
+		if err == nil {
+			return strings.TrimSpace(string(data)) == "1", nil
+		}
+	}
+
+	return false, fmt.Errorf("unable to determine disk type for %s", path)
+}
@@ -0,0 +1,38 @@
+//go:build !linux && !darwin && !windows
+
+/*
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 The Nodevin Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package system
+
+import (
+	"fmt"
+	"runtime"
+)
+
+func diskStats(path string) (DiskStats, error) {
+	return DiskStats{}, fmt.Errorf("disk inspection is not supported on %s", runtime.GOOS)
+}
+
+func totalMemory() (uint64, error) {
+	return 0, fmt.Errorf("memory inspection is not supported on %s", runtime.GOOS)
+}
+
+func isRotational(path string) (bool, error) {
+	return false, fmt.Errorf("disk type detection is not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,88 @@
+//go:build windows
+
+/*
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 The Nodevin Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package system
+
+import (
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// memoryStatusEx mirrors the Win32 MEMORYSTATUSEX structure
+type memoryStatusEx struct {
+	Length               uint32
+	MemoryLoad           uint32
+	TotalPhys            uint64
+	AvailPhys            uint64
+	TotalPageFile        uint64
+	AvailPageFile        uint64
+	TotalVirtual         uint64
+	AvailVirtual         uint64
+	AvailExtendedVirtual uint64
+}
+
+var procGlobalMemoryStatusEx = windows.NewLazySystemDLL("kernel32.dll").NewProc("GlobalMemoryStatusEx")
+
+func diskStats(path string) (DiskStats, error) {
+	pathPtr, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return DiskStats{}, err
+	}
+
+	var available, total, free uint64
+	if err := windows.GetDiskFreeSpaceEx(pathPtr, &available, &total, &free); err != nil {
+		return DiskStats{}, fmt.Errorf("failed to stat filesystem for %s: %w", path, err)
+	}
+
+	stats := DiskStats{
+		TotalBytes:     total,
+		AvailableBytes: available,
+	}
+
+	// Filesystem name is looked up on the volume root (ex: C:\)
+	volumePath := make([]uint16, windows.MAX_PATH+1)
+	if err := windows.GetVolumePathName(pathPtr, &volumePath[0], uint32(len(volumePath))); err == nil {
+		fsName := make([]uint16, windows.MAX_PATH+1)
+		var serialNumber uint32
+		if err := windows.GetVolumeInformation(&volumePath[0], nil, 0, &serialNumber, nil, nil, &fsName[0], uint32(len(fsName))); err == nil {
+			stats.FSType = windows.UTF16ToString(fsName)
+			stats.Device = fmt.Sprintf("%08x", serialNumber)
+		}
+	}
+
+	return stats, nil
+}
+
+func totalMemory() (uint64, error) {
+	status := memoryStatusEx{}
+	status.Length = uint32(unsafe.Sizeof(status))
+
+	result, _, err := procGlobalMemoryStatusEx.Call(uintptr(unsafe.Pointer(&status)))
+	if result == 0 {
+		return 0, fmt.Errorf("failed to read memory status: %w", err)
+	}
+	return status.TotalPhys, nil
+}
+
+func isRotational(path string) (bool, error) {
+	return false, fmt.Errorf("disk type detection is not supported on this platform")
+}
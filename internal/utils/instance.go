@@ -0,0 +1,64 @@
+/*
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 The Nodevin Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+var instanceNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,31}$`)
+
+// Returns the instance selected with --instance or a network/instance argument ("" for the default instance)
+func GetInstance() string {
+	return viper.GetString("instance")
+}
+
+// Checks that an instance name can be used in container, network, volume and directory names
+func ValidateInstanceName(instance string) error {
+	if instance == "" || instanceNamePattern.MatchString(instance) {
+		return nil
+	}
+	return fmt.Errorf("invalid instance name %q: use up to 32 lowercase letters, digits and dashes", instance)
+}
+
+// Appends the selected instance to a container, compose network, volume or data directory name
+func InstanceName(name string) string {
+	if instance := GetInstance(); instance != "" {
+		return name + "-" + instance
+	}
+	return name
+}
+
+// Splits a network/instance argument (ex: bitcoin/pruned) into the network and instance name
+func SplitNetworkInstance(arg string) (string, string) {
+	network, instance, _ := strings.Cut(arg, "/")
+	return network, instance
+}
+
+// Joins a network and instance name into a network/instance argument
+func JoinNetworkInstance(network string, instance string) string {
+	if instance == "" {
+		return network
+	}
+	return network + "/" + instance
+}
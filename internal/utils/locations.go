@@ -0,0 +1,129 @@
+/*
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 The Nodevin Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Name of the file (inside a network's data directory) recording where the
+// rest of that network's data lives when it is split across disks.
+const dataLocationsFileName = ".nodevin-locations.json"
+
+// DataLocation is a single host directory holding part of a network's data.
+type DataLocation struct {
+	Label string `json:"label" yaml:"label"`
+	Path  string `json:"path" yaml:"path"`
+}
+
+// Expands a leading ~ and returns an absolute path usable as a compose bind mount
+func ResolveLocalPath(path string) (string, error) {
+	if strings.HasPrefix(path, "~") {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get user home directory: %v", err)
+		}
+		path = filepath.Join(homeDir, path[1:])
+	}
+
+	return filepath.Abs(path)
+}
+
+// Records the extra (non data-dir) locations used by the software stored at localPath
+func WriteDataLocations(localPath string, extraPaths map[string]string) error {
+	locationsFile := filepath.Join(localPath, dataLocationsFileName)
+
+	if len(extraPaths) == 0 {
+		if err := os.Remove(locationsFile); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove data locations file: %v", err)
+		}
+		return nil
+	}
+
+	data, err := json.MarshalIndent(extraPaths, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode data locations: %v", err)
+	}
+
+	if err := os.WriteFile(locationsFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write data locations file: %v", err)
+	}
+
+	return nil
+}
+
+// Returns the extra locations recorded for the software stored at localPath
+func ReadDataLocations(localPath string) (map[string]string, error) {
+	data, err := os.ReadFile(filepath.Join(localPath, dataLocationsFileName))
+	if os.IsNotExist(err) {
+		return map[string]string{}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read data locations file: %v", err)
+	}
+
+	extraPaths := map[string]string{}
+	if err := json.Unmarshal(data, &extraPaths); err != nil {
+		return nil, fmt.Errorf("failed to parse data locations file: %v", err)
+	}
+
+	return extraPaths, nil
+}
+
+// Returns every host directory holding data for a network, starting with its
+// directory inside the nodevin data dir followed by any separate block or index dirs
+func GetNetworkDataLocations(network string) ([]DataLocation, error) {
+	containerName, exists := GetDefaultLocalMappedContainerName(network)
+	if !exists {
+		return nil, fmt.Errorf("unsupported blockchain network: %s", network)
+	}
+
+	nodevinDataDir, err := GetNodevinDataDir()
+	if err != nil {
+		return nil, err
+	}
+
+	return GetDataLocationsAt(filepath.Join(nodevinDataDir, containerName))
+}
+
+// Returns the data directory at localPath followed by any separate block or index dirs recorded in it
+func GetDataLocationsAt(localPath string) ([]DataLocation, error) {
+	locations := []DataLocation{{Label: "data", Path: localPath}}
+
+	extraPaths, err := ReadDataLocations(localPath)
+	if err != nil {
+		return locations, err
+	}
+
+	var labels []string
+	for label := range extraPaths {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+
+	for _, label := range labels {
+		locations = append(locations, DataLocation{Label: label, Path: extraPaths[label]})
+	}
+
+	return locations, nil
+}
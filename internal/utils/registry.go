@@ -0,0 +1,94 @@
+/*
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 The Nodevin Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package utils
+
+import (
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// Images are pulled from Docker Hub's fiftysix namespace unless --registry is given
+const defaultImageRegistry = "fiftysix/"
+
+// Returns the registry base image names are appended to, ex: fiftysix/ or registry.internal:5000/nodevin/
+func GetImageRegistry() string {
+	registry := strings.TrimSpace(viper.GetString("registry"))
+	if registry == "" {
+		return defaultImageRegistry
+	}
+	return strings.TrimSuffix(registry, "/") + "/"
+}
+
+// Returns the pull-through mirrors (registry hosts, ex: mirror.internal:5000) tried before Docker Hub
+func GetRegistryMirrors() []string {
+	var mirrors []string
+	for _, mirror := range splitList(viper.GetStringSlice("registry-mirrors")) {
+		mirror = strings.TrimSuffix(mirror, "/")
+		mirror = strings.TrimPrefix(strings.TrimPrefix(mirror, "https://"), "http://")
+		if mirror != "" {
+			mirrors = append(mirrors, mirror)
+		}
+	}
+	return mirrors
+}
+
+// Reports whether a registry host is reached over plain HTTP: localhost or one of --insecure-registries
+func IsInsecureRegistry(host string) bool {
+	hostname := host
+	if i := strings.LastIndex(host, ":"); i >= 0 && !strings.HasSuffix(host, "]") {
+		hostname = host[:i]
+	}
+	if hostname == "localhost" || hostname == "[::1]" || strings.HasPrefix(hostname, "127.") {
+		return true
+	}
+
+	for _, insecure := range splitList(viper.GetStringSlice("insecure-registries")) {
+		if insecure == host {
+			return true
+		}
+	}
+	return false
+}
+
+// Reports whether an image belongs to nodevin: it comes from the configured registry base or fiftysix/
+func IsNodevinImage(image string) bool {
+	return strings.HasPrefix(image, GetImageRegistry()) || strings.HasPrefix(image, defaultImageRegistry)
+}
+
+// Strips the registry base from an image for display, ex: registry.internal:5000/nodevin/ord:latest becomes ord:latest
+func TrimImageRegistry(image string) string {
+	if trimmed, found := strings.CutPrefix(image, GetImageRegistry()); found {
+		return trimmed
+	}
+	return strings.TrimPrefix(image, defaultImageRegistry)
+}
+
+// Splits comma separated entries, as a list set in the .env file arrives as one value
+func splitList(values []string) []string {
+	var list []string
+	for _, value := range values {
+		for _, entry := range strings.Split(value, ",") {
+			if entry = strings.TrimSpace(entry); entry != "" {
+				list = append(list, entry)
+			}
+		}
+	}
+	return list
+}
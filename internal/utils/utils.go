@@ -61,6 +61,16 @@ var networkInfoMap = map[string]NetworkInfo{
 		StartMessage:     "\"Testing is the lifeblood of innovation and security.\"",
 		CommandSupported: false,
 	},
+	"bitcoin-regtest": {
+		ContainerName:    "bitcoin-core-regtest",
+		DockerHubImage:   "bitcoin-core",
+		RPCPort:          18443,
+		SnapshotCID:      "",
+		DataSize:         0,
+		SnapshotSize:     0,
+		StartMessage:     "\"Testing is the lifeblood of innovation and security.\"",
+		CommandSupported: false,
+	},
 	"ord": {
 		ContainerName:    "ord",
 		DockerHubImage:   "ord",
@@ -141,6 +151,206 @@ var networkInfoMap = map[string]NetworkInfo{
 		StartMessage:     "\"A network of nodes working together to preserve and share data reliably.\"",
 		CommandSupported: false,
 	},
+	"lnd": {
+		ContainerName:    "lnd",
+		DockerHubImage:   "lightninglabs/lnd",
+		RPCPort:          8080,
+		SnapshotCID:      "",
+		DataSize:         5368709120, // 5 GB
+		SnapshotSize:     0,
+		StartMessage:     "\"The Lightning Network is a decentralized system for instant, high-volume micropayments.\" -- Joseph Poon and Thaddeus Dryja",
+		CommandSupported: false,
+	},
+	"lnd-testnet": {
+		ContainerName:    "lnd-testnet",
+		DockerHubImage:   "lightninglabs/lnd",
+		RPCPort:          8080,
+		SnapshotCID:      "",
+		DataSize:         0,
+		SnapshotSize:     0,
+		StartMessage:     "\"The Lightning Network is a decentralized system for instant, high-volume micropayments.\" -- Joseph Poon and Thaddeus Dryja",
+		CommandSupported: false,
+	},
+	"lnd-regtest": {
+		ContainerName:    "lnd-regtest",
+		DockerHubImage:   "lightninglabs/lnd",
+		RPCPort:          8080,
+		SnapshotCID:      "",
+		DataSize:         0,
+		SnapshotSize:     0,
+		StartMessage:     "\"The Lightning Network is a decentralized system for instant, high-volume micropayments.\" -- Joseph Poon and Thaddeus Dryja",
+		CommandSupported: false,
+	},
+	"cln": {
+		ContainerName:    "cln",
+		DockerHubImage:   "elementsproject/lightningd",
+		RPCPort:          0,
+		SnapshotCID:      "",
+		DataSize:         1073741824, // 1 GB
+		SnapshotSize:     0,
+		StartMessage:     "\"The Lightning Network is a decentralized system for instant, high-volume micropayments.\" -- Joseph Poon and Thaddeus Dryja",
+		CommandSupported: false,
+	},
+	"cln-testnet": {
+		ContainerName:    "cln-testnet",
+		DockerHubImage:   "elementsproject/lightningd",
+		RPCPort:          0,
+		SnapshotCID:      "",
+		DataSize:         0,
+		SnapshotSize:     0,
+		StartMessage:     "\"The Lightning Network is a decentralized system for instant, high-volume micropayments.\" -- Joseph Poon and Thaddeus Dryja",
+		CommandSupported: false,
+	},
+	"cln-regtest": {
+		ContainerName:    "cln-regtest",
+		DockerHubImage:   "elementsproject/lightningd",
+		RPCPort:          0,
+		SnapshotCID:      "",
+		DataSize:         0,
+		SnapshotSize:     0,
+		StartMessage:     "\"The Lightning Network is a decentralized system for instant, high-volume micropayments.\" -- Joseph Poon and Thaddeus Dryja",
+		CommandSupported: false,
+	},
+	"electrs": {
+		ContainerName:    "electrs",
+		DockerHubImage:   "getumbrel/electrs",
+		RPCPort:          50001,
+		SnapshotCID:      "",
+		DataSize:         64424509440, // 60 GB
+		SnapshotSize:     0,
+		StartMessage:     "\"Don't trust. Verify.\"",
+		CommandSupported: false,
+	},
+	"electrs-testnet": {
+		ContainerName:    "electrs-testnet",
+		DockerHubImage:   "getumbrel/electrs",
+		RPCPort:          50001,
+		SnapshotCID:      "",
+		DataSize:         0,
+		SnapshotSize:     0,
+		StartMessage:     "\"Don't trust. Verify.\"",
+		CommandSupported: false,
+	},
+	"electrs-regtest": {
+		ContainerName:    "electrs-regtest",
+		DockerHubImage:   "getumbrel/electrs",
+		RPCPort:          50001,
+		SnapshotCID:      "",
+		DataSize:         0,
+		SnapshotSize:     0,
+		StartMessage:     "\"Don't trust. Verify.\"",
+		CommandSupported: false,
+	},
+	"fulcrum": {
+		ContainerName:    "fulcrum",
+		DockerHubImage:   "cculianu/fulcrum",
+		RPCPort:          50001,
+		SnapshotCID:      "",
+		DataSize:         161061273600, // 150 GB
+		SnapshotSize:     0,
+		StartMessage:     "\"Don't trust. Verify.\"",
+		CommandSupported: false,
+	},
+	"fulcrum-testnet": {
+		ContainerName:    "fulcrum-testnet",
+		DockerHubImage:   "cculianu/fulcrum",
+		RPCPort:          50001,
+		SnapshotCID:      "",
+		DataSize:         0,
+		SnapshotSize:     0,
+		StartMessage:     "\"Don't trust. Verify.\"",
+		CommandSupported: false,
+	},
+	"fulcrum-regtest": {
+		ContainerName:    "fulcrum-regtest",
+		DockerHubImage:   "cculianu/fulcrum",
+		RPCPort:          50001,
+		SnapshotCID:      "",
+		DataSize:         0,
+		SnapshotSize:     0,
+		StartMessage:     "\"Don't trust. Verify.\"",
+		CommandSupported: false,
+	},
+	"fulcrum-litecoin": {
+		ContainerName:    "fulcrum-litecoin",
+		DockerHubImage:   "cculianu/fulcrum",
+		RPCPort:          50001,
+		SnapshotCID:      "",
+		DataSize:         32212254720, // 30 GB
+		SnapshotSize:     0,
+		StartMessage:     "\"Don't trust. Verify.\"",
+		CommandSupported: false,
+	},
+	"fulcrum-litecoin-testnet": {
+		ContainerName:    "fulcrum-litecoin-testnet",
+		DockerHubImage:   "cculianu/fulcrum",
+		RPCPort:          50001,
+		SnapshotCID:      "",
+		DataSize:         0,
+		SnapshotSize:     0,
+		StartMessage:     "\"Don't trust. Verify.\"",
+		CommandSupported: false,
+	},
+	"btc-rpc-explorer": {
+		ContainerName:    "btc-rpc-explorer",
+		DockerHubImage:   "getumbrel/btc-rpc-explorer",
+		RPCPort:          3002,
+		SnapshotCID:      "",
+		DataSize:         0,
+		SnapshotSize:     0,
+		StartMessage:     "\"Don't trust. Verify.\"",
+		CommandSupported: false,
+	},
+	"btc-rpc-explorer-testnet": {
+		ContainerName:    "btc-rpc-explorer-testnet",
+		DockerHubImage:   "getumbrel/btc-rpc-explorer",
+		RPCPort:          3002,
+		SnapshotCID:      "",
+		DataSize:         0,
+		SnapshotSize:     0,
+		StartMessage:     "\"Don't trust. Verify.\"",
+		CommandSupported: false,
+	},
+	"btc-rpc-explorer-regtest": {
+		ContainerName:    "btc-rpc-explorer-regtest",
+		DockerHubImage:   "getumbrel/btc-rpc-explorer",
+		RPCPort:          3002,
+		SnapshotCID:      "",
+		DataSize:         0,
+		SnapshotSize:     0,
+		StartMessage:     "\"Don't trust. Verify.\"",
+		CommandSupported: false,
+	},
+	"mempool": {
+		ContainerName:    "mempool",
+		DockerHubImage:   "mempool/backend",
+		RPCPort:          8999,
+		SnapshotCID:      "",
+		DataSize:         10737418240, // 10 GB
+		SnapshotSize:     0,
+		StartMessage:     "\"Don't trust. Verify.\"",
+		CommandSupported: false,
+	},
+	"mempool-testnet": {
+		ContainerName:    "mempool-testnet",
+		DockerHubImage:   "mempool/backend",
+		RPCPort:          8999,
+		SnapshotCID:      "",
+		DataSize:         0,
+		SnapshotSize:     0,
+		StartMessage:     "\"Don't trust. Verify.\"",
+		CommandSupported: false,
+	},
+	"mempool-regtest": {
+		ContainerName:    "mempool-regtest",
+		DockerHubImage:   "mempool/backend",
+		RPCPort:          8999,
+		SnapshotCID:      "",
+		DataSize:         0,
+		SnapshotSize:     0,
+		StartMessage:     "\"Don't trust. Verify.\"",
+		CommandSupported: false,
+	},
 	"dogecoin": {
 		ContainerName:    "dogecoin-core",
 		DockerHubImage:   "dogecoin-core",
@@ -199,9 +409,19 @@ func GetNetworkRequiredSnapshotSize(network string) (int, bool) {
 	return networkInfo.SnapshotSize, exists
 }
 
-func GetFiftysixDockerhubContainerName(network string) (string, bool) {
+// Returns the image repository of a network in the configured registry, ex: fiftysix/bitcoin-core.
+// Software nodevin does not build images for is named with its own repository, ex: lightninglabs/lnd.
+func GetNetworkImageRepository(network string) (string, bool) {
+	networkInfo, exists := networkInfoMap[network]
+	if strings.Contains(networkInfo.DockerHubImage, "/") {
+		return networkInfo.DockerHubImage, exists
+	}
+	return GetImageRegistry() + networkInfo.DockerHubImage, exists
+}
+
+func IsCommandSupportedNetwork(network string) bool {
 	networkInfo, exists := networkInfoMap[network]
-	return "fiftysix/" + networkInfo.DockerHubImage, exists
+	return exists && networkInfo.CommandSupported
 }
 
 func GetAllSupportedNetworks() string {
@@ -233,6 +453,37 @@ func CheckIfTestnetOrTestnetNetworkFlag() bool {
 	return testnetFlag || networkFlag == "testnet"
 }
 
+// Returns the network variant selected by --testnet or --network: mainnet, testnet or regtest
+func GetNetworkVariant() string {
+	if CheckIfTestnetOrTestnetNetworkFlag() {
+		return "testnet"
+	}
+	if viper.GetString("network") == "regtest" {
+		return "regtest"
+	}
+	return "mainnet"
+}
+
+// Returns the network of the selected variant when nodevin has one, ex: bitcoin-regtest for bitcoin
+func GetVariantNetwork(network string) string {
+	if variant := GetNetworkVariant(); variant != "mainnet" {
+		if _, exists := networkInfoMap[network+"-"+variant]; exists {
+			return network + "-" + variant
+		}
+	}
+	return network
+}
+
+// Splits a network into its software and variant, ex: lnd and testnet for lnd-testnet
+func SplitNetworkVariant(network string) (string, string) {
+	for _, variant := range []string{"testnet", "regtest"} {
+		if base, found := strings.CutSuffix(network, "-"+variant); found {
+			return base, variant
+		}
+	}
+	return network, "mainnet"
+}
+
 func GetSnapshotCIDByNetwork(network string) (string, bool) {
 	networkInfo, exists := networkInfoMap[network]
 	return networkInfo.SnapshotCID, exists
@@ -242,6 +493,12 @@ func IsSupportedExtendedInfoSoftware(software string) bool {
 	return software == "bitcoin-core" || software == "litecoin-core" || software == "dogecoin-core"
 }
 
+// Reports whether a network (mainnet or testnet) answers the bitcoin-core style RPCs used by info
+func IsSupportedExtendedInfoNetwork(network string) bool {
+	networkInfo, exists := networkInfoMap[network]
+	return exists && IsSupportedExtendedInfoSoftware(networkInfo.DockerHubImage)
+}
+
 // Returns path to the user's nodevin data directory (~/.nodevin/data)
 func GetNodevinDataDir() (string, error) {
 	homeDir, err := os.UserHomeDir()
@@ -0,0 +1,392 @@
+/*
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 The Nodevin Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package configure
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/fiftysixcrypto/nodevin/internal/config"
+	"github.com/fiftysixcrypto/nodevin/internal/logger"
+	"github.com/fiftysixcrypto/nodevin/internal/output"
+	"github.com/fiftysixcrypto/nodevin/internal/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Written to a new config file by `nodevin config edit`
+const configTemplate = `# nodevin configuration. Settings are named after their flags (see nodevin --help) and
+# are overridden by NODEVIN_ environment variables (ex: NODEVIN_DATA_DIR) and flags.
+#
+# data-dir: /mnt/nodevin
+# registry: registry.internal:5000/nodevin
+#
+# Per-network sections override the global settings for that network, its testnet
+# (ex: bitcoin-testnet) or a named instance (ex: bitcoin/pruned).
+#
+# networks:
+#   bitcoin:
+#     version: "27.0"
+#     rpc-user: admin
+`
+
+var ConfigCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Show and change the settings in config.yaml",
+}
+
+var configPathCmd = &cobra.Command{
+	Use:   "path",
+	Short: "Show which config file and .env file are in effect",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return showConfigPath()
+	},
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Print a setting of config.yaml (network settings as <network>.<key>)",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return getConfigSetting(args[0])
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>...",
+	Short: "Set a setting in config.yaml, ex: `config set data-dir /mnt/nodevin` or `config set bitcoin.set ord.mem-limit=2g ord.restart=always`",
+	Args:  cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setConfigSetting(cmd, args[0], args[1:])
+	},
+}
+
+var configUnsetCmd = &cobra.Command{
+	Use:   "unset <key>",
+	Short: "Remove a setting from config.yaml",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return unsetConfigSetting(args[0])
+	},
+}
+
+var configListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the settings of config.yaml, the .env file and NODEVIN_ environment variables",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return listConfigSettings()
+	},
+}
+
+var configEditCmd = &cobra.Command{
+	Use:   "edit",
+	Short: "Open config.yaml in $VISUAL or $EDITOR",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return editConfig()
+	},
+}
+
+// ConfigPathReport is the output of `nodevin config path` in json or yaml mode
+type ConfigPathReport struct {
+	ConfigFile string `json:"config_file" yaml:"config_file"`
+	Exists     bool   `json:"exists" yaml:"exists"`
+	EnvFile    string `json:"env_file,omitempty" yaml:"env_file,omitempty"`
+}
+
+// ConfigSetting is a setting of `nodevin config list` in json or yaml mode
+type ConfigSetting struct {
+	Key    string `json:"key" yaml:"key"`
+	Value  string `json:"value" yaml:"value"`
+	Source string `json:"source" yaml:"source"`
+	// Set for global settings a source of higher precedence replaces
+	OverriddenBy string `json:"overridden_by,omitempty" yaml:"overridden_by,omitempty"`
+}
+
+func showConfigPath() error {
+	path, err := config.Path()
+	if err != nil {
+		return err
+	}
+
+	_, exists, err := config.Load(path)
+	if err != nil {
+		return err
+	}
+
+	report := ConfigPathReport{ConfigFile: path, Exists: exists, EnvFile: config.EnvFileUsed()}
+	if output.IsStructured() {
+		return output.Print(report)
+	}
+
+	if exists {
+		fmt.Println("Config file: " + path)
+	} else {
+		fmt.Printf("Config file: %s (not created yet, `%s config set` or `%s config edit` creates it)\n", path, utils.GetNodevinExecutable(), utils.GetNodevinExecutable())
+	}
+	if report.EnvFile != "" {
+		fmt.Println(".env file:   " + report.EnvFile)
+	}
+	fmt.Printf("Precedence:  flags, %s_ environment variables, network sections, global settings, .env file\n", config.EnvPrefix)
+	return nil
+}
+
+func getConfigSetting(key string) error {
+	path, err := config.Path()
+	if err != nil {
+		return err
+	}
+
+	setting, exists, err := config.Get(path, key)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("%s is not set in %s", key, path)
+	}
+
+	if output.IsStructured() {
+		return output.Print(setting)
+	}
+	fmt.Println(setting.Value)
+	return nil
+}
+
+func setConfigSetting(cmd *cobra.Command, key string, values []string) error {
+	flag, err := lookupSettingFlag(cmd, key)
+	if err != nil {
+		return err
+	}
+
+	path, err := config.Path()
+	if err != nil {
+		return err
+	}
+
+	// List flags (ex: ports) are stored as lists so every entry is read back. Entries of
+	// array flags (ex: set) may contain commas, so each value is one entry.
+	var setting interface{}
+	if _, isList := flag.Value.(pflag.SliceValue); isList {
+		var list []string
+		for _, value := range values {
+			items := []string{value}
+			if flag.Value.Type() != "stringArray" {
+				items = strings.Split(value, ",")
+			}
+			for _, item := range items {
+				if item = strings.TrimSpace(item); item != "" {
+					list = append(list, item)
+				}
+			}
+		}
+		setting = list
+	} else if len(values) > 1 {
+		return fmt.Errorf("%s takes a single value", key)
+	} else {
+		setting = values[0]
+	}
+
+	if err := config.Set(path, key, setting); err != nil {
+		return err
+	}
+	logger.LogInfo(fmt.Sprintf("Set %s in %s", key, path))
+	return nil
+}
+
+func unsetConfigSetting(key string) error {
+	path, err := config.Path()
+	if err != nil {
+		return err
+	}
+
+	removed, err := config.Unset(path, key)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return fmt.Errorf("%s is not set in %s", key, path)
+	}
+	logger.LogInfo(fmt.Sprintf("Removed %s from %s", key, path))
+	return nil
+}
+
+// Checks that a key names a flag, in the global settings or a section of a supported network
+func lookupSettingFlag(cmd *cobra.Command, key string) (*pflag.Flag, error) {
+	name := key
+	if section, setting, found := strings.Cut(key, "."); found {
+		network, _ := utils.SplitNetworkInstance(section)
+		base, _ := utils.SplitNetworkVariant(network)
+		if _, exists := utils.GetNetworkImageRepository(base); !exists {
+			return nil, fmt.Errorf("unknown network %s in %s, nodevin supports: %s", network, key, utils.GetCommandSupportedNetworks())
+		}
+		name = setting
+	}
+
+	flags := map[string]*pflag.Flag{}
+	var collect func(*cobra.Command)
+	collect = func(c *cobra.Command) {
+		c.Flags().VisitAll(func(flag *pflag.Flag) {
+			// Flags renamed in a network's tree (ex: --cluster) are stored under their setting (ex: ipfs-cluster)
+			if setting, exists := flag.Annotations[config.SettingAnnotation]; exists {
+				flags[setting[0]] = flag
+				return
+			}
+			flags[flag.Name] = flag
+		})
+		for _, child := range c.Commands() {
+			collect(child)
+		}
+	}
+	collect(cmd.Root())
+
+	flag, exists := flags[name]
+	if !exists || name == "help" {
+		return nil, fmt.Errorf("unknown setting %s, settings are named after nodevin's flags (ex: data-dir, version)", name)
+	}
+	return flag, nil
+}
+
+func listConfigSettings() error {
+	path, err := config.Path()
+	if err != nil {
+		return err
+	}
+
+	fileSettings, err := config.List(path)
+	if err != nil {
+		return err
+	}
+
+	var settings []ConfigSetting
+	fileKeys := map[string]bool{}
+	for _, setting := range fileSettings {
+		source := path
+		if section, _, found := strings.Cut(setting.Key, "."); found {
+			source = fmt.Sprintf("%s [%s]", path, section)
+		}
+		fileKeys[setting.Key] = true
+		settings = append(settings, ConfigSetting{Key: setting.Key, Value: setting.Value, Source: source})
+	}
+
+	envSettings := config.EnvSettings()
+	envKeys := map[string]string{}
+	for variable, setting := range envSettings {
+		envKeys[setting.Key] = variable
+		settings = append(settings, ConfigSetting{Key: setting.Key, Value: setting.Value, Source: "environment " + variable})
+	}
+
+	for _, setting := range config.EnvFileSettings() {
+		settings = append(settings, ConfigSetting{Key: setting.Key, Value: setting.Value, Source: config.EnvFileUsed()})
+	}
+
+	// Mark global settings replaced by a source of higher precedence
+	for i, setting := range settings {
+		if strings.Contains(setting.Key, ".") || strings.HasPrefix(setting.Source, "environment ") {
+			continue
+		}
+		if variable, exists := envKeys[setting.Key]; exists {
+			settings[i].OverriddenBy = "environment " + variable
+		} else if setting.Source == config.EnvFileUsed() && fileKeys[setting.Key] {
+			settings[i].OverriddenBy = path
+		}
+	}
+
+	sort.SliceStable(settings, func(i, j int) bool { return settings[i].Key < settings[j].Key })
+
+	if output.IsStructured() {
+		return output.Print(settings)
+	}
+
+	if len(settings) == 0 {
+		logger.LogInfo(fmt.Sprintf("No settings found. Add some with `%s config set <key> <value>`.", utils.GetNodevinExecutable()))
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', tabwriter.Debug)
+	fmt.Fprintln(w, "| KEY\t VALUE\t SOURCE\t NOTE")
+	for _, setting := range settings {
+		note := "-"
+		if setting.OverriddenBy != "" {
+			note = "overridden by " + setting.OverriddenBy
+		}
+		fmt.Fprintf(w, "| %s\t %s\t %s\t %s\n", setting.Key, setting.Value, setting.Source, note)
+	}
+	w.Flush()
+	return nil
+}
+
+func editConfig() error {
+	path, err := config.Path()
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("failed to create config directory: %w", err)
+		}
+		if err := os.WriteFile(path, []byte(configTemplate), 0644); err != nil {
+			return fmt.Errorf("failed to create config file: %w", err)
+		}
+	}
+
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+		if runtime.GOOS == "windows" {
+			editor = "notepad"
+		}
+	}
+
+	// Editors are often given with arguments, ex: "code --wait"
+	parts := strings.Fields(editor)
+	cmd := exec.Command(parts[0], append(parts[1:], path)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to run %s: %w", editor, err)
+	}
+
+	if _, _, err := config.Load(path); err != nil {
+		return fmt.Errorf("%w, fix it with `%s config edit`", err, utils.GetNodevinExecutable())
+	}
+	logger.LogInfo("Saved " + path)
+	return nil
+}
+
+func init() {
+	ConfigCmd.AddCommand(configPathCmd)
+	ConfigCmd.AddCommand(configGetCmd)
+	ConfigCmd.AddCommand(configSetCmd)
+	ConfigCmd.AddCommand(configUnsetCmd)
+	ConfigCmd.AddCommand(configListCmd)
+	ConfigCmd.AddCommand(configEditCmd)
+}
@@ -0,0 +1,164 @@
+/*
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 The Nodevin Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package compose
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/fiftysixcrypto/nodevin/internal/utils"
+	"github.com/spf13/viper"
+)
+
+// Node an add-on's service connects to, ex: bitcoin-core-testnet for lnd-testnet
+type addonBackend struct {
+	variant   string // mainnet, testnet or regtest
+	software  string // node's container without the instance, ex: bitcoin-core-testnet
+	container string
+	rpcPort   int
+	p2pPort   int
+	net       string // compose network shared with the node
+	mount     string // container path of the node's volume, ex: /node/bitcoin-core
+	volume    string // the node's volume, mounted at the same path in the add-on
+	dataDir   string // the node's data directory for the variant, ex: /node/bitcoin-core/data/testnet3
+}
+
+// Data sub-directory and P2P port of every node network add-ons connect to
+var backendNetworks = map[string]struct {
+	dataDir string
+	p2pPort int
+}{
+	"bitcoin":          {"", 8333},
+	"bitcoin-testnet":  {"testnet3", 18333},
+	"bitcoin-regtest":  {"regtest", 18444},
+	"litecoin":         {"", 9333},
+	"litecoin-testnet": {"testnet4", 19335},
+}
+
+// Returns the node of nodeNetwork (ex: bitcoin) an add-on's service network (ex: lnd-regtest) runs alongside
+func getAddonBackend(nodeNetwork string, network string) (addonBackend, error) {
+	_, variant := utils.SplitNetworkVariant(network)
+	if variant != "mainnet" {
+		nodeNetwork = nodeNetwork + "-" + variant
+	}
+
+	backend, known := backendNetworks[nodeNetwork]
+	container, exists := utils.GetDefaultLocalMappedContainerName(nodeNetwork)
+	if !known || !exists {
+		return addonBackend{}, fmt.Errorf("unknown network: %s", network)
+	}
+
+	nodevinDataDir, err := utils.GetNodevinDataDir()
+	if err != nil {
+		return addonBackend{}, err
+	}
+
+	software, _ := utils.SplitNetworkVariant(container)
+	mount := "/node/" + software
+	return addonBackend{
+		variant:   variant,
+		software:  container,
+		container: utils.InstanceName(container),
+		rpcPort:   utils.NetworkDefaultRPCPorts()[nodeNetwork],
+		p2pPort:   backend.p2pPort,
+		net:       utils.InstanceName(nodeNetwork + "-net"),
+		mount:     mount,
+		volume:    fmt.Sprintf("%s:%s", filepath.Join(nodevinDataDir, utils.InstanceName(container), software), mount),
+		dataDir:   path.Join(mount, "data", backend.dataDir),
+	}, nil
+}
+
+// Returns the node's RPC cookie file, used with --cookie-auth
+func (b addonBackend) cookie() string {
+	return path.Join(b.dataDir, ".cookie")
+}
+
+// Returns the RPC user and password the node runs with, empty with --cookie-auth
+func getNodeRPCCredentials() (string, string) {
+	if viper.GetBool("cookie-auth") {
+		return "", ""
+	}
+
+	rpcUsername := viper.GetString("rpc-user")
+	rpcPassword := viper.GetString("rpc-pass")
+
+	if rpcUsername == "" {
+		rpcUsername = "user"
+	}
+
+	if rpcPassword == "" {
+		rpcPassword = "fiftysix"
+	}
+	return rpcUsername, rpcPassword
+}
+
+// Returns the base service of an add-on on its node's compose network, with the node's volume and
+// its own data (mounted at dataPath) in the nodevin data dir
+func getAddonBaseConfig(network string, dataPath string, backend addonBackend) (NetworkConfig, error) {
+	// Get base nodevin data directory
+	nodevinDataDir, err := utils.GetNodevinDataDir()
+	if err != nil {
+		return NetworkConfig{}, err
+	}
+
+	localPath := filepath.Join(nodevinDataDir, utils.InstanceName(network)) // nodevin data dir, software type
+
+	return NetworkConfig{
+		Network:       network,
+		ContainerName: utils.InstanceName(network),
+		Restart:       "always",
+		Volumes: []string{
+			backend.volume,
+			fmt.Sprintf("%s:%s", filepath.Join(localPath, filepath.Base(dataPath)), dataPath),
+		},
+		Networks: []string{backend.net},
+		NetworkDefs: map[string]NetworkDetails{
+			backend.net: {
+				Driver: "bridge",
+			},
+		},
+		VolumeDefs:   map[string]VolumeDetails{},
+		LocalPath:    localPath,
+		SkipDataInit: true,
+	}, nil
+}
+
+// Holds an add-on's service back until the node has synced, run is the add-on's executable
+// the command is given to, ex: electrs
+func (b addonBackend) applySyncGate(config *NetworkConfig, dataPath string, run string) {
+	software, _ := utils.SplitNetworkVariant(b.software)
+	check := fmt.Sprintf("%s-cli -rpcconnect=%s -rpcport=%d", strings.TrimSuffix(software, "-core"), b.container, b.rpcPort)
+	if b.variant != "mainnet" {
+		check = fmt.Sprintf("%s -%s", check, b.variant)
+	}
+	if user, password := getNodeRPCCredentials(); user != "" {
+		check = fmt.Sprintf("%s -rpcuser=%s -rpcpassword=%s", check, user, password)
+	} else {
+		check = fmt.Sprintf("%s -datadir=%s/data", check, b.mount)
+	}
+
+	config.SyncGate = &SyncGate{
+		Check: check + " getblockchaininfo",
+		Done:  path.Join(dataPath, ".node-synced"),
+	}
+	config.Entrypoint = "/bin/sh"
+	config.Command = fmt.Sprintf(`-c "until [ -f %s ]; do sleep 30; done; exec %s %s"`, config.SyncGate.Done, run, config.Command)
+}
@@ -0,0 +1,496 @@
+/*
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 The Nodevin Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package compose
+
+import (
+	"fmt"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/fiftysixcrypto/nodevin/internal/utils"
+	"github.com/spf13/viper"
+)
+
+// Addon is a service nodevin runs alongside a node, ex: ord next to bitcoin. Add-ons are
+// enabled with --addon <name> or their own flag (ex: --ord) and written as extra services
+// of the node's compose file.
+type Addon struct {
+	// Name given to --addon and to the add-on's flag, ex: ord for both ord and ord-litecoin
+	Name string
+	// Compose service, also the prefix of the add-on's settings (ex: ord-litecoin-image)
+	Service string
+	// Usage of the add-on's flag
+	Usage string
+	// Networks whose nodes the add-on runs alongside
+	Networks []string
+	// Architectures (GOARCH) the add-on's image is built for, every one when empty
+	Architectures []string
+	// Settings the node must run with for the add-on to work, ex: -txindex=1
+	DaemonArgs []string
+	// Container paths of the node's volumes the add-on mounts as well, ex: /node/bitcoin-core
+	SharedVolumes []string
+	// Image repository in the configured registry, or a full repository (ex: lightninglabs/lnd),
+	// its default tag (latest when empty) and the ports the add-on publishes
+	Image   string
+	Version string
+	Ports   []string
+	// Add-ons that cannot run alongside the same node, ex: lnd and cln
+	Conflicts []string
+	// Further services the add-on runs, ex: mempool's web frontend and database
+	Companions []AddonCompanion
+
+	// Returns the rest of the add-on's service configuration for a service network, ex: ord-testnet
+	config func(network string) (NetworkConfig, error)
+}
+
+// AddonCompanion is a further service of an add-on, written, pulled and pinned with it. Each
+// runs as its own service network (ex: mempool-db-testnet) and its image is a full repository.
+type AddonCompanion struct {
+	Service string
+	Image   string
+	Version string
+	Ports   []string
+
+	config func(network string) (NetworkConfig, error)
+}
+
+// Every add-on nodevin can run, in the order their services are written
+var addons = []Addon{
+	{
+		Name:          "ord",
+		Service:       "ord",
+		Usage:         "Run ordinal software ord alongside the Bitcoin node",
+		Networks:      []string{"bitcoin"},
+		Architectures: []string{"amd64"},
+		SharedVolumes: []string{"/node/bitcoin-core"},
+		Image:         "ord",
+		Ports:         []string{"80:80"},
+		config:        getOrdNetworkComposeConfig,
+	},
+	{
+		Name:          "ord",
+		Service:       "ord-litecoin",
+		Usage:         "Run ordinal software ord alongside the Litecoin node",
+		Networks:      []string{"litecoin"},
+		Architectures: []string{"amd64"},
+		SharedVolumes: []string{"/node/litecoin-core"},
+		Image:         "ord-litecoin",
+		Ports:         []string{"80:80"},
+		config:        getOrdLitecoinNetworkComposeConfig,
+	},
+	{
+		Name:          "cluster",
+		Service:       "ipfs-cluster",
+		Usage:         "Run ipfs-cluster alongside the IPFS node",
+		Networks:      []string{"ipfs"},
+		Architectures: []string{"amd64"},
+		SharedVolumes: []string{"/node/ipfs"},
+		Image:         "ipfs-cluster",
+		Ports:         []string{"9094:9094", "9096:9096"},
+		config:        getIpfsClusterNetworkComposeConfig,
+	},
+	{
+		Name:          "lnd",
+		Service:       "lnd",
+		Usage:         "Run the LND Lightning node alongside the Bitcoin node",
+		Networks:      []string{"bitcoin"},
+		DaemonArgs:    []string{"-zmqpubrawblock=tcp://0.0.0.0:28332", "-zmqpubrawtx=tcp://0.0.0.0:28333"},
+		SharedVolumes: []string{"/node/bitcoin-core"},
+		Image:         "lightninglabs/lnd",
+		Version:       "v0.18.3-beta",
+		Ports:         []string{"9735:9735", "10009:10009", "8082:8080"},
+		Conflicts:     []string{"cln"},
+		config:        getLndNetworkComposeConfig,
+	},
+	{
+		Name:          "cln",
+		Service:       "cln",
+		Usage:         "Run the Core Lightning node alongside the Bitcoin node",
+		Networks:      []string{"bitcoin"},
+		SharedVolumes: []string{"/node/bitcoin-core"},
+		Image:         "elementsproject/lightningd",
+		Version:       "v24.08",
+		Ports:         []string{"9735:9735"},
+		Conflicts:     []string{"lnd"},
+		config:        getClnNetworkComposeConfig,
+	},
+	{
+		Name:          "electrs",
+		Service:       "electrs",
+		Usage:         "Run the electrs Electrum server alongside the Bitcoin node",
+		Networks:      []string{"bitcoin"},
+		SharedVolumes: []string{"/node/bitcoin-core"},
+		Image:         "getumbrel/electrs",
+		Version:       "v0.10.6",
+		Ports:         []string{"50001:50001"},
+		Conflicts:     []string{"fulcrum"},
+		config:        getElectrsNetworkComposeConfig,
+	},
+	{
+		Name:          "fulcrum",
+		Service:       "fulcrum",
+		Usage:         "Run the Fulcrum Electrum server alongside the Bitcoin node",
+		Networks:      []string{"bitcoin"},
+		DaemonArgs:    []string{"-txindex=1"},
+		SharedVolumes: []string{"/node/bitcoin-core"},
+		Image:         "cculianu/fulcrum",
+		Version:       "v1.11.1",
+		Ports:         []string{"50001:50001", "50002:50002"},
+		Conflicts:     []string{"electrs"},
+		config:        getFulcrumNetworkComposeConfig,
+	},
+	{
+		Name:          "btc-rpc-explorer",
+		Service:       "btc-rpc-explorer",
+		Usage:         "Run the btc-rpc-explorer block explorer alongside the Bitcoin node",
+		Networks:      []string{"bitcoin"},
+		SharedVolumes: []string{"/node/bitcoin-core"},
+		Image:         "getumbrel/btc-rpc-explorer",
+		Version:       "v3.4.0",
+		Ports:         []string{"3002:3002"},
+		config:        getBtcRpcExplorerNetworkComposeConfig,
+	},
+	{
+		Name:          "mempool",
+		Service:       "mempool",
+		Usage:         "Run the mempool.space block explorer alongside the Bitcoin node",
+		Networks:      []string{"bitcoin"},
+		SharedVolumes: []string{"/node/bitcoin-core"},
+		Image:         "mempool/backend",
+		Version:       "v3.0.0",
+		Companions: []AddonCompanion{
+			{
+				Service: "mempool-web",
+				Image:   "mempool/frontend",
+				Version: "v3.0.0",
+				Ports:   []string{"4080:8080"},
+				config:  getMempoolWebNetworkComposeConfig,
+			},
+			{
+				Service: "mempool-db",
+				Image:   "mariadb",
+				Version: "10.5.21",
+				config:  getMempoolDbNetworkComposeConfig,
+			},
+		},
+		config: getMempoolNetworkComposeConfig,
+	},
+	{
+		Name:          "fulcrum",
+		Service:       "fulcrum-litecoin",
+		Usage:         "Run the Fulcrum Electrum server alongside the Litecoin node",
+		Networks:      []string{"litecoin"},
+		DaemonArgs:    []string{"-txindex=1"},
+		SharedVolumes: []string{"/node/litecoin-core"},
+		Image:         "cculianu/fulcrum",
+		Version:       "v1.11.1",
+		Ports:         []string{"50011:50001", "50012:50002"}, // Not 50001/50002, so it runs next to bitcoin's Fulcrum
+		config:        getFulcrumNetworkComposeConfig,
+	},
+}
+
+// Returns every add-on, ex: for commands that take any network
+func GetAddons() []Addon {
+	return addons
+}
+
+// Returns the add-ons that run alongside a network's node
+func GetNetworkAddons(network string) []Addon {
+	var networkAddons []Addon
+	for _, addon := range addons {
+		if slices.Contains(addon.Networks, network) {
+			networkAddons = append(networkAddons, addon)
+		}
+	}
+	return networkAddons
+}
+
+// Finds an add-on of a network by its name or service, ex: ord or ord-litecoin for litecoin
+func FindAddon(network string, name string) (Addon, bool) {
+	for _, addon := range GetNetworkAddons(network) {
+		if addon.Name == name || addon.Service == name {
+			return addon, true
+		}
+	}
+	return Addon{}, false
+}
+
+// Returns the node network an add-on's service network runs alongside, ex: bitcoin-testnet for electrs-testnet
+func GetAddonNodeNetwork(serviceNetwork string) (string, bool) {
+	service, variant := utils.SplitNetworkVariant(serviceNetwork)
+	for _, addon := range addons {
+		if addon.Service == service {
+			if variant == "mainnet" {
+				return addon.Networks[0], true
+			}
+			return addon.Networks[0] + "-" + variant, true
+		}
+	}
+	return "", false
+}
+
+// Reports whether a service network belongs to an add-on's companion, ex: mempool-db-testnet
+func IsAddonCompanion(serviceNetwork string) bool {
+	service, _ := utils.SplitNetworkVariant(serviceNetwork)
+	for _, addon := range addons {
+		for _, companion := range addon.Companions {
+			if companion.Service == service {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Returns the names of a network's add-ons, ex: ord
+func GetNetworkAddonNames(network string) []string {
+	var names []string
+	for _, addon := range GetNetworkAddons(network) {
+		names = append(names, addon.Name)
+	}
+	return names
+}
+
+// Returns the add-ons requested for a network's node with --addon or their own flags,
+// rejecting those that do not run alongside it
+func GetRequestedAddons(network string) ([]Addon, error) {
+	var requested []Addon
+	add := func(addon Addon) {
+		if !slices.ContainsFunc(requested, func(a Addon) bool { return a.Service == addon.Service }) {
+			requested = append(requested, addon)
+		}
+	}
+
+	for _, name := range viper.GetStringSlice("addon") {
+		addon, exists := FindAddon(network, strings.TrimSpace(name))
+		if !exists {
+			names := strings.Join(GetNetworkAddonNames(network), ", ")
+			if names == "" {
+				names = "none"
+			}
+			return nil, fmt.Errorf("add-on %s does not run alongside %s, add-ons of %s are: %s", name, network, network, names)
+		}
+		add(addon)
+	}
+
+	// Earlier flags are kept, ex: --ord for ord-litecoin
+	for _, addon := range GetNetworkAddons(network) {
+		if viper.GetBool(addon.Service) || viper.GetBool(addon.Name) {
+			add(addon)
+		}
+	}
+
+	for _, addon := range requested {
+		// An add-on without a service for the node's variant would run against mainnet
+		if variant := utils.GetNetworkVariant(); utils.GetVariantNetwork(network) != network && addon.ServiceNetwork() == addon.Service {
+			return nil, fmt.Errorf("add-on %s does not support %s", addon.Name, variant)
+		}
+		for _, other := range requested {
+			if slices.Contains(addon.Conflicts, other.Name) {
+				return nil, fmt.Errorf("add-ons %s and %s cannot run together", addon.Name, other.Name)
+			}
+		}
+	}
+
+	return requested, nil
+}
+
+// Returns the requested add-ons of a network whose images are built for this architecture
+func GetEnabledAddons(network string) ([]Addon, error) {
+	requested, err := GetRequestedAddons(network)
+	if err != nil {
+		return nil, err
+	}
+
+	var enabled []Addon
+	for _, addon := range requested {
+		if addon.IsSupportedArchitecture() {
+			enabled = append(enabled, addon)
+		}
+	}
+	return enabled, nil
+}
+
+// Reports whether the add-on's image is built for this architecture
+func (a Addon) IsSupportedArchitecture() bool {
+	return len(a.Architectures) == 0 || slices.Contains(a.Architectures, runtime.GOARCH)
+}
+
+// Returns the network the add-on's service runs as, ex: ord-testnet with --testnet
+func (a Addon) ServiceNetwork() string {
+	return utils.GetVariantNetwork(a.Service)
+}
+
+// Returns the add-on's default image repository, ex: fiftysix/ord or lightninglabs/lnd
+func (a Addon) Repository() string {
+	if strings.Contains(a.Image, "/") {
+		return a.Image
+	}
+	return utils.GetImageRegistry() + a.Image
+}
+
+// Returns the add-on's default image tag
+func (a Addon) DefaultVersion() string {
+	if a.Version == "" {
+		return "latest"
+	}
+	return a.Version
+}
+
+// Returns the add-on's image from --<service>-image and --<service>-version, its repository and default tag otherwise
+func (a Addon) GetImage() string {
+	return getServiceImage(a.Service, a.Repository(), a.DefaultVersion())
+}
+
+// Returns the compose services the add-on writes, ex: mempool, mempool-web and mempool-db
+func (a Addon) Services() []string {
+	services := []string{a.Service}
+	for _, companion := range a.Companions {
+		services = append(services, companion.Service)
+	}
+	return services
+}
+
+// Returns the images of the add-on and its companions keyed by service network, ex: mempool-web-testnet
+func (a Addon) GetImages() map[string]string {
+	images := map[string]string{a.ServiceNetwork(): a.GetImage()}
+	for _, companion := range a.Companions {
+		images[a.companionNetwork(companion)] = getServiceImage(companion.Service, companion.Image, companion.Version)
+	}
+	return images
+}
+
+// Returns the service network a companion runs as, in the add-on's variant
+func (a Addon) companionNetwork(companion AddonCompanion) string {
+	if _, variant := utils.SplitNetworkVariant(a.ServiceNetwork()); variant != "mainnet" {
+		return companion.Service + "-" + variant
+	}
+	return companion.Service
+}
+
+// Returns a service's image from --<service>-image and --<service>-version, repository:version otherwise
+func getServiceImage(service string, repository string, version string) string {
+	if image := viper.GetString(service + "-image"); image != "" {
+		repository = image
+	}
+	if tag := viper.GetString(service + "-version"); tag != "" {
+		version = tag
+	}
+	return repository + ":" + version
+}
+
+// Returns the service configuration of an add-on run on its own, ex: nodevin ord start
+func GetAddonComposeConfig(service string, network string) (NetworkConfig, error) {
+	for _, addon := range addons {
+		if addon.Service == service {
+			return addon.composeConfig(network)
+		}
+	}
+	return NetworkConfig{}, fmt.Errorf("unknown add-on: %s", service)
+}
+
+// Returns the service names and configurations of add-ons run alongside a node. The node's
+// command gets the settings the add-ons need and its shared volumes are mounted in them.
+func GetAddonComposeConfigs(enabled []Addon, node *NetworkConfig) ([]string, []NetworkConfig, error) {
+	var names []string
+	var configs []NetworkConfig
+	for _, addon := range enabled {
+		config, err := addon.composeConfig(addon.ServiceNetwork())
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, sharedPath := range addon.SharedVolumes {
+			config.Volumes = slices.DeleteFunc(config.Volumes, func(volume string) bool {
+				return volumeTarget(volume) == sharedPath
+			})
+			for _, volume := range node.Volumes {
+				if volumeTarget(volume) == sharedPath {
+					config.Volumes = append([]string{volume}, config.Volumes...)
+				}
+			}
+		}
+
+		for _, arg := range addon.DaemonArgs {
+			if !slices.Contains(strings.Fields(node.Command), arg) {
+				node.Command = node.Command + " " + arg
+			}
+		}
+
+		names = append(names, addon.Service)
+		configs = append(configs, config)
+
+		for _, companion := range addon.Companions {
+			config, err := companion.config(addon.companionNetwork(companion))
+			if err != nil {
+				return nil, nil, err
+			}
+			config.Image = companion.Image
+			config.Version = companion.Version
+			config.Ports = addonPorts(companion.Ports, addon.companionNetwork(companion))
+
+			names = append(names, companion.Service)
+			configs = append(configs, config)
+		}
+	}
+	return names, configs, nil
+}
+
+// Returns the service configuration of the add-on with its declared image and ports
+func (a Addon) composeConfig(network string) (NetworkConfig, error) {
+	config, err := a.config(network)
+	if err != nil {
+		return NetworkConfig{}, err
+	}
+	config.Image = a.Repository()
+	config.Version = a.DefaultVersion()
+	config.Ports = addonPorts(a.Ports, network)
+	return config, nil
+}
+
+// Host port shift of add-ons by network variant, so the same add-on can run for each variant side by side
+var addonVariantPortOffsets = map[string]int{
+	"mainnet": 0,
+	"testnet": 1000,
+	"regtest": 2000,
+}
+
+// Returns an add-on's port mappings for a service network (ex: lnd-testnet publishes 10735:9735),
+// further shifted by --port-offset for named instances
+func addonPorts(ports []string, network string) []string {
+	_, variant := utils.SplitNetworkVariant(network)
+
+	shifted := make([]string, 0, len(ports))
+	for _, mapping := range ports {
+		shifted = append(shifted, OffsetHostPort(mapping, addonVariantPortOffsets[variant]))
+	}
+	return instancePorts(shifted)
+}
+
+// Returns the container path of a "host:container[:mode]" volume, ex: /node/bitcoin-core.
+// Host paths may contain colons themselves (ex: C:\nodevin), container paths start with a slash.
+func volumeTarget(volume string) string {
+	target := volume
+	if i := strings.LastIndex(volume, ":/"); i >= 0 {
+		target = volume[i+1:]
+	}
+	target, _, _ = strings.Cut(target, ":")
+	return target
+}
@@ -35,13 +35,14 @@ func GetBitcoinNetworkComposeConfig(network string) (NetworkConfig, error) {
 
 	// Define the base configuration for the Bitcoin network
 	baseConfig := NetworkConfig{
-		Image:    "fiftysix/bitcoin-core",
+		Network:  network,
+		Image:    utils.GetImageRegistry() + "bitcoin-core",
 		Version:  "latest",
-		Ports:    []string{"8332:8332", "8333:8333"},
+		Ports:    instancePorts([]string{"8332:8332", "8333:8333"}),
 		Volumes:  []string{},
-		Networks: []string{"bitcoin-net"},
+		Networks: []string{utils.InstanceName("bitcoin-net")},
 		NetworkDefs: map[string]NetworkDetails{
-			"bitcoin-net": {
+			utils.InstanceName("bitcoin-net"): {
 				Driver: "bridge",
 			},
 		},
@@ -57,13 +58,13 @@ func GetBitcoinNetworkComposeConfig(network string) (NetworkConfig, error) {
 	// Set the container name and command based on the network
 	switch network {
 	case "bitcoin":
-		localPath := filepath.Join(nodevinDataDir, "bitcoin-core")     // nodevin data dir, software type
-		localChainDataPath := filepath.Join(localPath, "bitcoin-core") // on-image data dir
-		baseConfig.ContainerName = "bitcoin-core"
+		localPath := filepath.Join(nodevinDataDir, utils.InstanceName("bitcoin-core")) // nodevin data dir, software type
+		localChainDataPath := filepath.Join(localPath, "bitcoin-core")                 // on-image data dir
+		baseConfig.ContainerName = utils.InstanceName("bitcoin-core")
 		baseConfig.Command = "bitcoind --server=1 --rpcbind=0.0.0.0 --rpcport=8332 --rpcallowip=0.0.0.0/0"
 		baseConfig.Volumes = []string{fmt.Sprintf("%s:/node/bitcoin-core", localChainDataPath)}
 		baseConfig.VolumeDefs = map[string]VolumeDetails{
-			"bitcoin-core-data": {
+			utils.InstanceName("bitcoin-core-data"): {
 				Labels: map[string]string{
 					"nodevin.blockchain.software": "bitcoin-core",
 				},
@@ -75,20 +76,20 @@ func GetBitcoinNetworkComposeConfig(network string) (NetworkConfig, error) {
 		baseConfig.LocalChainDataPath = "/nodevin-volume/bitcoin-core/data"
 
 	case "bitcoin-testnet":
-		localPath := filepath.Join(nodevinDataDir, "bitcoin-core-testnet") // nodevin data dir, software type
-		localChainDataPath := filepath.Join(localPath, "bitcoin-core")     // on-image data dir
-		baseConfig.ContainerName = "bitcoin-core-testnet"
+		localPath := filepath.Join(nodevinDataDir, utils.InstanceName("bitcoin-core-testnet")) // nodevin data dir, software type
+		localChainDataPath := filepath.Join(localPath, "bitcoin-core")                         // on-image data dir
+		baseConfig.ContainerName = utils.InstanceName("bitcoin-core-testnet")
 		baseConfig.Command = "bitcoind --testnet --server=1 --rpcbind=0.0.0.0 --rpcport=18332 --rpcallowip=0.0.0.0/0"
-		baseConfig.Networks = []string{"bitcoin-testnet-net"}
+		baseConfig.Networks = []string{utils.InstanceName("bitcoin-testnet-net")}
 		baseConfig.NetworkDefs = map[string]NetworkDetails{
-			"bitcoin-testnet-net": {
+			utils.InstanceName("bitcoin-testnet-net"): {
 				Driver: "bridge",
 			},
 		}
-		baseConfig.Ports = []string{"18332:18332", "18333:18333"}
+		baseConfig.Ports = instancePorts([]string{"18332:18332", "18333:18333"})
 		baseConfig.Volumes = []string{fmt.Sprintf("%s:/node/bitcoin-core", localChainDataPath)}
 		baseConfig.VolumeDefs = map[string]VolumeDetails{
-			"bitcoin-core-testnet-data": {
+			utils.InstanceName("bitcoin-core-testnet-data"): {
 				Labels: map[string]string{
 					"nodevin.blockchain.software": "bitcoin-core",
 				},
@@ -99,10 +100,40 @@ func GetBitcoinNetworkComposeConfig(network string) (NetworkConfig, error) {
 		baseConfig.SnapshotDataFilename = "bitcoin-testnet-chain-data.tar.gz"
 		baseConfig.LocalChainDataPath = "/nodevin-volume/bitcoin-core/data/testnet3"
 
+	case "bitcoin-regtest":
+		localPath := filepath.Join(nodevinDataDir, utils.InstanceName("bitcoin-core-regtest")) // nodevin data dir, software type
+		localChainDataPath := filepath.Join(localPath, "bitcoin-core")                         // on-image data dir
+		baseConfig.ContainerName = utils.InstanceName("bitcoin-core-regtest")
+		baseConfig.Command = "bitcoind --regtest --server=1 --rpcbind=0.0.0.0 --rpcport=18443 --rpcallowip=0.0.0.0/0 --fallbackfee=0.0002"
+		baseConfig.Networks = []string{utils.InstanceName("bitcoin-regtest-net")}
+		baseConfig.NetworkDefs = map[string]NetworkDetails{
+			utils.InstanceName("bitcoin-regtest-net"): {
+				Driver: "bridge",
+			},
+		}
+		baseConfig.Ports = instancePorts([]string{"18443:18443", "18444:18444"})
+		baseConfig.Volumes = []string{fmt.Sprintf("%s:/node/bitcoin-core", localChainDataPath)}
+		baseConfig.VolumeDefs = map[string]VolumeDetails{
+			utils.InstanceName("bitcoin-core-regtest-data"): {
+				Labels: map[string]string{
+					"nodevin.blockchain.software": "bitcoin-core",
+				},
+			},
+		}
+		baseConfig.LocalPath = localPath
+		baseConfig.SnapshotSyncCID = networkCID
+		baseConfig.SnapshotDataFilename = ""
+		baseConfig.LocalChainDataPath = "/nodevin-volume/bitcoin-core/data/regtest"
+
 	default:
 		return NetworkConfig{}, fmt.Errorf("unknown network: %s", network)
 	}
 
+	// Optionally keep raw block files on a separate disk
+	if err := applyBlocksDir(&baseConfig); err != nil {
+		return NetworkConfig{}, err
+	}
+
 	// Optionally add RPC authentication to the command
 	cookieAuth := viper.GetBool("cookie-auth")
 	if !cookieAuth {
@@ -120,5 +151,10 @@ func GetBitcoinNetworkComposeConfig(network string) (NetworkConfig, error) {
 		baseConfig.Command = fmt.Sprintf("%s -rpcuser=%s -rpcpassword=%s", baseConfig.Command, rpcUsername, rpcPassword)
 	}
 
+	// Optionally size resources and daemon caches from the host hardware
+	if err := applyProfile(&baseConfig, network); err != nil {
+		return NetworkConfig{}, err
+	}
+
 	return baseConfig, nil
 }
@@ -22,11 +22,12 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"runtime"
-	"strings"
 
 	"github.com/fiftysixcrypto/nodevin/internal/logger"
+	"github.com/fiftysixcrypto/nodevin/internal/sizeindex"
 	"github.com/fiftysixcrypto/nodevin/internal/utils"
+	"github.com/fiftysixcrypto/nodevin/internal/version"
+	"github.com/fiftysixcrypto/nodevin/pkg/docker"
 	"github.com/spf13/viper"
 	"gopkg.in/yaml.v3"
 )
@@ -102,7 +103,22 @@ func isDeploySet(deploy Deploy) bool {
 		deploy.Resources.Reservations.CPUs != "" ||
 		deploy.Resources.Reservations.Memory != ""
 }
-func createExtraServices(extraServiceNames []string, extraServiceConfigs []NetworkConfig, extraNetworkDefs map[string]NetworkDetails, extraVolumeDefs map[string]VolumeDetails) (map[string]Service, map[string]NetworkDetails, map[string]VolumeDetails) {
+
+// Labels identifying a generated service to info, stop and update
+func serviceLabels(network string, role string, composeFilePath string) map[string]string {
+	labels := map[string]string{
+		docker.LabelNetwork:     network,
+		docker.LabelRole:        role,
+		docker.LabelComposeFile: composeFilePath,
+		docker.LabelVersion:     version.Version,
+	}
+	if instance := utils.GetInstance(); instance != "" {
+		labels[docker.LabelInstance] = instance
+	}
+	return labels
+}
+
+func createExtraServices(extraServiceNames []string, extraServiceConfigs []NetworkConfig, extraNetworkDefs map[string]NetworkDetails, extraVolumeDefs map[string]VolumeDetails, overrides []ServiceOverride, nodeImage string, composeFilePath string) (map[string]Service, map[string]NetworkDetails, map[string]VolumeDetails) {
 	// Initialize maps to hold all services, networks, and volumes
 	services := make(map[string]Service)
 	networkDefs := make(map[string]NetworkDetails)
@@ -112,7 +128,13 @@ func createExtraServices(extraServiceNames []string, extraServiceConfigs []Netwo
 		// Dynamically generate the sub-directory for this specific image within ~/.nodevin
 		err := os.MkdirAll(extraServiceConfigs[i].LocalPath, 0755)
 		if err != nil {
-			logger.LogError(fmt.Sprintf("failed to create image-specific directory: %w", err))
+			logger.LogError(fmt.Sprintf("failed to create image-specific directory: %v", err))
+			continue
+		}
+
+		// Create and record any block or index directories stored outside ~/.nodevin
+		if err := prepareExtraDataPaths(extraServiceConfigs[i]); err != nil {
+			logger.LogError(err.Error())
 			continue
 		}
 
@@ -124,7 +146,7 @@ func createExtraServices(extraServiceNames []string, extraServiceConfigs []Netwo
 		}
 
 		// If the user specified volume info, do not start the init volume
-		if viper.IsSet(fmt.Sprintf("%s-volumes", serviceName)) {
+		if viper.IsSet(fmt.Sprintf("%s-volumes", serviceName)) || extraServiceConfigs[i].SkipDataInit {
 			filesNeedCopy = false
 		}
 
@@ -161,8 +183,14 @@ func createExtraServices(extraServiceNames []string, extraServiceConfigs []Netwo
 			SnapshotDataFilename: viper.GetString(fmt.Sprintf("%s-snapshot-sync-file-name", serviceName)),
 		}
 
-		// Merge the override configuration into the service configuration
+		// Merge the override configuration into the service configuration, then apply --set
 		finalConfig := mergeConfigs(config, override)
+		applyServiceOverrides(serviceName, &finalConfig, overrides)
+
+		// Record the resource profile in effect so info can show it
+		if err := recordProfile(finalConfig); err != nil {
+			logger.LogError("Failed to record resource profile: " + err.Error())
+		}
 
 		// Create the main service configuration
 		service := Service{
@@ -170,10 +198,14 @@ func createExtraServices(extraServiceNames []string, extraServiceConfigs []Netwo
 			ContainerName: finalConfig.ContainerName,
 			Restart:       finalConfig.Restart,
 			Command:       finalConfig.Command,
+			Entrypoint:    finalConfig.Entrypoint,
 			Ports:         finalConfig.Ports,
 			Volumes:       finalConfig.Volumes,
 			Networks:      finalConfig.Networks,
+			Environment:   finalConfig.Environment,
+			Labels:        serviceLabels(config.Network, docker.RoleSidecar, composeFilePath),
 		}
+		service.Labels[docker.LabelDataDir] = config.LocalPath
 
 		if isDeploySet(finalConfig.Deploy) {
 			service.Deploy = &Deploy{Resources: finalConfig.Deploy.Resources}
@@ -182,10 +214,29 @@ func createExtraServices(extraServiceNames []string, extraServiceConfigs []Netwo
 		// Add the main service to the services map
 		services[serviceName] = service
 
+		// Poll the node from its own image until it has synced, the service waits for the file it leaves
+		if gate := finalConfig.SyncGate; gate != nil {
+			gateContainerName := fmt.Sprintf("wait-sync-%s", finalConfig.ContainerName)
+			services[gateContainerName] = Service{
+				Image:         nodeImage,
+				ContainerName: gateContainerName,
+				Restart:       "on-failure",
+				Command: fmt.Sprintf(`/bin/sh -c "
+until %s | grep -q 'initialblockdownload.: false'; do
+  echo 'Waiting for the node to sync before starting %s';
+  sleep 60;
+done &&
+touch %s"`, gate.Check, serviceName, gate.Done),
+				Volumes:  finalConfig.Volumes,
+				Networks: finalConfig.Networks,
+				Labels:   serviceLabels(config.Network, docker.RoleInit, composeFilePath),
+			}
+		}
+
 		// Add the init container if files need to be copied
 		if filesNeedCopy {
-			initContainerName := fmt.Sprintf("init-config-%s", serviceName)
-			initVolumeName := fmt.Sprintf("%s-init-volume", serviceName)
+			initContainerName := fmt.Sprintf("init-config-%s", utils.InstanceName(serviceName))
+			initVolumeName := fmt.Sprintf("%s-init-volume", utils.InstanceName(serviceName))
 
 			initSnapshotSyncCommand := "echo 'Snapshot sync not enabled. Skipping download.'"
 
@@ -235,6 +286,7 @@ fi"`, serviceName, serviceName, serviceName, initSnapshotSyncCommand, serviceNam
 					fmt.Sprintf("%s:/nodevin-volume-%s", config.LocalPath, serviceName),
 				},
 				Entrypoint: "",
+				Labels:     serviceLabels(config.Network, docker.RoleInit, composeFilePath),
 			}
 
 			// Add the init container to the services map
@@ -279,12 +331,26 @@ func CreateComposeFile(nodeName string, config NetworkConfig, extraServiceNames
 		return "", fmt.Errorf("failed to create image-specific directory: %w", err)
 	}
 
+	// --set is checked against the services of a start before anything is pulled
+	overrides, err := GetServiceOverrides()
+	if err != nil {
+		return "", err
+	}
+
 	// Dynamically generate the sub-directory for this specific image within ~/.nodevin
 	err = os.MkdirAll(config.LocalPath, 0755)
 	if err != nil {
 		return "", fmt.Errorf("failed to create image-specific directory: %w", err)
 	}
 
+	// Create and record any block or index directories stored outside ~/.nodevin
+	if err := prepareExtraDataPaths(config); err != nil {
+		return "", err
+	}
+
+	composeFileName := fmt.Sprintf("docker-compose_%s.yml", nodeName)
+	composeFilePath := filepath.Join(nodevinDir, composeFileName)
+
 	// Check if the total size of files in imageDir is greater than 1 GB
 	filesNeedCopy := false
 	totalSize, err := getDirectorySize(config.LocalPath)
@@ -347,6 +413,12 @@ func CreateComposeFile(nodeName string, config NetworkConfig, extraServiceNames
 	}
 
 	finalConfig := mergeConfigs(config, override)
+	applyServiceOverrides(nodeName, &finalConfig, overrides)
+
+	// Record the resource profile in effect so info can show it
+	if err := recordProfile(finalConfig); err != nil {
+		logger.LogError("Failed to record resource profile: " + err.Error())
+	}
 
 	// Main service configuration
 	mainService := Service{
@@ -357,6 +429,13 @@ func CreateComposeFile(nodeName string, config NetworkConfig, extraServiceNames
 		Ports:         finalConfig.Ports,
 		Volumes:       finalConfig.Volumes,
 		Networks:      finalConfig.Networks,
+		Environment:   finalConfig.Environment,
+		Labels:        serviceLabels(config.Network, docker.RoleNode, composeFilePath),
+	}
+	mainService.Labels[docker.LabelDataDir] = config.LocalPath
+
+	if isDeploySet(finalConfig.Deploy) {
+		mainService.Deploy = &Deploy{Resources: finalConfig.Deploy.Resources}
 	}
 
 	// Initialize services map and volume labels
@@ -421,6 +500,7 @@ fi"`, initSnapshotSyncCommand),
 				fmt.Sprintf("%s:/nodevin-volume", config.LocalPath),
 			},
 			Entrypoint: "",
+			Labels:     serviceLabels(config.Network, docker.RoleInit, composeFilePath),
 		}
 
 		// Add init container service to the services map
@@ -450,7 +530,7 @@ fi"`, initSnapshotSyncCommand),
 	extraVolumeDefs := finalConfig.VolumeDefs
 
 	if len(extraServiceNames) > 0 && len(extraServiceConfigs) > 0 {
-		extraServices, extraNetworks, extraVolumes := createExtraServices(extraServiceNames, extraServiceConfigs, extraNetworkDefs, extraVolumeDefs)
+		extraServices, extraNetworks, extraVolumes := createExtraServices(extraServiceNames, extraServiceConfigs, extraNetworkDefs, extraVolumeDefs, overrides, mainService.Image, composeFilePath)
 		for k, v := range extraServices {
 			services[k] = v
 		}
@@ -473,26 +553,6 @@ fi"`, initSnapshotSyncCommand),
 		allVolumeDefs[volumeName] = volumeDetails
 	}
 
-	// Add Watchtower service to monitor software image updates
-	watchtowerContainerNames := []string{}
-	for name := range services {
-		if !strings.HasPrefix(name, "init-") {
-			watchtowerContainerNames = append(watchtowerContainerNames, name)
-		}
-	}
-
-	watchtowerService := Service{
-		ContainerName: "watchtower-nodevin",
-		Image:         "containrrr/watchtower",
-		Ports:         []string{},
-		Volumes: []string{
-			getDockerSocketVolume(),
-		},
-		Command: fmt.Sprintf("%s --interval 7200", strings.Join(watchtowerContainerNames, " ")),
-	}
-
-	services["watchtower"] = watchtowerService
-
 	// Build the compose file structure
 	composeFile := ComposeFile{
 		//Version:  "3.9", // Throws a warning on start, also requires commenting/removing ComposeFile.Version
@@ -502,8 +562,6 @@ fi"`, initSnapshotSyncCommand),
 	}
 
 	// Generate and save the Compose file
-	composeFileName := fmt.Sprintf("docker-compose_%s.yml", nodeName)
-	composeFilePath := filepath.Join(nodevinDir, composeFileName)
 	composeData, err := yaml.Marshal(&composeFile)
 	if err != nil {
 		return "", fmt.Errorf("failed to marshal docker-compose.yml: %w", err)
@@ -516,26 +574,93 @@ fi"`, initSnapshotSyncCommand),
 	return composeFilePath, nil
 }
 
-func getDockerSocketVolume() string {
-	if runtime.GOOS == "windows" {
-		// Use named pipe for Windows
-		return "//./pipe/docker_engine:/var/run/docker.sock"
+// Reads back a compose file written by CreateComposeFile
+func ReadComposeFile(composeFilePath string) (*ComposeFile, error) {
+	composeData, err := os.ReadFile(composeFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read compose file: %w", err)
 	}
-	// Default for Linux and macOS
-	return "/var/run/docker.sock:/var/run/docker.sock"
+
+	var composeFile ComposeFile
+	if err := yaml.Unmarshal(composeData, &composeFile); err != nil {
+		return nil, fmt.Errorf("failed to parse compose file %s: %w", composeFilePath, err)
+	}
+
+	return &composeFile, nil
 }
 
-// Helper function to calculate the total size of files in a directory
+// Helper function to calculate the total size of files in a directory (from the cached size index)
 func getDirectorySize(dir string) (int64, error) {
-	var totalSize int64 = 0
-	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
+	return sizeindex.DirectorySize(dir)
+}
+
+// Reads a generated compose file, lets edit change it and writes it back
+func EditComposeFile(composeFilePath string, edit func(*ComposeFile) error) error {
+	composeFile, err := ReadComposeFile(composeFilePath)
+	if err != nil {
+		return err
+	}
+
+	if err := edit(composeFile); err != nil {
+		return err
+	}
+
+	composeData, err := yaml.Marshal(composeFile)
+	if err != nil {
+		return fmt.Errorf("failed to marshal docker-compose.yml: %w", err)
+	}
+
+	if err := os.WriteFile(composeFilePath, composeData, 0644); err != nil {
+		return fmt.Errorf("failed to write docker-compose.yml: %w", err)
+	}
+
+	return nil
+}
+
+// Rewrites the images of a generated compose file's nodes and sidecars to the references
+// in images, keyed by each service's network label (ex: bitcoin, ord). Init containers
+// follow the image of the service they prepare. Every node and sidecar must have an entry.
+func PinComposeImages(composeFilePath string, images map[string]string) error {
+	return EditComposeFile(composeFilePath, func(composeFile *ComposeFile) error {
+		pinnedImages := map[string]string{}
+		for name, service := range composeFile.Services {
+			role := service.Labels[docker.LabelRole]
+			if role != docker.RoleNode && role != docker.RoleSidecar {
+				continue
+			}
+
+			network := service.Labels[docker.LabelNetwork]
+			image, exists := images[network]
+			if !exists {
+				return fmt.Errorf("no locked image for %s (service %s)", network, name)
+			}
+
+			pinnedImages[service.Image] = image
+			service.Image = image
+			composeFile.Services[name] = service
+		}
+
+		for name, service := range composeFile.Services {
+			if service.Labels[docker.LabelRole] != docker.RoleInit {
+				continue
+			}
+			if image, exists := pinnedImages[service.Image]; exists {
+				service.Image = image
+				composeFile.Services[name] = service
+			}
 		}
-		if !info.IsDir() {
-			totalSize += info.Size()
+
+		return nil
+	})
+}
+
+// Sets the pull_policy of every service in a generated compose file, ex: never for bundled images
+func SetComposePullPolicy(composeFilePath string, policy string) error {
+	return EditComposeFile(composeFilePath, func(composeFile *ComposeFile) error {
+		for name, service := range composeFile.Services {
+			service.PullPolicy = policy
+			composeFile.Services[name] = service
 		}
 		return nil
 	})
-	return totalSize, err
 }
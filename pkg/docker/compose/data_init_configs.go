@@ -20,43 +20,35 @@ package compose
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"os/exec"
 	"strings"
 	"time"
 
 	"github.com/fiftysixcrypto/nodevin/internal/logger"
+	"github.com/fiftysixcrypto/nodevin/pkg/docker"
 )
 
-// RemoveInitContainersAndVolumes removes all containers that match "init-config-*" and their associated volumes,
+// RemoveInitContainersAndVolumes removes all init containers (labelled nodevin.role=init) and their associated volumes,
 // deletes volumes with the label "nodevin.init.volume", and anonymous volumes created within the last minute.
 func RemoveInitContainersAndVolumes() error {
-	listContainersCmd := exec.Command("docker", "ps", "-a", "--filter", "name=init-config-", "--format", "{{.Names}}")
-	var containerListOutput bytes.Buffer
-	listContainersCmd.Stdout = &containerListOutput
-	if err := listContainersCmd.Run(); err != nil {
-		logger.LogError("Failed to list containers with name pattern init-config-*: " + err.Error())
+	initContainers, err := docker.ListNodevinContainers(context.Background(), true, docker.RoleInit)
+	if err != nil {
+		logger.LogError("Failed to list init containers: " + err.Error())
 		return err
 	}
 
-	// Parse the list of container names
-	containerNames := strings.Split(strings.TrimSpace(containerListOutput.String()), "\n")
-	if len(containerNames) == 0 || containerNames[0] == "" {
-		//
-	} else {
-		// Loop through each container and remove it along with its associated volume
-		for _, containerName := range containerNames {
-			if containerName == "" {
-				continue
-			}
+	// Loop through each container and remove it along with its associated volume
+	for _, container := range initContainers {
+		containerName := docker.ContainerName(container)
 
-			// Stop and remove the container
-			removeContainerCmd := exec.Command("docker", "rm", "-f", containerName)
-			_, err := removeContainerCmd.CombinedOutput()
-			if err != nil {
-				logger.LogError(fmt.Sprintf("Failed to remove container: %s, Error: %s", containerName, err.Error()))
-				continue // Continue even if one container fails
-			}
+		// Stop and remove the container
+		removeContainerCmd := exec.Command("docker", "rm", "-f", container.ID)
+		_, err := removeContainerCmd.CombinedOutput()
+		if err != nil {
+			logger.LogError(fmt.Sprintf("Failed to remove container: %s, Error: %s", containerName, err.Error()))
+			continue // Continue even if one container fails
 		}
 	}
 
@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"path/filepath"
 
+	"github.com/fiftysixcrypto/nodevin/internal/logger"
 	"github.com/fiftysixcrypto/nodevin/internal/utils"
 	"github.com/spf13/viper"
 )
@@ -35,13 +36,14 @@ func GetDogecoinNetworkComposeConfig(network string) (NetworkConfig, error) {
 
 	// Define the base configuration for the Dogecoin network
 	baseConfig := NetworkConfig{
-		Image:    "fiftysix/dogecoin-core",
+		Network:  network,
+		Image:    utils.GetImageRegistry() + "dogecoin-core",
 		Version:  "latest",
-		Ports:    []string{"22555:22555", "22556:22556"},
+		Ports:    instancePorts([]string{"22555:22555", "22556:22556"}),
 		Volumes:  []string{},
-		Networks: []string{"dogecoin-net"},
+		Networks: []string{utils.InstanceName("dogecoin-net")},
 		NetworkDefs: map[string]NetworkDetails{
-			"dogecoin-net": {
+			utils.InstanceName("dogecoin-net"): {
 				Driver: "bridge",
 			},
 		},
@@ -57,13 +59,13 @@ func GetDogecoinNetworkComposeConfig(network string) (NetworkConfig, error) {
 	// Set the container name and command based on the network
 	switch network {
 	case "dogecoin":
-		localPath := filepath.Join(nodevinDataDir, "dogecoin-core")     // nodevin data dir, software type
-		localChainDataPath := filepath.Join(localPath, "dogecoin-core") // on-image data dir
-		baseConfig.ContainerName = "dogecoin-core"
+		localPath := filepath.Join(nodevinDataDir, utils.InstanceName("dogecoin-core")) // nodevin data dir, software type
+		localChainDataPath := filepath.Join(localPath, "dogecoin-core")                 // on-image data dir
+		baseConfig.ContainerName = utils.InstanceName("dogecoin-core")
 		baseConfig.Command = "dogecoind --server=1 --rpcbind=0.0.0.0 --rpcport=22555 --rpcallowip=0.0.0.0/0"
 		baseConfig.Volumes = []string{fmt.Sprintf("%s:/node/dogecoin-core", localChainDataPath)}
 		baseConfig.VolumeDefs = map[string]VolumeDetails{
-			"dogecoin-core-data": {
+			utils.InstanceName("dogecoin-core-data"): {
 				Labels: map[string]string{
 					"nodevin.blockchain.software": "dogecoin-core",
 				},
@@ -75,20 +77,20 @@ func GetDogecoinNetworkComposeConfig(network string) (NetworkConfig, error) {
 		baseConfig.LocalChainDataPath = "/nodevin-volume/dogecoin-core/data"
 
 	case "dogecoin-testnet":
-		localPath := filepath.Join(nodevinDataDir, "dogecoin-core-testnet") // nodevin data dir, software type
-		localChainDataPath := filepath.Join(localPath, "dogecoin-core")     // on-image data dir
-		baseConfig.ContainerName = "dogecoin-core-testnet"
+		localPath := filepath.Join(nodevinDataDir, utils.InstanceName("dogecoin-core-testnet")) // nodevin data dir, software type
+		localChainDataPath := filepath.Join(localPath, "dogecoin-core")                         // on-image data dir
+		baseConfig.ContainerName = utils.InstanceName("dogecoin-core-testnet")
 		baseConfig.Command = "dogecoind --testnet --server=1 --rpcbind=0.0.0.0 --rpcport=44555 --rpcallowip=0.0.0.0/0"
-		baseConfig.Networks = []string{"dogecoin-testnet-net"}
+		baseConfig.Networks = []string{utils.InstanceName("dogecoin-testnet-net")}
 		baseConfig.NetworkDefs = map[string]NetworkDetails{
-			"dogecoin-testnet-net": {
+			utils.InstanceName("dogecoin-testnet-net"): {
 				Driver: "bridge",
 			},
 		}
-		baseConfig.Ports = []string{"44555:44555", "44556:44556"}
+		baseConfig.Ports = instancePorts([]string{"44555:44555", "44556:44556"})
 		baseConfig.Volumes = []string{fmt.Sprintf("%s:/node/dogecoin-core", localChainDataPath)}
 		baseConfig.VolumeDefs = map[string]VolumeDetails{
-			"dogecoin-core-testnet-data": {
+			utils.InstanceName("dogecoin-core-testnet-data"): {
 				Labels: map[string]string{
 					"nodevin.blockchain.software": "dogecoin-core",
 				},
@@ -103,6 +105,11 @@ func GetDogecoinNetworkComposeConfig(network string) (NetworkConfig, error) {
 		return NetworkConfig{}, fmt.Errorf("unknown network: %s", network)
 	}
 
+	// Dogecoin Core predates -blocksdir, so block files always stay with the chainstate
+	if viper.GetString("blocks-dir") != "" {
+		logger.LogError("Dogecoin Core does not support a separate blocks directory. Ignoring --blocks-dir.")
+	}
+
 	// Optionally add RPC authentication to the command
 	cookieAuth := viper.GetBool("cookie-auth")
 	if !cookieAuth {
@@ -120,5 +127,10 @@ func GetDogecoinNetworkComposeConfig(network string) (NetworkConfig, error) {
 		baseConfig.Command = fmt.Sprintf("%s -rpcuser=%s -rpcpassword=%s", baseConfig.Command, rpcUsername, rpcPassword)
 	}
 
+	// Optionally size resources and daemon caches from the host hardware
+	if err := applyProfile(&baseConfig, network); err != nil {
+		return NetworkConfig{}, err
+	}
+
 	return baseConfig, nil
 }
@@ -0,0 +1,189 @@
+/*
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 The Nodevin Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package compose
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fiftysixcrypto/nodevin/internal/utils"
+	"github.com/spf13/viper"
+)
+
+// Returns the electrs add-on's service for electrs, electrs-testnet or electrs-regtest. electrs
+// reads blocks from the bitcoin node's data dir and serves plain TCP on port 50001.
+func getElectrsNetworkComposeConfig(network string) (NetworkConfig, error) {
+	if viper.GetString("electrum-tls-cert") != "" || viper.GetString("electrum-tls-key") != "" {
+		return NetworkConfig{}, errors.New("electrs serves plain TCP on port 50001 only, run fulcrum for TLS on port 50002")
+	}
+
+	backend, err := getAddonBackend("bitcoin", network)
+	if err != nil {
+		return NetworkConfig{}, err
+	}
+
+	baseConfig, err := getAddonBaseConfig(network, "/node/electrs", backend)
+	if err != nil {
+		return NetworkConfig{}, err
+	}
+
+	electrsNetwork := backend.variant
+	if electrsNetwork == "mainnet" {
+		electrsNetwork = "bitcoin"
+	}
+
+	// The image runs electrs, so the command holds its arguments only
+	baseConfig.Command = fmt.Sprintf("--network=%s --db-dir=/node/electrs --daemon-dir=%s/data --daemon-rpc-addr=%s:%d --daemon-p2p-addr=%s:%d --electrum-rpc-addr=0.0.0.0:50001 --log-filters=INFO",
+		electrsNetwork, backend.mount, backend.container, backend.rpcPort, backend.container, backend.p2pPort)
+
+	if user, password := getNodeRPCCredentials(); user != "" {
+		baseConfig.Command = fmt.Sprintf("%s --auth=%s:%s", baseConfig.Command, user, password)
+	} else {
+		baseConfig.Command = fmt.Sprintf("%s --cookie-file=%s", baseConfig.Command, backend.cookie())
+	}
+
+	if viper.GetBool("electrum-wait-sync") {
+		backend.applySyncGate(&baseConfig, "/node/electrs", "electrs")
+	}
+
+	return baseConfig, nil
+}
+
+// Returns the Fulcrum add-on's service for fulcrum or fulcrum-litecoin and their test networks.
+// Fulcrum asks the node for blocks over RPC and serves port 50001, and TLS on port 50002 with
+// --electrum-tls-cert and --electrum-tls-key or a certificate nodevin signs itself.
+func getFulcrumNetworkComposeConfig(network string) (NetworkConfig, error) {
+	nodeNetwork := "bitcoin"
+	if software, _ := utils.SplitNetworkVariant(network); software == "fulcrum-litecoin" {
+		nodeNetwork = "litecoin"
+	}
+
+	backend, err := getAddonBackend(nodeNetwork, network)
+	if err != nil {
+		return NetworkConfig{}, err
+	}
+
+	baseConfig, err := getAddonBaseConfig(network, "/node/fulcrum", backend)
+	if err != nil {
+		return NetworkConfig{}, err
+	}
+
+	certFile, keyFile := viper.GetString("electrum-tls-cert"), viper.GetString("electrum-tls-key")
+	if (certFile == "") != (keyFile == "") {
+		return NetworkConfig{}, errors.New("--electrum-tls-cert and --electrum-tls-key must be given together")
+	}
+	if certFile != "" {
+		for _, file := range []string{certFile, keyFile} {
+			if _, err := os.Stat(file); err != nil {
+				return NetworkConfig{}, fmt.Errorf("TLS file %s: %w", file, err)
+			}
+		}
+		baseConfig.Volumes = append(baseConfig.Volumes,
+			fmt.Sprintf("%s:/node/tls/tls.cert:ro", certFile),
+			fmt.Sprintf("%s:/node/tls/tls.key:ro", keyFile))
+	} else if err := ensureSelfSignedCert(filepath.Join(baseConfig.LocalPath, "fulcrum"), baseConfig.ContainerName); err != nil {
+		return NetworkConfig{}, err
+	}
+
+	tlsDir := "/node/fulcrum"
+	if certFile != "" {
+		tlsDir = "/node/tls"
+	}
+	baseConfig.Command = fmt.Sprintf("--datadir /node/fulcrum --bitcoind %s:%d --tcp 0.0.0.0:50001 --ssl 0.0.0.0:50002 --cert %s/tls.cert --key %s/tls.key",
+		backend.container, backend.rpcPort, tlsDir, tlsDir)
+
+	if user, password := getNodeRPCCredentials(); user != "" {
+		baseConfig.Command = fmt.Sprintf("%s --rpcuser %s --rpcpassword %s", baseConfig.Command, user, password)
+	} else {
+		baseConfig.Command = fmt.Sprintf("%s --rpccookie %s", baseConfig.Command, backend.cookie())
+	}
+
+	if viper.GetBool("electrum-wait-sync") {
+		backend.applySyncGate(&baseConfig, "/node/fulcrum", "Fulcrum")
+	} else {
+		baseConfig.Command = "Fulcrum " + baseConfig.Command
+	}
+
+	return baseConfig, nil
+}
+
+// Writes a self-signed certificate (tls.cert and tls.key) for the Electrum server's TLS port, once
+func ensureSelfSignedCert(dir string, host string) error {
+	certPath, keyPath := filepath.Join(dir, "tls.cert"), filepath.Join(dir, "tls.key")
+	if _, err := os.Stat(certPath); err == nil {
+		return nil
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create TLS directory: %w", err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return fmt.Errorf("failed to generate TLS key: %w", err)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return fmt.Errorf("failed to generate TLS certificate serial: %w", err)
+	}
+
+	template := x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: host, Organization: []string{"nodevin"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().AddDate(10, 0, 0),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames:     []string{host, "localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		return fmt.Errorf("failed to create TLS certificate: %w", err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return fmt.Errorf("failed to encode TLS key: %w", err)
+	}
+
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600); err != nil {
+		return fmt.Errorf("failed to write TLS key: %w", err)
+	}
+	if err := os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		return fmt.Errorf("failed to write TLS certificate: %w", err)
+	}
+	return nil
+}
+
+// Reports whether a service network runs an Electrum server, ex: fulcrum-litecoin-testnet
+func IsElectrumServer(network string) bool {
+	software, _ := utils.SplitNetworkVariant(network)
+	return software == "electrs" || software == "fulcrum" || software == "fulcrum-litecoin"
+}
@@ -0,0 +1,213 @@
+/*
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 The Nodevin Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package compose
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/fiftysixcrypto/nodevin/internal/utils"
+	"github.com/spf13/viper"
+)
+
+// Returns the btc-rpc-explorer add-on's service for btc-rpc-explorer and its test networks. The
+// explorer asks the node over RPC, and looks up addresses on an Electrum add-on when one runs.
+func getBtcRpcExplorerNetworkComposeConfig(network string) (NetworkConfig, error) {
+	backend, err := getAddonBackend("bitcoin", network)
+	if err != nil {
+		return NetworkConfig{}, err
+	}
+
+	baseConfig, err := getAddonBaseConfig(network, "/node/btc-rpc-explorer", backend)
+	if err != nil {
+		return NetworkConfig{}, err
+	}
+
+	// The explorer keeps no data of its own. The image starts it, configured through its environment.
+	baseConfig.Volumes = []string{backend.volume}
+	baseConfig.Command = ""
+	baseConfig.Environment = map[string]string{
+		"BTCEXP_HOST":          "0.0.0.0",
+		"BTCEXP_PORT":          "3002",
+		"BTCEXP_BITCOIND_HOST": backend.container,
+		"BTCEXP_BITCOIND_PORT": strconv.Itoa(backend.rpcPort),
+		"BTCEXP_PRIVACY_MODE":  "true",
+		"BTCEXP_NO_RATES":      "true",
+	}
+
+	if user, password := getNodeRPCCredentials(); user != "" {
+		baseConfig.Environment["BTCEXP_BITCOIND_USER"] = user
+		baseConfig.Environment["BTCEXP_BITCOIND_PASS"] = password
+	} else {
+		baseConfig.Environment["BTCEXP_BITCOIND_COOKIE"] = backend.cookie()
+	}
+
+	if electrum, exists := getElectrumAddonContainer(network); exists {
+		baseConfig.Environment["BTCEXP_ADDRESS_API"] = "electrum"
+		baseConfig.Environment["BTCEXP_ELECTRUM_SERVERS"] = fmt.Sprintf("tcp://%s:50001", electrum)
+	}
+
+	return baseConfig, nil
+}
+
+// Returns the mempool add-on's backend service for mempool and its test networks. The backend
+// asks the node over RPC, an Electrum add-on when one runs, and keeps its statistics in mempool-db.
+func getMempoolNetworkComposeConfig(network string) (NetworkConfig, error) {
+	backend, err := getAddonBackend("bitcoin", network)
+	if err != nil {
+		return NetworkConfig{}, err
+	}
+
+	baseConfig, err := getAddonBaseConfig(network, "/backend/cache", backend)
+	if err != nil {
+		return NetworkConfig{}, err
+	}
+
+	// mempool runs regtest nodes as mainnet
+	mempoolNetwork := backend.variant
+	if mempoolNetwork == "regtest" {
+		mempoolNetwork = "mainnet"
+	}
+
+	baseConfig.Command = ""
+	baseConfig.Environment = map[string]string{
+		"MEMPOOL_NETWORK":    mempoolNetwork,
+		"MEMPOOL_BACKEND":    "none",
+		"CORE_RPC_HOST":      backend.container,
+		"CORE_RPC_PORT":      strconv.Itoa(backend.rpcPort),
+		"DATABASE_ENABLED":   "true",
+		"DATABASE_HOST":      utils.InstanceName(networkInVariant("mempool-db", network)),
+		"DATABASE_DATABASE":  "mempool",
+		"DATABASE_USERNAME":  "mempool",
+		"DATABASE_PASSWORD":  "mempool",
+		"STATISTICS_ENABLED": "true",
+	}
+
+	if user, password := getNodeRPCCredentials(); user != "" {
+		baseConfig.Environment["CORE_RPC_USERNAME"] = user
+		baseConfig.Environment["CORE_RPC_PASSWORD"] = password
+	} else {
+		baseConfig.Environment["CORE_RPC_COOKIE"] = "true"
+		baseConfig.Environment["CORE_RPC_COOKIE_PATH"] = backend.cookie()
+	}
+
+	if electrum, exists := getElectrumAddonContainer(network); exists {
+		baseConfig.Environment["MEMPOOL_BACKEND"] = "electrum"
+		baseConfig.Environment["ELECTRUM_HOST"] = electrum
+		baseConfig.Environment["ELECTRUM_PORT"] = "50001"
+		baseConfig.Environment["ELECTRUM_TLS_ENABLED"] = "false"
+	}
+
+	return baseConfig, nil
+}
+
+// Returns mempool's web frontend, serving the explorer on port 8080 from the backend's API
+func getMempoolWebNetworkComposeConfig(network string) (NetworkConfig, error) {
+	baseConfig, err := getMempoolCompanionBaseConfig(network, "")
+	if err != nil {
+		return NetworkConfig{}, err
+	}
+
+	baseConfig.Command = ""
+	baseConfig.Environment = map[string]string{
+		"FRONTEND_HTTP_PORT":        "8080",
+		"BACKEND_MAINNET_HTTP_HOST": utils.InstanceName(networkInVariant("mempool", network)),
+		"BACKEND_MAINNET_HTTP_PORT": "8999",
+	}
+
+	return baseConfig, nil
+}
+
+// Returns mempool's database. It is reachable on the node's compose network only, so it keeps
+// the credentials of mempool's own compose example.
+func getMempoolDbNetworkComposeConfig(network string) (NetworkConfig, error) {
+	baseConfig, err := getMempoolCompanionBaseConfig(network, "/var/lib/mysql")
+	if err != nil {
+		return NetworkConfig{}, err
+	}
+
+	baseConfig.Command = ""
+	baseConfig.Environment = map[string]string{
+		"MYSQL_DATABASE":      "mempool",
+		"MYSQL_USER":          "mempool",
+		"MYSQL_PASSWORD":      "mempool",
+		"MYSQL_ROOT_PASSWORD": "mempool",
+	}
+
+	return baseConfig, nil
+}
+
+// Returns the base service of a mempool companion (ex: mempool-db-testnet) on the node's compose
+// network, keeping its data (mounted at dataPath, if any) in the mempool service's directory
+func getMempoolCompanionBaseConfig(network string, dataPath string) (NetworkConfig, error) {
+	backend, err := getAddonBackend("bitcoin", network)
+	if err != nil {
+		return NetworkConfig{}, err
+	}
+
+	baseConfig, err := getAddonBaseConfig(networkInVariant("mempool", network), "/backend/cache", backend)
+	if err != nil {
+		return NetworkConfig{}, err
+	}
+
+	baseConfig.Network = network
+	baseConfig.ContainerName = utils.InstanceName(network)
+	baseConfig.Volumes = []string{}
+	if dataPath != "" {
+		baseConfig.Volumes = append(baseConfig.Volumes, fmt.Sprintf("%s:%s", filepath.Join(baseConfig.LocalPath, filepath.Base(dataPath)), dataPath))
+	}
+
+	return baseConfig, nil
+}
+
+// Returns the network of another service in the variant of network, ex: mempool-db-testnet for mempool-web-testnet
+func networkInVariant(service string, network string) string {
+	if _, variant := utils.SplitNetworkVariant(network); variant != "mainnet" {
+		return service + "-" + variant
+	}
+	return service
+}
+
+// Returns the container of the Electrum add-on requested alongside the bitcoin node in the variant
+// of network, ex: electrs-testnet. The add-on registry cannot be used here as it refers to the explorers.
+func getElectrumAddonContainer(network string) (string, bool) {
+	for _, service := range []string{"electrs", "fulcrum"} {
+		requested := viper.GetBool(service)
+		for _, name := range viper.GetStringSlice("addon") {
+			requested = requested || strings.TrimSpace(name) == service
+		}
+		if requested {
+			return utils.InstanceName(networkInVariant(service, network)), true
+		}
+	}
+	return "", false
+}
+
+// Returns the container port a block explorer's service serves its web interface on, ex: 8080 for mempool-web-testnet
+func GetExplorerWebPort(network string) (int, bool) {
+	switch software, _ := utils.SplitNetworkVariant(network); software {
+	case "btc-rpc-explorer":
+		return 3002, true
+	case "mempool-web":
+		return 8080, true
+	}
+	return 0, false
+}
@@ -0,0 +1,64 @@
+/*
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 The Nodevin Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package compose
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// Shifts the host side of port mappings (ex: 8332:8332 -> 8342:8332) by --port-offset,
+// which start sets automatically for named instances so they don't collide
+func instancePorts(ports []string) []string {
+	offset := viper.GetInt("port-offset")
+	if offset == 0 {
+		return ports
+	}
+
+	shifted := make([]string, 0, len(ports))
+	for _, mapping := range ports {
+		shifted = append(shifted, OffsetHostPort(mapping, offset))
+	}
+	return shifted
+}
+
+// Offsets the host port of a single "[ip:]host:container[/proto]" mapping
+func OffsetHostPort(mapping string, offset int) string {
+	spec, protocol, hasProtocol := strings.Cut(mapping, "/")
+
+	parts := strings.Split(spec, ":")
+	if len(parts) < 2 {
+		// Only a container port, nothing is published on the host
+		return mapping
+	}
+
+	hostPort, err := strconv.Atoi(parts[len(parts)-2])
+	if err != nil {
+		return mapping
+	}
+	parts[len(parts)-2] = strconv.Itoa(hostPort + offset)
+
+	shifted := strings.Join(parts, ":")
+	if hasProtocol {
+		shifted += "/" + protocol
+	}
+	return shifted
+}
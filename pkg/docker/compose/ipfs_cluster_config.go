@@ -26,7 +26,8 @@ import (
 	"github.com/spf13/viper"
 )
 
-func GetIpfsClusterNetworkComposeConfig(network string) (NetworkConfig, error) {
+// Returns the ipfs-cluster add-on's service, including its connection settings
+func getIpfsClusterNetworkComposeConfig(network string) (NetworkConfig, error) {
 	// Get base nodevin data directory
 	nodevinDataDir, err := utils.GetNodevinDataDir()
 	if err != nil {
@@ -35,14 +36,13 @@ func GetIpfsClusterNetworkComposeConfig(network string) (NetworkConfig, error) {
 
 	// Define the base configuration for ipfs-cluster (used alongside IPFS)
 	baseConfig := NetworkConfig{
-		Image:    "fiftysix/ipfs-cluster",
+		Network:  network,
 		Version:  "latest",
 		Restart:  "always",
-		Ports:    []string{"9094:9094", "9096:9096"},
 		Volumes:  []string{},
-		Networks: []string{"ipfs-net"},
+		Networks: []string{utils.InstanceName("ipfs-net")},
 		NetworkDefs: map[string]NetworkDetails{
-			"ipfs-net": {
+			utils.InstanceName("ipfs-net"): {
 				Driver: "bridge",
 			},
 		},
@@ -70,16 +70,16 @@ func GetIpfsClusterNetworkComposeConfig(network string) (NetworkConfig, error) {
 	// Set the container name and command based on the network
 	switch network {
 	case "ipfs-cluster":
-		localPath := filepath.Join(nodevinDataDir, "ipfs-cluster")     // nodevin data dir, software type
-		localChainDataPath := filepath.Join(localPath, "ipfs-cluster") // on-image data dir
-		baseConfig.ContainerName = "ipfs-cluster"
+		localPath := filepath.Join(nodevinDataDir, utils.InstanceName("ipfs-cluster")) // nodevin data dir, software type
+		localChainDataPath := filepath.Join(localPath, "ipfs-cluster")                 // on-image data dir
+		baseConfig.ContainerName = utils.InstanceName("ipfs-cluster")
 		baseConfig.Command = ""
 		baseConfig.Volumes = []string{
-			fmt.Sprintf("%s:/node/ipfs", filepath.Join(nodevinDataDir, "ipfs", "ipfs")),
+			fmt.Sprintf("%s:/node/ipfs", filepath.Join(nodevinDataDir, utils.InstanceName("ipfs"), "ipfs")),
 			fmt.Sprintf("%s:/node/ipfs-cluster", localChainDataPath),
 		}
 		baseConfig.VolumeDefs = map[string]VolumeDetails{
-			"ipfs-cluster-data": {
+			utils.InstanceName("ipfs-cluster-data"): {
 				Labels: map[string]string{
 					"nodevin.blockchain.software": "ipfs-cluster",
 				},
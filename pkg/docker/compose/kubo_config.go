@@ -34,13 +34,14 @@ func GetKuboNetworkComposeConfig(network string) (NetworkConfig, error) {
 
 	// Define the base configuration for Kubo (IPFS)
 	baseConfig := NetworkConfig{
-		Image:    "fiftysix/kubo",
+		Network:  network,
+		Image:    utils.GetImageRegistry() + "kubo",
 		Version:  "latest",
-		Ports:    []string{"4001:4001", "5001:5001", "8080:8080"},
+		Ports:    instancePorts([]string{"4001:4001", "5001:5001", "8080:8080"}),
 		Volumes:  []string{},
-		Networks: []string{"ipfs-net"},
+		Networks: []string{utils.InstanceName("ipfs-net")},
 		NetworkDefs: map[string]NetworkDetails{
-			"ipfs-net": {
+			utils.InstanceName("ipfs-net"): {
 				Driver: "bridge",
 			},
 		},
@@ -50,13 +51,13 @@ func GetKuboNetworkComposeConfig(network string) (NetworkConfig, error) {
 	// Set the container name and command based on the network
 	switch network {
 	case "ipfs":
-		localPath := filepath.Join(nodevinDataDir, "ipfs")     // nodevin data dir, software type
-		localChainDataPath := filepath.Join(localPath, "ipfs") // on-image data dir
-		baseConfig.ContainerName = "ipfs"
+		localPath := filepath.Join(nodevinDataDir, utils.InstanceName("ipfs")) // nodevin data dir, software type
+		localChainDataPath := filepath.Join(localPath, "ipfs")                 // on-image data dir
+		baseConfig.ContainerName = utils.InstanceName("ipfs")
 		baseConfig.Command = ""
 		baseConfig.Volumes = []string{fmt.Sprintf("%s:/node/ipfs", localChainDataPath)}
 		baseConfig.VolumeDefs = map[string]VolumeDetails{
-			"ipfs-data": {
+			utils.InstanceName("ipfs-data"): {
 				Labels: map[string]string{
 					"nodevin.blockchain.software": "kubo",
 				},
@@ -0,0 +1,139 @@
+/*
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 The Nodevin Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package compose
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/fiftysixcrypto/nodevin/internal/utils"
+)
+
+// Returns the LND add-on's service for lnd, lnd-testnet or lnd-regtest, connected to the
+// bitcoin node's RPC and ZMQ publishers
+func getLndNetworkComposeConfig(network string) (NetworkConfig, error) {
+	backend, err := getAddonBackend("bitcoin", network)
+	if err != nil {
+		return NetworkConfig{}, err
+	}
+
+	baseConfig, err := getAddonBaseConfig(network, "/node/lnd", backend)
+	if err != nil {
+		return NetworkConfig{}, err
+	}
+
+	// The image runs lnd, so the command holds its arguments only
+	baseConfig.Command = fmt.Sprintf("--lnddir=/node/lnd --bitcoin.%s --bitcoin.node=bitcoind"+
+		" --bitcoind.rpchost=%s:%d --bitcoind.zmqpubrawblock=tcp://%s:28332 --bitcoind.zmqpubrawtx=tcp://%s:28333"+
+		" --listen=0.0.0.0:9735 --rpclisten=0.0.0.0:10009 --restlisten=0.0.0.0:8080 --tlsextradomain=%s",
+		backend.variant, backend.container, backend.rpcPort, backend.container, backend.container, baseConfig.ContainerName)
+
+	if user, password := getNodeRPCCredentials(); user != "" {
+		baseConfig.Command = fmt.Sprintf("%s --bitcoind.rpcuser=%s --bitcoind.rpcpass=%s", baseConfig.Command, user, password)
+	} else {
+		baseConfig.Command = fmt.Sprintf("%s --bitcoind.rpccookie=%s", baseConfig.Command, backend.cookie())
+	}
+
+	// Regtest wallets are throwaway, others are unlocked on restart with a password kept next to the wallet
+	if backend.variant == "regtest" {
+		baseConfig.Command = baseConfig.Command + " --noseedbackup"
+	} else {
+		if err := ensureLndWalletPassword(filepath.Join(baseConfig.LocalPath, "lnd")); err != nil {
+			return NetworkConfig{}, err
+		}
+		baseConfig.Command = baseConfig.Command + " --wallet-unlock-password-file=/node/lnd/wallet-password --wallet-unlock-allow-create"
+	}
+
+	return baseConfig, nil
+}
+
+// Creates the password LND unlocks its wallet with, once, ex: for `nodevin ln create`
+func ensureLndWalletPassword(dir string) error {
+	path := filepath.Join(dir, "wallet-password")
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create lnd data directory: %w", err)
+	}
+
+	secret := make([]byte, 24)
+	if _, err := rand.Read(secret); err != nil {
+		return fmt.Errorf("failed to generate lnd wallet password: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(hex.EncodeToString(secret)), 0600); err != nil {
+		return fmt.Errorf("failed to write lnd wallet password: %w", err)
+	}
+	return nil
+}
+
+// Returns the Core Lightning add-on's service for cln, cln-testnet or cln-regtest, connected
+// to the bitcoin node's RPC
+func getClnNetworkComposeConfig(network string) (NetworkConfig, error) {
+	backend, err := getAddonBackend("bitcoin", network)
+	if err != nil {
+		return NetworkConfig{}, err
+	}
+
+	baseConfig, err := getAddonBaseConfig(network, "/node/cln", backend)
+	if err != nil {
+		return NetworkConfig{}, err
+	}
+
+	// The image's entrypoint runs lightningd for LIGHTNINGD_NETWORK with the command as arguments
+	baseConfig.Environment = map[string]string{
+		"LIGHTNINGD_NETWORK": clnNetworkName(backend.variant),
+		"LIGHTNINGD_DATA":    "/node/cln",
+	}
+	baseConfig.Command = fmt.Sprintf("--lightning-dir=/node/cln --bind-addr=0.0.0.0:9735 --bitcoin-rpcconnect=%s --bitcoin-rpcport=%d",
+		backend.container, backend.rpcPort)
+
+	if user, password := getNodeRPCCredentials(); user != "" {
+		baseConfig.Command = fmt.Sprintf("%s --bitcoin-rpcuser=%s --bitcoin-rpcpassword=%s", baseConfig.Command, user, password)
+	} else {
+		baseConfig.Command = fmt.Sprintf("%s --bitcoin-datadir=%s/data", baseConfig.Command, backend.mount)
+	}
+
+	return baseConfig, nil
+}
+
+// Returns Core Lightning's name for a network variant, ex: bitcoin for mainnet
+func clnNetworkName(variant string) string {
+	if variant == "mainnet" {
+		return "bitcoin"
+	}
+	return variant
+}
+
+// Returns the command line client of a Lightning service network inside its container,
+// ex: lncli for lnd-testnet, reporting whether the network is a Lightning node
+func LightningCLI(network string) ([]string, bool) {
+	software, variant := utils.SplitNetworkVariant(network)
+	switch software {
+	case "lnd":
+		return []string{"lncli", "--lnddir=/node/lnd", "--network=" + variant}, true
+	case "cln":
+		return []string{"lightning-cli", "--lightning-dir=/node/cln", "--network=" + clnNetworkName(variant)}, true
+	}
+	return nil, false
+}
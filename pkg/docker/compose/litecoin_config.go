@@ -35,13 +35,14 @@ func GetLitecoinNetworkComposeConfig(network string) (NetworkConfig, error) {
 
 	// Define the base configuration for the Litecoin network
 	baseConfig := NetworkConfig{
-		Image:    "fiftysix/litecoin-core",
+		Network:  network,
+		Image:    utils.GetImageRegistry() + "litecoin-core",
 		Version:  "latest",
-		Ports:    []string{"9332:9332", "9333:9333"},
+		Ports:    instancePorts([]string{"9332:9332", "9333:9333"}),
 		Volumes:  []string{},
-		Networks: []string{"litecoin-net"},
+		Networks: []string{utils.InstanceName("litecoin-net")},
 		NetworkDefs: map[string]NetworkDetails{
-			"litecoin-net": {
+			utils.InstanceName("litecoin-net"): {
 				Driver: "bridge",
 			},
 		},
@@ -57,13 +58,13 @@ func GetLitecoinNetworkComposeConfig(network string) (NetworkConfig, error) {
 	// Set the container name and command based on the network
 	switch network {
 	case "litecoin":
-		localPath := filepath.Join(nodevinDataDir, "litecoin-core")     // nodevin data dir, software type
-		localChainDataPath := filepath.Join(localPath, "litecoin-core") // on-image data dir
-		baseConfig.ContainerName = "litecoin-core"
+		localPath := filepath.Join(nodevinDataDir, utils.InstanceName("litecoin-core")) // nodevin data dir, software type
+		localChainDataPath := filepath.Join(localPath, "litecoin-core")                 // on-image data dir
+		baseConfig.ContainerName = utils.InstanceName("litecoin-core")
 		baseConfig.Command = "litecoind --server=1 --rpcbind=0.0.0.0 --rpcport=9332 --rpcallowip=0.0.0.0/0"
 		baseConfig.Volumes = []string{fmt.Sprintf("%s:/node/litecoin-core", localChainDataPath)}
 		baseConfig.VolumeDefs = map[string]VolumeDetails{
-			"litecoin-core-data": {
+			utils.InstanceName("litecoin-core-data"): {
 				Labels: map[string]string{
 					"nodevin.blockchain.software": "litecoin-core",
 				},
@@ -75,20 +76,20 @@ func GetLitecoinNetworkComposeConfig(network string) (NetworkConfig, error) {
 		baseConfig.LocalChainDataPath = "/nodevin-volume/litecoin-core/data"
 
 	case "litecoin-testnet":
-		localPath := filepath.Join(nodevinDataDir, "litecoin-core-testnet") // data dir, software type
-		localChainDataPath := filepath.Join(localPath, "litecoin-core")     // on-image data dir
-		baseConfig.ContainerName = "litecoin-core-testnet"
+		localPath := filepath.Join(nodevinDataDir, utils.InstanceName("litecoin-core-testnet")) // data dir, software type
+		localChainDataPath := filepath.Join(localPath, "litecoin-core")                         // on-image data dir
+		baseConfig.ContainerName = utils.InstanceName("litecoin-core-testnet")
 		baseConfig.Command = "litecoind --testnet --server=1 --rpcbind=0.0.0.0 --rpcport=19332 --rpcallowip=0.0.0.0/0"
-		baseConfig.Networks = []string{"litecoin-testnet-net"}
+		baseConfig.Networks = []string{utils.InstanceName("litecoin-testnet-net")}
 		baseConfig.NetworkDefs = map[string]NetworkDetails{
-			"litecoin-testnet-net": {
+			utils.InstanceName("litecoin-testnet-net"): {
 				Driver: "bridge",
 			},
 		}
-		baseConfig.Ports = []string{"19332:19332", "19333:19333"}
+		baseConfig.Ports = instancePorts([]string{"19332:19332", "19333:19333"})
 		baseConfig.Volumes = []string{fmt.Sprintf("%s:/node/litecoin-core", localChainDataPath)}
 		baseConfig.VolumeDefs = map[string]VolumeDetails{
-			"litecoin-core-testnet-data": {
+			utils.InstanceName("litecoin-core-testnet-data"): {
 				Labels: map[string]string{
 					"nodevin.blockchain.software": "litecoin-core",
 				},
@@ -103,6 +104,11 @@ func GetLitecoinNetworkComposeConfig(network string) (NetworkConfig, error) {
 		return NetworkConfig{}, fmt.Errorf("unknown network: %s", network)
 	}
 
+	// Optionally keep raw block files on a separate disk
+	if err := applyBlocksDir(&baseConfig); err != nil {
+		return NetworkConfig{}, err
+	}
+
 	// Optionally add RPC authentication to the command
 	cookieAuth := viper.GetBool("cookie-auth")
 	if !cookieAuth {
@@ -120,5 +126,10 @@ func GetLitecoinNetworkComposeConfig(network string) (NetworkConfig, error) {
 		baseConfig.Command = fmt.Sprintf("%s -rpcuser=%s -rpcpassword=%s", baseConfig.Command, rpcUsername, rpcPassword)
 	}
 
+	// Optionally size resources and daemon caches from the host hardware
+	if err := applyProfile(&baseConfig, network); err != nil {
+		return NetworkConfig{}, err
+	}
+
 	return baseConfig, nil
 }
@@ -26,7 +26,8 @@ import (
 	"github.com/spf13/viper"
 )
 
-func GetOrdNetworkComposeConfig(network string) (NetworkConfig, error) {
+// Returns the ord add-on's service for ord or ord-testnet, the image and ports come from its declaration
+func getOrdNetworkComposeConfig(network string) (NetworkConfig, error) {
 	// Get base nodevin data directory
 	nodevinDataDir, err := utils.GetNodevinDataDir()
 	if err != nil {
@@ -35,14 +36,13 @@ func GetOrdNetworkComposeConfig(network string) (NetworkConfig, error) {
 
 	// Define the base configuration for ord
 	baseConfig := NetworkConfig{
-		Image:    "fiftysix/ord",
+		Network:  network,
 		Version:  "latest",
 		Restart:  "always",
-		Ports:    []string{"80:80"},
 		Volumes:  []string{},
-		Networks: []string{"bitcoin-net"},
+		Networks: []string{utils.InstanceName("bitcoin-net")},
 		NetworkDefs: map[string]NetworkDetails{
-			"bitcoin-net": {
+			utils.InstanceName("bitcoin-net"): {
 				Driver: "bridge",
 			},
 		},
@@ -58,16 +58,16 @@ func GetOrdNetworkComposeConfig(network string) (NetworkConfig, error) {
 	// Set the container name and command based on the network
 	switch network {
 	case "ord":
-		localPath := filepath.Join(nodevinDataDir, "ord")     // nodevin data dir, software type
-		localChainDataPath := filepath.Join(localPath, "ord") // on-image data dir
-		baseConfig.ContainerName = "ord"
-		baseConfig.Command = "ord --bitcoin-rpc-url http://bitcoin-core:8332"
+		localPath := filepath.Join(nodevinDataDir, utils.InstanceName("ord")) // nodevin data dir, software type
+		localChainDataPath := filepath.Join(localPath, "ord")                 // on-image data dir
+		baseConfig.ContainerName = utils.InstanceName("ord")
+		baseConfig.Command = "ord --bitcoin-rpc-url http://" + utils.InstanceName("bitcoin-core") + ":8332"
 		baseConfig.Volumes = []string{
-			fmt.Sprintf("%s:/node/bitcoin-core", filepath.Join(nodevinDataDir, "bitcoin-core", "bitcoin-core")),
+			fmt.Sprintf("%s:/node/bitcoin-core", filepath.Join(nodevinDataDir, utils.InstanceName("bitcoin-core"), "bitcoin-core")),
 			fmt.Sprintf("%s:/node/ord", localChainDataPath),
 		}
 		baseConfig.VolumeDefs = map[string]VolumeDetails{
-			"ord-data": {
+			utils.InstanceName("ord-data"): {
 				Labels: map[string]string{
 					"nodevin.blockchain.software": "ord",
 				},
@@ -79,22 +79,22 @@ func GetOrdNetworkComposeConfig(network string) (NetworkConfig, error) {
 		baseConfig.LocalChainDataPath = "/nodevin-volume-ord/ord/data"
 
 	case "ord-testnet":
-		localPath := filepath.Join(nodevinDataDir, "ord-testnet") // nodevin data dir, software type
-		localChainDataPath := filepath.Join(localPath, "ord")     // on-image data dir
-		baseConfig.ContainerName = "ord-testnet"
-		baseConfig.Command = "ord --testnet --bitcoin-rpc-url http://bitcoin-core-testnet:18332"
+		localPath := filepath.Join(nodevinDataDir, utils.InstanceName("ord-testnet")) // nodevin data dir, software type
+		localChainDataPath := filepath.Join(localPath, "ord")                         // on-image data dir
+		baseConfig.ContainerName = utils.InstanceName("ord-testnet")
+		baseConfig.Command = "ord --testnet --bitcoin-rpc-url http://" + utils.InstanceName("bitcoin-core-testnet") + ":18332"
 		baseConfig.Volumes = []string{
-			fmt.Sprintf("%s:/node/bitcoin-core", filepath.Join(nodevinDataDir, "bitcoin-core-testnet", "bitcoin-core")),
+			fmt.Sprintf("%s:/node/bitcoin-core", filepath.Join(nodevinDataDir, utils.InstanceName("bitcoin-core-testnet"), "bitcoin-core")),
 			fmt.Sprintf("%s:/node/ord", localChainDataPath),
 		}
-		baseConfig.Networks = []string{"bitcoin-testnet-net"}
+		baseConfig.Networks = []string{utils.InstanceName("bitcoin-testnet-net")}
 		baseConfig.NetworkDefs = map[string]NetworkDetails{
-			"bitcoin-testnet-net": {
+			utils.InstanceName("bitcoin-testnet-net"): {
 				Driver: "bridge",
 			},
 		}
 		baseConfig.VolumeDefs = map[string]VolumeDetails{
-			"ord-testnet-data": {
+			utils.InstanceName("ord-testnet-data"): {
 				Labels: map[string]string{
 					"nodevin.blockchain.software": "ord",
 				},
@@ -109,6 +109,11 @@ func GetOrdNetworkComposeConfig(network string) (NetworkConfig, error) {
 		return NetworkConfig{}, fmt.Errorf("unknown network: %s", network)
 	}
 
+	// Optionally keep the ord index on a separate disk
+	if err := applyIndexDir(&baseConfig); err != nil {
+		return NetworkConfig{}, err
+	}
+
 	// Optionally add RPC authentication to the command
 	cookieAuth := viper.GetBool("ord-cookie-auth")
 	if !cookieAuth {
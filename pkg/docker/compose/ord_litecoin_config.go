@@ -26,7 +26,8 @@ import (
 	"github.com/spf13/viper"
 )
 
-func GetOrdLitecoinNetworkComposeConfig(network string) (NetworkConfig, error) {
+// Returns the ord-litecoin add-on's service for ord-litecoin or ord-litecoin-testnet
+func getOrdLitecoinNetworkComposeConfig(network string) (NetworkConfig, error) {
 	// Get base nodevin data directory
 	nodevinDataDir, err := utils.GetNodevinDataDir()
 	if err != nil {
@@ -35,14 +36,13 @@ func GetOrdLitecoinNetworkComposeConfig(network string) (NetworkConfig, error) {
 
 	// Define the base configuration for ord-litecoin
 	baseConfig := NetworkConfig{
-		Image:    "fiftysix/ord-litecoin",
+		Network:  network,
 		Version:  "latest",
 		Restart:  "always",
-		Ports:    []string{"80:80"},
 		Volumes:  []string{},
-		Networks: []string{"litecoin-net"},
+		Networks: []string{utils.InstanceName("litecoin-net")},
 		NetworkDefs: map[string]NetworkDetails{
-			"litecoin-net": {
+			utils.InstanceName("litecoin-net"): {
 				Driver: "bridge",
 			},
 		},
@@ -58,16 +58,16 @@ func GetOrdLitecoinNetworkComposeConfig(network string) (NetworkConfig, error) {
 	// Set the container name and command based on the network
 	switch network {
 	case "ord-litecoin":
-		localPath := filepath.Join(nodevinDataDir, "ord-litecoin")     // nodevin data dir, software type
-		localChainDataPath := filepath.Join(localPath, "ord-litecoin") // on-image data dir
-		baseConfig.ContainerName = "ord-litecoin"
-		baseConfig.Command = "ord --litecoin-rpc-url http://litecoin-core:9332"
+		localPath := filepath.Join(nodevinDataDir, utils.InstanceName("ord-litecoin")) // nodevin data dir, software type
+		localChainDataPath := filepath.Join(localPath, "ord-litecoin")                 // on-image data dir
+		baseConfig.ContainerName = utils.InstanceName("ord-litecoin")
+		baseConfig.Command = "ord --litecoin-rpc-url http://" + utils.InstanceName("litecoin-core") + ":9332"
 		baseConfig.Volumes = []string{
-			fmt.Sprintf("%s:/node/litecoin-core", filepath.Join(nodevinDataDir, "litecoin-core", "litecoin-core")),
+			fmt.Sprintf("%s:/node/litecoin-core", filepath.Join(nodevinDataDir, utils.InstanceName("litecoin-core"), "litecoin-core")),
 			fmt.Sprintf("%s:/node/ord-litecoin", localChainDataPath),
 		}
 		baseConfig.VolumeDefs = map[string]VolumeDetails{
-			"ord-litecoin-data": {
+			utils.InstanceName("ord-litecoin-data"): {
 				Labels: map[string]string{
 					"nodevin.blockchain.software": "ord-litecoin",
 				},
@@ -79,22 +79,22 @@ func GetOrdLitecoinNetworkComposeConfig(network string) (NetworkConfig, error) {
 		baseConfig.LocalChainDataPath = "/nodevin-volume-ord-litecoin/ord-litecoin/data"
 
 	case "ord-litecoin-testnet":
-		localPath := filepath.Join(nodevinDataDir, "ord-litecoin-testnet") // nodevin data dir, software type
-		localChainDataPath := filepath.Join(localPath, "ord-litecoin")     // on-image data dir
-		baseConfig.ContainerName = "ord-litecoin-testnet"
-		baseConfig.Command = "ord --testnet --litecoin-rpc-url http://litecoin-core-testnet:19332"
+		localPath := filepath.Join(nodevinDataDir, utils.InstanceName("ord-litecoin-testnet")) // nodevin data dir, software type
+		localChainDataPath := filepath.Join(localPath, "ord-litecoin")                         // on-image data dir
+		baseConfig.ContainerName = utils.InstanceName("ord-litecoin-testnet")
+		baseConfig.Command = "ord --testnet --litecoin-rpc-url http://" + utils.InstanceName("litecoin-core-testnet") + ":19332"
 		baseConfig.Volumes = []string{
-			fmt.Sprintf("%s:/node/litecoin-core", filepath.Join(nodevinDataDir, "litecoin-core-testnet", "litecoin-core")),
+			fmt.Sprintf("%s:/node/litecoin-core", filepath.Join(nodevinDataDir, utils.InstanceName("litecoin-core-testnet"), "litecoin-core")),
 			fmt.Sprintf("%s:/node/ord-litecoin", localChainDataPath),
 		}
-		baseConfig.Networks = []string{"litecoin-testnet-net"}
+		baseConfig.Networks = []string{utils.InstanceName("litecoin-testnet-net")}
 		baseConfig.NetworkDefs = map[string]NetworkDetails{
-			"litecoin-testnet-net": {
+			utils.InstanceName("litecoin-testnet-net"): {
 				Driver: "bridge",
 			},
 		}
 		baseConfig.VolumeDefs = map[string]VolumeDetails{
-			"ord-litecoin-testnet-data": {
+			utils.InstanceName("ord-litecoin-testnet-data"): {
 				Labels: map[string]string{
 					"nodevin.blockchain.software": "ord-litecoin",
 				},
@@ -109,6 +109,11 @@ func GetOrdLitecoinNetworkComposeConfig(network string) (NetworkConfig, error) {
 		return NetworkConfig{}, fmt.Errorf("unknown network: %s", network)
 	}
 
+	// Optionally keep the ord index on a separate disk
+	if err := applyIndexDir(&baseConfig); err != nil {
+		return NetworkConfig{}, err
+	}
+
 	// Optionally add RPC authentication to the command
 	cookieAuth := viper.GetBool("ord-litecoin-cookie-auth") || viper.GetBool("ord-cookie-auth")
 	if !cookieAuth {
@@ -0,0 +1,114 @@
+/*
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 The Nodevin Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package compose
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/fiftysixcrypto/nodevin/internal/logger"
+	"github.com/fiftysixcrypto/nodevin/internal/utils"
+	"github.com/fiftysixcrypto/nodevin/pkg/profile"
+	"github.com/spf13/viper"
+)
+
+// Sizes docker resource limits and daemon tuning (dbcache, par, maxmempool)
+// from the host hardware when --profile is set.
+func applyProfile(config *NetworkConfig, network string) error {
+	profileName := viper.GetString("profile")
+	if profileName == "" {
+		return nil
+	}
+
+	// Block files take most of the disk I/O, so with --blocks-dir (ex: a large HDD) its disk decides
+	hardwareDir := filepath.Dir(config.LocalPath)
+	if blocksPath, exists := config.ExtraDataPaths["blocks"]; exists {
+		hardwareDir = filepath.Dir(blocksPath)
+	}
+
+	hardware := profile.DetectHardware(hardwareDir)
+	settings, err := profile.Resolve(profileName, hardware, isInitialSync(*config, network))
+	if err != nil {
+		return err
+	}
+
+	config.Command = fmt.Sprintf("%s %s", config.Command, settings.DaemonArgs())
+	config.Deploy.Resources = Resources{
+		Limits: ResourceDetails{
+			CPUs:   settings.CPULimit,
+			Memory: settings.MemLimit,
+		},
+		Reservations: ResourceDetails{
+			CPUs:   settings.CPUReservation,
+			Memory: settings.MemReservation,
+		},
+	}
+	config.Profile = &settings
+
+	logger.LogInfo(fmt.Sprintf("Using %s profile (%s): %s", settings.Profile, settings.Phase, settings.DaemonArgs()))
+
+	return nil
+}
+
+// A node is treated as still syncing until its data reaches most of the
+// expected chain size, so restarting after sync lowers dbcache again
+func isInitialSync(config NetworkConfig, network string) bool {
+	var existingSize int64
+	for _, path := range append([]string{config.LocalPath}, extraDataPathList(config)...) {
+		size, err := getDirectorySize(path)
+		if err == nil {
+			existingSize += size
+		}
+	}
+
+	dataSize, _ := utils.GetNetworkRequiredDataSize(network)
+	if dataSize <= 0 {
+		return existingSize < GB
+	}
+
+	return float64(existingSize) < float64(dataSize)*0.9
+}
+
+func extraDataPathList(config NetworkConfig) []string {
+	var paths []string
+	for _, path := range config.ExtraDataPaths {
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+// Records the profile actually deployed, reflecting any resource flags that overrode it
+func recordProfile(finalConfig NetworkConfig) error {
+	if _, err := os.Stat(finalConfig.LocalPath); err != nil {
+		return nil
+	}
+
+	if finalConfig.Profile == nil {
+		return profile.Record(finalConfig.LocalPath, nil)
+	}
+
+	settings := *finalConfig.Profile
+	settings.CPULimit = finalConfig.Deploy.Resources.Limits.CPUs
+	settings.MemLimit = finalConfig.Deploy.Resources.Limits.Memory
+	settings.CPUReservation = finalConfig.Deploy.Resources.Reservations.CPUs
+	settings.MemReservation = finalConfig.Deploy.Resources.Reservations.Memory
+
+	return profile.Record(finalConfig.LocalPath, &settings)
+}
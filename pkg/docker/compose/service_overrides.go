@@ -0,0 +1,153 @@
+/*
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 The Nodevin Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package compose
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/fiftysixcrypto/nodevin/internal/utils"
+	"github.com/spf13/viper"
+)
+
+// Fields of a generated service --set can change, ex: --set ord.mem-limit=2g
+var serviceOverrideFields = []string{
+	"image", "version", "container-name", "restart", "command",
+	"ports", "volumes", "networks", "environment",
+	"cpu-limit", "mem-limit", "cpu-reservation", "mem-reservation",
+	"snapshot-sync-command", "snapshot-sync-cid", "snapshot-sync-data-dir", "snapshot-sync-file-name",
+}
+
+// ServiceOverride is a --set entry: a field of a generated compose service and its value
+type ServiceOverride struct {
+	Service string
+	Field   string
+	Value   string
+}
+
+// Parses --set entries of the form <service>.<field>=<value>, ex: ord.ports=8080:80,8443:443
+func ParseServiceOverrides(entries []string) ([]ServiceOverride, error) {
+	var overrides []ServiceOverride
+	for _, entry := range entries {
+		key, value, found := strings.Cut(entry, "=")
+		service, field, hasField := strings.Cut(key, ".")
+		if !found || !hasField || service == "" {
+			return nil, fmt.Errorf("invalid --set %q, expected <service>.<field>=<value> (ex: ord.mem-limit=2g)", entry)
+		}
+		if !slices.Contains(serviceOverrideFields, field) {
+			return nil, fmt.Errorf("unknown field %s in --set %q, fields are: %s", field, entry, strings.Join(serviceOverrideFields, ", "))
+		}
+		overrides = append(overrides, ServiceOverride{Service: service, Field: field, Value: value})
+	}
+	return overrides, nil
+}
+
+// Returns the --set overrides, given as flags or in the config file
+func GetServiceOverrides() ([]ServiceOverride, error) {
+	return ParseServiceOverrides(viper.GetStringSlice("set"))
+}
+
+// Checks every override names one of the generated services. A node service may be
+// named without its instance suffix, ex: bitcoin-core for bitcoin-core-pruned.
+func ValidateServiceOverrides(overrides []ServiceOverride, services []string) error {
+	for _, override := range overrides {
+		if overrideServiceName(override, services) == "" {
+			return fmt.Errorf("unknown service %s in --set %s.%s, services of this deployment are: %s", override.Service, override.Service, override.Field, strings.Join(services, ", "))
+		}
+	}
+	return nil
+}
+
+// Returns the generated service an override names, or "" if none
+func overrideServiceName(override ServiceOverride, services []string) string {
+	for _, service := range services {
+		if override.Service == service || utils.InstanceName(override.Service) == service {
+			return service
+		}
+	}
+	return ""
+}
+
+// Applies the overrides of one generated service to its final configuration
+func applyServiceOverrides(service string, config *NetworkConfig, overrides []ServiceOverride) {
+	for _, override := range overrides {
+		if overrideServiceName(override, []string{service}) == "" {
+			continue
+		}
+
+		value := override.Value
+		switch override.Field {
+		case "image":
+			config.Image = value
+		case "version":
+			config.Version = value
+		case "container-name":
+			config.ContainerName = value
+		case "restart":
+			config.Restart = value
+		case "command":
+			config.Command = value
+		case "ports":
+			config.Ports = splitOverrideList(value)
+		case "volumes":
+			config.Volumes = splitOverrideList(value)
+		case "networks":
+			config.Networks = splitOverrideList(value)
+		case "environment":
+			// Variables are added to those the service already sets
+			environment := map[string]string{}
+			for name, existing := range config.Environment {
+				environment[name] = existing
+			}
+			for _, variable := range splitOverrideList(value) {
+				name, variableValue, _ := strings.Cut(variable, "=")
+				environment[name] = variableValue
+			}
+			config.Environment = environment
+		case "cpu-limit":
+			config.Deploy.Resources.Limits.CPUs = value
+		case "mem-limit":
+			config.Deploy.Resources.Limits.Memory = value
+		case "cpu-reservation":
+			config.Deploy.Resources.Reservations.CPUs = value
+		case "mem-reservation":
+			config.Deploy.Resources.Reservations.Memory = value
+		case "snapshot-sync-command":
+			config.SnapshotSyncCommand = value
+		case "snapshot-sync-cid":
+			config.SnapshotSyncCID = value
+		case "snapshot-sync-data-dir":
+			config.LocalChainDataPath = value
+		case "snapshot-sync-file-name":
+			config.SnapshotDataFilename = value
+		}
+	}
+}
+
+// Splits a comma separated --set value, ex: 8080:80,8443:443
+func splitOverrideList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
@@ -0,0 +1,93 @@
+/*
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 The Nodevin Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package compose
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/fiftysixcrypto/nodevin/internal/utils"
+	"github.com/spf13/viper"
+)
+
+const (
+	containerBlocksPath = "/node/blocks"
+	containerIndexPath  = "/node/ord-index"
+)
+
+// Stores raw block files for a bitcoin-core style node under --blocks-dir (ex: a large HDD)
+// while the chainstate and txindex remain in the nodevin data directory.
+func applyBlocksDir(config *NetworkConfig) error {
+	blocksDir := viper.GetString("blocks-dir")
+	if blocksDir == "" {
+		return nil
+	}
+
+	resolvedBlocksDir, err := utils.ResolveLocalPath(blocksDir)
+	if err != nil {
+		return fmt.Errorf("invalid blocks directory: %w", err)
+	}
+
+	hostBlocksPath := filepath.Join(resolvedBlocksDir, config.ContainerName)
+	config.Volumes = append(config.Volumes, fmt.Sprintf("%s:%s", hostBlocksPath, containerBlocksPath))
+	config.Command = fmt.Sprintf("%s -blocksdir=%s", config.Command, containerBlocksPath)
+	setExtraDataPath(config, "blocks", hostBlocksPath)
+
+	return nil
+}
+
+// Stores the ord index (index.redb) under --index-dir (ex: a fast SSD).
+func applyIndexDir(config *NetworkConfig) error {
+	indexDir := viper.GetString("index-dir")
+	if indexDir == "" {
+		return nil
+	}
+
+	resolvedIndexDir, err := utils.ResolveLocalPath(indexDir)
+	if err != nil {
+		return fmt.Errorf("invalid index directory: %w", err)
+	}
+
+	hostIndexPath := filepath.Join(resolvedIndexDir, config.ContainerName)
+	config.Volumes = append(config.Volumes, fmt.Sprintf("%s:%s", hostIndexPath, containerIndexPath))
+	config.Command = fmt.Sprintf("%s --index %s/index.redb", config.Command, containerIndexPath)
+	setExtraDataPath(config, "index", hostIndexPath)
+
+	return nil
+}
+
+func setExtraDataPath(config *NetworkConfig, label string, hostPath string) {
+	if config.ExtraDataPaths == nil {
+		config.ExtraDataPaths = map[string]string{}
+	}
+	config.ExtraDataPaths[label] = hostPath
+}
+
+// Creates any separate data directories and records them next to the main data
+// so that info and delete can find every location later.
+func prepareExtraDataPaths(config NetworkConfig) error {
+	for _, hostPath := range config.ExtraDataPaths {
+		if err := os.MkdirAll(hostPath, 0755); err != nil {
+			return fmt.Errorf("failed to create data directory %s: %w", hostPath, err)
+		}
+	}
+
+	return utils.WriteDataLocations(config.LocalPath, config.ExtraDataPaths)
+}
@@ -18,6 +18,8 @@
 
 package compose
 
+import "github.com/fiftysixcrypto/nodevin/pkg/profile"
+
 // ResourceDetails defines CPU and memory limits and reservations.
 type ResourceDetails struct {
 	CPUs   string `yaml:"cpus,omitempty"`
@@ -52,6 +54,7 @@ type ServiceDependsOnCondition struct {
 // Service defines the configuration of a service in the Docker Compose file.
 type Service struct {
 	Image         string                               `yaml:"image"`
+	PullPolicy    string                               `yaml:"pull_policy,omitempty"`
 	ContainerName string                               `yaml:"container_name"`
 	User          string                               `yaml:"user,omitempty"`
 	Restart       string                               `yaml:"restart"`
@@ -64,6 +67,7 @@ type Service struct {
 	Environment   map[string]string                    `yaml:"environment,omitempty"`
 	DependsOn     map[string]ServiceDependsOnCondition `yaml:"depends_on,omitempty"`
 	Deploy        *Deploy                              `yaml:"deploy,omitempty"`
+	Labels        map[string]string                    `yaml:"labels,omitempty"`
 }
 
 // NetworkDetails defines the network configuration for a service.
@@ -86,6 +90,7 @@ type ComposeFile struct {
 
 // NetworkConfig holds the configuration used to override or define services.
 type NetworkConfig struct {
+	Network              string
 	Image                string
 	Version              string
 	ContainerName        string
@@ -103,4 +108,17 @@ type NetworkConfig struct {
 	LocalChainDataPath   string
 	SnapshotDataFilename string
 	SnapshotSyncCommand  string
+	ExtraDataPaths       map[string]string
+	Profile              *profile.Settings
+	SkipDataInit         bool // images not built by nodevin have no files to copy into LocalPath
+	Entrypoint           string
+	SyncGate             *SyncGate
+}
+
+// SyncGate holds an add-on back until its node has synced. A container of the node's image
+// polls the node with Check (ex: bitcoin-cli ... getblockchaininfo) and creates the file Done,
+// which the add-on's command waits for.
+type SyncGate struct {
+	Check string
+	Done  string
 }
@@ -21,15 +21,22 @@ package docker
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os/exec"
 	"strings"
+	"sync"
 
 	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/api/types/volume"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/jsonmessage"
 	"github.com/fiftysixcrypto/nodevin/internal/logger"
+	"github.com/fiftysixcrypto/nodevin/internal/utils"
 )
 
 var dockerClient *client.Client
@@ -171,13 +178,99 @@ func RemoveVolume(volumeName string) (bool, error) {
 	return true, nil
 }
 func PullImage(image string) error {
+	if dockerClient == nil {
+		if err := InitDockerClient(); err != nil {
+			return fmt.Errorf("failed to initialize Docker client: %w", err)
+		}
+	}
+
+	pull, err := needsPull(image)
+	if err != nil || !pull {
+		return err
+	}
+
+	// If local image is outdated or not found, pull the new image
+	logger.LogInfo("Pulling Docker image: " + image)
+	if err := pullRemoteImage(context.Background(), image, newPullProgress()); err != nil {
+		logger.LogError("Failed to pull Docker image: " + err.Error())
+		return err
+	}
+	return nil
+}
+
+// Pulls images that aren't available locally concurrently, showing their progress together.
+// Returns the failures of all pulls once every pull is done.
+func PullImages(images []string) error {
+	if dockerClient == nil {
+		if err := InitDockerClient(); err != nil {
+			return fmt.Errorf("failed to initialize Docker client: %w", err)
+		}
+	}
+
+	// Check local images first so their log lines don't interleave with the progress display
+	var pulls []string
+	seen := map[string]bool{}
+	for _, image := range images {
+		if seen[image] {
+			continue
+		}
+		seen[image] = true
+
+		pull, err := needsPull(image)
+		if err != nil {
+			return err
+		}
+		if pull {
+			pulls = append(pulls, image)
+		}
+	}
+	if len(pulls) == 0 {
+		return nil
+	}
+
+	logger.LogInfo("Pulling Docker images: " + strings.Join(pulls, ", "))
+	err := PullRemoteImages(context.Background(), pulls)
+	if err != nil {
+		logger.LogError(err.Error())
+	}
+	return err
+}
+
+// Pulls images concurrently whether or not they are available locally, showing their progress
+// together. Returns the failures of all pulls once every pull is done.
+func PullRemoteImages(ctx context.Context, images []string) error {
+	if dockerClient == nil {
+		if err := InitDockerClient(); err != nil {
+			return fmt.Errorf("failed to initialize Docker client: %w", err)
+		}
+	}
+
+	progress := newPullProgress()
+	errs := make([]error, len(images))
+	var wg sync.WaitGroup
+	for i, image := range images {
+		wg.Add(1)
+		go func(i int, image string) {
+			defer wg.Done()
+			if err := pullRemoteImage(ctx, image, progress); err != nil {
+				errs[i] = fmt.Errorf("failed to pull %s: %w", image, err)
+			}
+		}(i, image)
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// Reports whether an image has to be pulled because it isn't available locally
+func needsPull(image string) (bool, error) {
 	logger.LogInfo("Checking if Docker image exists locally: " + image)
 
 	// List images to check if the desired image already exists locally
 	images, err := dockerClient.ImageList(context.Background(), types.ImageListOptions{})
 	if err != nil {
 		logger.LogError("Failed to list Docker images: " + err.Error())
-		return err
+		return false, err
 	}
 
 	// Track if the image is found locally
@@ -197,24 +290,227 @@ func PullImage(image string) error {
 		upstreamImage, _, err := dockerClient.ImageInspectWithRaw(context.Background(), image)
 		if err != nil {
 			logger.LogError("Failed to inspect Docker image: " + err.Error())
-			return err
+			return false, err
 		}
 
 		if localDigest == upstreamImage.ID {
 			logger.LogInfo("Local Docker image is up to date: " + image)
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Pulls an image with the credentials from the Docker config. Docker Hub tags are pulled through
+// the configured mirrors first and tagged under their original name.
+func PullRemoteImage(ctx context.Context, image string) error {
+	if dockerClient == nil {
+		if err := InitDockerClient(); err != nil {
+			return fmt.Errorf("failed to initialize Docker client: %w", err)
+		}
+	}
+
+	return pullRemoteImage(ctx, image, newPullProgress())
+}
+
+func pullRemoteImage(ctx context.Context, image string, progress *pullProgress) error {
+	ref := parseImageReference(image)
+	if ref.Host == dockerHubRegistry && !strings.Contains(image, "@") {
+		for _, mirror := range utils.GetRegistryMirrors() {
+			mirrorImage := fmt.Sprintf("%s/%s:%s", mirror, ref.Repository, ref.Tag)
+			if err := pullImage(ctx, mirrorImage, image, mirror, progress); err != nil {
+				logger.LogInfo(fmt.Sprintf("Failed to pull %s through mirror %s: %v", image, mirror, err))
+				continue
+			}
+			if err := dockerClient.ImageTag(ctx, mirrorImage, image); err != nil {
+				return fmt.Errorf("failed to tag %s as %s: %w", mirrorImage, image, err)
+			}
 			return nil
 		}
 	}
 
-	// If local image is outdated or not found, pull the new image
-	logger.LogInfo("Pulling Docker image: " + image)
-	out, err := dockerClient.ImagePull(context.Background(), image, types.ImagePullOptions{})
+	return pullImage(ctx, image, image, ref.Host, progress)
+}
+
+// Pulls an image from a registry host, reporting its progress under displayName
+func pullImage(ctx context.Context, image string, displayName string, host string, progress *pullProgress) error {
+	auth, err := getRegistryAuth(host)
+	if err != nil {
+		return err
+	}
+
+	out, err := dockerClient.ImagePull(ctx, image, types.ImagePullOptions{RegistryAuth: auth})
 	if err != nil {
-		logger.LogError("Failed to pull Docker image: " + err.Error())
 		return err
 	}
 	defer out.Close()
 
-	fmt.Println(out)
-	return nil
+	decoder := json.NewDecoder(out)
+	for {
+		var message jsonmessage.JSONMessage
+		if err := decoder.Decode(&message); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to read docker pull output: %w", err)
+		}
+		if message.Error != nil {
+			return message.Error
+		}
+		progress.update(displayName, message)
+	}
+}
+
+// Returns the repository digest (ex: fiftysix/bitcoin-core@sha256:...) of a local image
+func GetImageDigest(ctx context.Context, image string) (string, error) {
+	if dockerClient == nil {
+		if err := InitDockerClient(); err != nil {
+			return "", fmt.Errorf("failed to initialize Docker client: %w", err)
+		}
+	}
+
+	inspect, _, err := dockerClient.ImageInspectWithRaw(ctx, image)
+	if err != nil {
+		return "", err
+	}
+	if len(inspect.RepoDigests) == 0 {
+		// Locally built images have no registry digest, fall back to the image ID
+		return inspect.ID, nil
+	}
+	return inspect.RepoDigests[0], nil
+}
+
+// Returns the ID of a local image, which stays valid after its tag moves to a newer image
+func GetImageID(ctx context.Context, image string) (string, error) {
+	if dockerClient == nil {
+		if err := InitDockerClient(); err != nil {
+			return "", fmt.Errorf("failed to initialize Docker client: %w", err)
+		}
+	}
+
+	inspect, _, err := dockerClient.ImageInspectWithRaw(ctx, image)
+	if err != nil {
+		return "", err
+	}
+	return inspect.ID, nil
+}
+
+// Points an image reference (ex: fiftysix/bitcoin-core:latest) at a local image ID
+func TagImage(ctx context.Context, imageID string, image string) error {
+	if dockerClient == nil {
+		if err := InitDockerClient(); err != nil {
+			return fmt.Errorf("failed to initialize Docker client: %w", err)
+		}
+	}
+
+	return dockerClient.ImageTag(ctx, imageID, image)
+}
+
+// Returns the state of a container (running, exit code, health) by name or ID
+func GetContainerState(ctx context.Context, containerName string) (*types.ContainerState, error) {
+	if dockerClient == nil {
+		if err := InitDockerClient(); err != nil {
+			return nil, fmt.Errorf("failed to initialize Docker client: %w", err)
+		}
+	}
+
+	inspect, err := dockerClient.ContainerInspect(ctx, containerName)
+	if err != nil {
+		return nil, err
+	}
+	if inspect.ContainerJSONBase == nil || inspect.State == nil {
+		return nil, fmt.Errorf("no state reported for container %s", containerName)
+	}
+	return inspect.State, nil
+}
+
+// Sets a container's restart policy to "no", so a process that exits on its own stays stopped
+func DisableContainerRestart(ctx context.Context, containerName string) error {
+	if dockerClient == nil {
+		if err := InitDockerClient(); err != nil {
+			return fmt.Errorf("failed to initialize Docker client: %w", err)
+		}
+	}
+
+	_, err := dockerClient.ContainerUpdate(ctx, containerName, container.UpdateConfig{
+		RestartPolicy: container.RestartPolicy{Name: container.RestartPolicyDisabled},
+	})
+	return err
+}
+
+// Returns the tags and digests of a repository (ex: fiftysix/bitcoin-core) that are pulled locally
+func ListLocalImageTags(ctx context.Context, repository string) (map[string]bool, map[string]bool, error) {
+	if dockerClient == nil {
+		if err := InitDockerClient(); err != nil {
+			return nil, nil, fmt.Errorf("failed to initialize Docker client: %w", err)
+		}
+	}
+
+	images, err := dockerClient.ImageList(ctx, types.ImageListOptions{
+		Filters: filters.NewArgs(filters.Arg("reference", repository)),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tags := map[string]bool{}
+	digests := map[string]bool{}
+	for _, image := range images {
+		for _, repoTag := range image.RepoTags {
+			if tag, found := strings.CutPrefix(repoTag, repository+":"); found {
+				tags[tag] = true
+			}
+		}
+		for _, repoDigest := range image.RepoDigests {
+			if digest, found := strings.CutPrefix(repoDigest, repository+"@"); found {
+				digests[digest] = true
+			}
+		}
+	}
+	return tags, digests, nil
+}
+
+// Writes the given images to w in `docker save` format
+func SaveImages(ctx context.Context, images []string, w io.Writer) error {
+	if dockerClient == nil {
+		if err := InitDockerClient(); err != nil {
+			return fmt.Errorf("failed to initialize Docker client: %w", err)
+		}
+	}
+
+	reader, err := dockerClient.ImageSave(ctx, images)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	_, err = io.Copy(w, reader)
+	return err
+}
+
+// Loads images from a `docker save` archive, returning the error the daemon reports if any
+func LoadImages(ctx context.Context, r io.Reader) error {
+	if dockerClient == nil {
+		if err := InitDockerClient(); err != nil {
+			return fmt.Errorf("failed to initialize Docker client: %w", err)
+		}
+	}
+
+	response, err := dockerClient.ImageLoad(ctx, r, true)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	decoder := json.NewDecoder(response.Body)
+	for {
+		var message jsonmessage.JSONMessage
+		if err := decoder.Decode(&message); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to read docker load output: %w", err)
+		}
+		if message.Error != nil {
+			return message.Error
+		}
+	}
 }
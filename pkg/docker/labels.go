@@ -0,0 +1,198 @@
+/*
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 The Nodevin Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package docker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/fiftysixcrypto/nodevin/internal/utils"
+)
+
+// Labels stamped on every service nodevin generates, used to find containers
+// regardless of their image or container name.
+const (
+	LabelNetwork     = "nodevin.network"
+	LabelRole        = "nodevin.role"
+	LabelComposeFile = "nodevin.compose-file"
+	LabelVersion     = "nodevin.version"
+	LabelDataDir     = "nodevin.data-dir"
+	LabelInstance    = "nodevin.instance"
+)
+
+// Values of the nodevin.role label
+const (
+	RoleNode    = "node"
+	RoleSidecar = "sidecar"
+	RoleInit    = "init"
+	RoleUpdater = "updater"
+)
+
+// Lists containers created by nodevin (running only unless all is set), limited to roles if any are given.
+// Containers created before nodevin labelled its services are matched by their default names instead.
+func ListNodevinContainers(ctx context.Context, all bool, roles ...string) ([]types.Container, error) {
+	if dockerClient == nil {
+		if err := InitDockerClient(); err != nil {
+			return nil, fmt.Errorf("failed to initialize Docker client: %w", err)
+		}
+	}
+
+	// Label filters are ANDed by Docker, so each role needs its own query
+	var queries []filters.Args
+	if len(roles) == 0 {
+		queries = append(queries, filters.NewArgs(filters.Arg("label", LabelNetwork)))
+	}
+	for _, role := range roles {
+		queries = append(queries, filters.NewArgs(
+			filters.Arg("label", LabelNetwork),
+			filters.Arg("label", LabelRole+"="+role),
+		))
+	}
+
+	var matching []types.Container
+	for _, query := range queries {
+		containers, err := dockerClient.ContainerList(ctx, container.ListOptions{All: all, Filters: query})
+		if err != nil {
+			return nil, err
+		}
+		matching = append(matching, containers...)
+	}
+
+	legacy, err := listLegacyContainers(ctx, all)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range legacy {
+		if len(roles) == 0 {
+			matching = append(matching, c)
+			continue
+		}
+		for _, role := range roles {
+			if c.Labels[LabelRole] == role {
+				matching = append(matching, c)
+				break
+			}
+		}
+	}
+
+	return matching, nil
+}
+
+// Lists unlabelled containers with the fixed names older nodevin versions gave them, with their labels inferred
+func listLegacyContainers(ctx context.Context, all bool) ([]types.Container, error) {
+	// Name filters are ORed by Docker and match substrings, legacyLabels checks the exact names
+	names := filters.NewArgs(filters.Arg("name", "init-config-"), filters.Arg("name", "watchtower-nodevin"))
+	for _, containerName := range utils.NetworkContainerMap() {
+		names.Add("name", containerName)
+	}
+
+	// Catches a watchtower-nodevin that compose renamed
+	watchtowers := filters.NewArgs(filters.Arg("label", "com.docker.compose.service=watchtower"))
+
+	seen := map[string]bool{}
+	var legacy []types.Container
+	for _, query := range []filters.Args{names, watchtowers} {
+		containers, err := dockerClient.ContainerList(ctx, container.ListOptions{All: all, Filters: query})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, c := range containers {
+			if _, labelled := c.Labels[LabelNetwork]; labelled || seen[c.ID] {
+				continue
+			}
+			labels, ok := legacyLabels(c)
+			if !ok {
+				continue
+			}
+			seen[c.ID] = true
+			c.Labels = labels
+			legacy = append(legacy, c)
+		}
+	}
+
+	return legacy, nil
+}
+
+// Infers the labels of an unlabelled container from the fixed names older nodevin versions gave them
+func legacyLabels(c types.Container) (map[string]string, bool) {
+	if !fromNodevinCompose(c) {
+		return nil, false
+	}
+	name := ContainerName(c)
+
+	if network, isInit := strings.CutPrefix(name, "init-config-"); isInit {
+		return map[string]string{LabelNetwork: network, LabelRole: RoleInit}, true
+	}
+
+	if name == "watchtower-nodevin" || isLegacyWatchtower(c) {
+		return map[string]string{LabelNetwork: "", LabelRole: RoleUpdater}, true
+	}
+
+	for network, containerName := range utils.NetworkContainerMap() {
+		if containerName == name {
+			return map[string]string{LabelNetwork: network, LabelRole: RoleNode}, true
+		}
+	}
+
+	return nil, false
+}
+
+// Reports whether c runs the watchtower image, ex: a watchtower-nodevin that compose renamed
+func isLegacyWatchtower(c types.Container) bool {
+	image := strings.TrimPrefix(strings.SplitN(c.Image, ":", 2)[0], "docker.io/")
+	return image == "containrrr/watchtower"
+}
+
+// Reports whether c was started from a nodevin compose file (docker-compose_<name>.yml), so an
+// unrelated container that happens to be named bitcoin-core is left alone
+func fromNodevinCompose(c types.Container) bool {
+	return strings.Contains(c.Labels["com.docker.compose.project.config_files"], "docker-compose_")
+}
+
+// Returns the container's name without the leading slash
+func ContainerName(c types.Container) string {
+	if len(c.Names) == 0 {
+		return ""
+	}
+	return strings.TrimPrefix(c.Names[0], "/")
+}
+
+// Stops (if running) and removes a container
+func StopAndRemoveContainer(ctx context.Context, containerID string) error {
+	if dockerClient == nil {
+		if err := InitDockerClient(); err != nil {
+			return fmt.Errorf("failed to initialize Docker client: %w", err)
+		}
+	}
+
+	if err := dockerClient.ContainerStop(ctx, containerID, container.StopOptions{}); err != nil {
+		return fmt.Errorf("failed to stop container %s: %w", containerID, err)
+	}
+
+	if err := dockerClient.ContainerRemove(ctx, containerID, container.RemoveOptions{}); err != nil {
+		return fmt.Errorf("failed to remove container %s: %w", containerID, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,151 @@
+/*
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 The Nodevin Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package docker
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/docker/docker/pkg/jsonmessage"
+	"github.com/docker/go-units"
+	"github.com/fiftysixcrypto/nodevin/internal/logger"
+	"github.com/fiftysixcrypto/nodevin/internal/output"
+)
+
+const progressBarWidth = 30
+
+// Renders the progress streams of one or more concurrent pulls: a progress bar per layer when
+// stdout is a terminal, otherwise a log line (a JSON line on stderr with --output json/yaml)
+// each time a layer changes status
+type pullProgress struct {
+	mu          sync.Mutex
+	interactive bool
+	structured  bool
+	// Lines on screen in display order, keyed by image and layer ID
+	lines []string
+	text  map[string]string
+	// Last status logged per layer, so download ticks don't produce a line each
+	statuses map[string]string
+	// Number of lines drawn by the last render
+	drawn int
+}
+
+// A layer status change in structured mode
+type pullEvent struct {
+	Image   string `json:"image"`
+	Layer   string `json:"layer,omitempty"`
+	Status  string `json:"status"`
+	Current int64  `json:"current,omitempty"`
+	Total   int64  `json:"total,omitempty"`
+}
+
+func newPullProgress() *pullProgress {
+	structured := output.IsStructured()
+	return &pullProgress{
+		interactive: !structured && isTerminal(os.Stdout),
+		structured:  structured,
+		text:        map[string]string{},
+		statuses:    map[string]string{},
+	}
+}
+
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Records one message of an image's pull stream
+func (p *pullProgress) update(image string, message jsonmessage.JSONMessage) {
+	if message.Status == "" {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := image + " " + message.ID
+	if p.interactive {
+		if _, exists := p.text[key]; !exists {
+			p.lines = append(p.lines, key)
+		}
+		p.text[key] = formatPullLine(image, message)
+		p.render()
+		return
+	}
+
+	if p.statuses[key] == message.Status {
+		return
+	}
+	p.statuses[key] = message.Status
+
+	if p.structured {
+		event := pullEvent{Image: image, Layer: message.ID, Status: message.Status}
+		if message.Progress != nil {
+			event.Current, event.Total = message.Progress.Current, message.Progress.Total
+		}
+		data, _ := json.Marshal(event)
+		fmt.Fprintln(os.Stderr, string(data))
+		return
+	}
+
+	// Messages about the whole image carry no ID or the tag (Pulling from ...) as ID
+	if message.ID == "" || message.ID == parseImageReference(image).Tag {
+		logger.LogInfo(fmt.Sprintf("%s: %s", image, message.Status))
+	} else {
+		logger.LogInfo(fmt.Sprintf("%s: layer %s: %s", image, message.ID, message.Status))
+	}
+}
+
+// Redraws every line in place, moving the cursor back over the previous render
+func (p *pullProgress) render() {
+	var b strings.Builder
+	if p.drawn > 0 {
+		fmt.Fprintf(&b, "\033[%dA", p.drawn)
+	}
+	for _, key := range p.lines {
+		fmt.Fprintf(&b, "\033[2K\r%s\n", p.text[key])
+	}
+	p.drawn = len(p.lines)
+	fmt.Fprint(os.Stdout, b.String())
+}
+
+// Formats a layer's line, ex: fiftysix/ord:latest 4f4fb700ef54 Downloading [=====>     ] 12.3MB/45.6MB
+func formatPullLine(image string, message jsonmessage.JSONMessage) string {
+	line := image
+	if message.ID != "" {
+		line += " " + message.ID
+	}
+	line += " " + message.Status
+
+	if progress := message.Progress; progress != nil && progress.Total > 0 {
+		filled := int(float64(progressBarWidth) * float64(progress.Current) / float64(progress.Total))
+		if filled > progressBarWidth {
+			filled = progressBarWidth
+		}
+		bar := strings.Repeat("=", filled)
+		if filled < progressBarWidth {
+			bar += ">" + strings.Repeat(" ", progressBarWidth-filled-1)
+		}
+		line += fmt.Sprintf(" [%s] %s/%s", bar, units.HumanSize(float64(progress.Current)), units.HumanSize(float64(progress.Total)))
+	}
+	return line
+}
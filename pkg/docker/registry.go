@@ -0,0 +1,513 @@
+/*
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 The Nodevin Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package docker
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/registry"
+	"github.com/fiftysixcrypto/nodevin/internal/utils"
+)
+
+const (
+	registryTimeout = 30 * time.Second
+
+	// Registry API host of Docker Hub
+	dockerHubRegistry = "registry-1.docker.io"
+)
+
+// Manifest types a digest lookup accepts. Listing the index types first returns the digest
+// `docker pull` records in RepoDigests for multi-platform images.
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// An image reference split into the registry host, the repository on it and the tag
+type imageReference struct {
+	Host       string
+	Repository string
+	Tag        string
+}
+
+// Parses an image reference the way docker does: without a registry host (a first part with
+// a dot, a colon or localhost) it is on Docker Hub, where single names live under library/
+func parseImageReference(image string) imageReference {
+	image = strings.SplitN(image, "@", 2)[0]
+
+	ref := imageReference{Host: dockerHubRegistry, Tag: "latest"}
+	if lastColon := strings.LastIndex(image, ":"); lastColon > strings.LastIndex(image, "/") {
+		image, ref.Tag = image[:lastColon], image[lastColon+1:]
+	}
+
+	parts := strings.SplitN(image, "/", 2)
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		ref.Host = parts[0]
+		image = parts[1]
+	}
+	if isDockerHubHost(ref.Host) {
+		ref.Host = dockerHubRegistry
+		if !strings.Contains(image, "/") {
+			image = "library/" + image
+		}
+	}
+	ref.Repository = image
+
+	return ref
+}
+
+func isDockerHubHost(host string) bool {
+	return host == dockerHubRegistry || host == "docker.io" || host == "index.docker.io"
+}
+
+// Returns the registry hosts to query for a reference: the pull-through mirrors first for Docker Hub images
+func (r imageReference) hosts() []string {
+	if r.Host != dockerHubRegistry {
+		return []string{r.Host}
+	}
+	return append(utils.GetRegistryMirrors(), r.Host)
+}
+
+// Returns the digest (ex: sha256:...) an image tag currently points to in its registry, asking
+// the pull-through mirrors first for Docker Hub images
+func GetRemoteImageDigest(ctx context.Context, image string) (string, error) {
+	ref := parseImageReference(image)
+
+	var lastErr error
+	for _, host := range ref.hosts() {
+		digest, err := newRegistryClient(host).manifestDigest(ctx, ref.Repository, ref.Tag)
+		if err == nil {
+			return digest, nil
+		}
+		lastErr = fmt.Errorf("%s: %w", host, err)
+	}
+	return "", lastErr
+}
+
+// A tag of an image repository
+type RemoteTag struct {
+	Name        string
+	Digest      string
+	LastUpdated time.Time
+	SizeBytes   int64
+}
+
+// Lists up to maxTags tags of an image's repository (all if maxTags is 0). Docker Hub reports them
+// most recently updated first with their sizes; other registries only list names, which are sorted
+// newest version first and resolved to digests one by one.
+func ListRemoteImageTags(ctx context.Context, image string, maxTags int) ([]RemoteTag, error) {
+	ref := parseImageReference(image)
+
+	if ref.Host == dockerHubRegistry {
+		tags, err := listDockerHubTags(ctx, ref, maxTags)
+		if err == nil || len(utils.GetRegistryMirrors()) == 0 {
+			return tags, err
+		}
+	}
+
+	var lastErr error
+	for _, host := range ref.hosts() {
+		client := newRegistryClient(host)
+		names, err := client.listTags(ctx, ref.Repository)
+		if err != nil {
+			lastErr = fmt.Errorf("%s: %w", host, err)
+			continue
+		}
+
+		sortTagsNewestFirst(names)
+		if maxTags > 0 && len(names) > maxTags {
+			names = names[:maxTags]
+		}
+
+		tags := make([]RemoteTag, 0, len(names))
+		for _, name := range names {
+			digest, err := client.manifestDigest(ctx, ref.Repository, name)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", host, err)
+			}
+			tags = append(tags, RemoteTag{Name: name, Digest: digest})
+		}
+		return tags, nil
+	}
+	return nil, lastErr
+}
+
+func listDockerHubTags(ctx context.Context, ref imageReference, maxTags int) ([]RemoteTag, error) {
+	namespace, repository, _ := strings.Cut(ref.Repository, "/")
+
+	httpClient := &http.Client{Timeout: registryTimeout}
+	url := fmt.Sprintf("https://hub.docker.com/v2/namespaces/%s/repositories/%s/tags?page_size=100&ordering=last_updated", namespace, repository)
+
+	var tags []RemoteTag
+	for url != "" && (maxTags <= 0 || len(tags) < maxTags) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		var page struct {
+			Next    string `json:"next"`
+			Results []struct {
+				Name        string    `json:"name"`
+				Digest      string    `json:"digest"`
+				LastUpdated time.Time `json:"last_updated"`
+				FullSize    int64     `json:"full_size"`
+			} `json:"results"`
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("failed to list tags of %s: %s", ref.Repository, resp.Status)
+		}
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse tags of %s: %w", ref.Repository, err)
+		}
+
+		for _, result := range page.Results {
+			tags = append(tags, RemoteTag{
+				Name:        result.Name,
+				Digest:      result.Digest,
+				LastUpdated: result.LastUpdated,
+				SizeBytes:   result.FullSize,
+			})
+		}
+		url = page.Next
+	}
+
+	if maxTags > 0 && len(tags) > maxTags {
+		tags = tags[:maxTags]
+	}
+	return tags, nil
+}
+
+// Sorts tags by version, newest first, with tags that aren't versions (ex: latest) at the front
+func sortTagsNewestFirst(tags []string) {
+	sort.SliceStable(tags, func(i, j int) bool {
+		a, aIsVersion := parseTagVersion(tags[i])
+		b, bIsVersion := parseTagVersion(tags[j])
+		if aIsVersion != bIsVersion {
+			return !aIsVersion
+		}
+		if !aIsVersion {
+			return tags[i] < tags[j]
+		}
+		for k := 0; k < len(a) || k < len(b); k++ {
+			var x, y int
+			if k < len(a) {
+				x = a[k]
+			}
+			if k < len(b) {
+				y = b[k]
+			}
+			if x != y {
+				return x > y
+			}
+		}
+		return false
+	})
+}
+
+func parseTagVersion(tag string) ([]int, bool) {
+	var version []int
+	for _, part := range strings.Split(strings.TrimPrefix(tag, "v"), ".") {
+		var number int
+		if _, err := fmt.Sscanf(part, "%d", &number); err != nil || fmt.Sprint(number) != part {
+			return nil, false
+		}
+		version = append(version, number)
+	}
+	return version, true
+}
+
+// Returns the digest part of a repository digest (fiftysix/bitcoin-core@sha256:... becomes sha256:...)
+func DigestOf(repoDigest string) string {
+	if _, digest, found := strings.Cut(repoDigest, "@"); found {
+		return digest
+	}
+	return repoDigest
+}
+
+// A client of the OCI distribution API (/v2/) of one registry host, authenticating with the
+// credentials from the Docker config through the registry's bearer token or basic auth challenge
+type registryClient struct {
+	host        string
+	scheme      string
+	httpClient  *http.Client
+	credentials *registry.AuthConfig
+	// Authorization header to send, set after answering a challenge
+	authorization string
+}
+
+func newRegistryClient(host string) *registryClient {
+	scheme := "https"
+	if utils.IsInsecureRegistry(host) {
+		scheme = "http"
+	}
+	return &registryClient{host: host, scheme: scheme, httpClient: &http.Client{Timeout: registryTimeout}}
+}
+
+// Returns the digest of a manifest from the Docker-Content-Digest header, or by hashing it
+func (c *registryClient) manifestDigest(ctx context.Context, repository string, reference string) (string, error) {
+	path := fmt.Sprintf("/v2/%s/manifests/%s", repository, reference)
+
+	resp, err := c.do(ctx, http.MethodHead, path)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		if digest := resp.Header.Get("Docker-Content-Digest"); digest != "" {
+			return digest, nil
+		}
+	} else if resp.StatusCode != http.StatusMethodNotAllowed {
+		return "", fmt.Errorf("failed to fetch manifest %s:%s: %s", repository, reference, resp.Status)
+	}
+
+	resp, err = c.do(ctx, http.MethodGet, path)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to fetch manifest %s:%s: %s", repository, reference, resp.Status)
+	}
+	if digest := resp.Header.Get("Docker-Content-Digest"); digest != "" {
+		return digest, nil
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, resp.Body); err != nil {
+		return "", fmt.Errorf("failed to read manifest %s:%s: %w", repository, reference, err)
+	}
+	return "sha256:" + hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Lists every tag of a repository, following the Link header across pages
+func (c *registryClient) listTags(ctx context.Context, repository string) ([]string, error) {
+	path := fmt.Sprintf("/v2/%s/tags/list?n=1000", repository)
+
+	var tags []string
+	for path != "" {
+		resp, err := c.do(ctx, http.MethodGet, path)
+		if err != nil {
+			return nil, err
+		}
+
+		var page struct {
+			Tags []string `json:"tags"`
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("failed to list tags of %s: %s", repository, resp.Status)
+		}
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse tags of %s: %w", repository, err)
+		}
+
+		tags = append(tags, page.Tags...)
+		path = nextPageLink(resp.Header.Get("Link"))
+	}
+	return tags, nil
+}
+
+// Returns the path of a Link: </v2/...>; rel="next" header, or "" on the last page
+func nextPageLink(link string) string {
+	if !strings.Contains(link, `rel="next"`) {
+		return ""
+	}
+	start, end := strings.Index(link, "<"), strings.Index(link, ">")
+	if start < 0 || end < start {
+		return ""
+	}
+	return link[start+1 : end]
+}
+
+// Sends a request, answering an authentication challenge once
+func (c *registryClient) do(ctx context.Context, method string, path string) (*http.Response, error) {
+	resp, err := c.send(ctx, method, path)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	resp.Body.Close()
+
+	if err := c.authenticate(ctx, resp.Header.Get("WWW-Authenticate")); err != nil {
+		return nil, err
+	}
+	return c.send(ctx, method, path)
+}
+
+func (c *registryClient) send(ctx context.Context, method string, path string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.scheme+"://"+c.host+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+	if c.authorization != "" {
+		req.Header.Set("Authorization", c.authorization)
+	}
+	return c.httpClient.Do(req)
+}
+
+// Answers a WWW-Authenticate challenge: Basic with the stored credentials, or Bearer with a token
+// from the registry's token service (anonymous if there are no credentials)
+func (c *registryClient) authenticate(ctx context.Context, challenge string) error {
+	if c.credentials == nil {
+		credentials, err := getRegistryCredentials(c.host)
+		if err != nil {
+			return err
+		}
+		c.credentials = &credentials
+	}
+
+	scheme, params := parseAuthChallenge(challenge)
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if c.credentials.Username == "" {
+			return fmt.Errorf("%s requires credentials, run `docker login %s`", c.host, c.host)
+		}
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		req.SetBasicAuth(c.credentials.Username, c.credentials.Password)
+		c.authorization = req.Header.Get("Authorization")
+		return nil
+	case "bearer":
+		token, err := c.fetchToken(ctx, params)
+		if err != nil {
+			return err
+		}
+		c.authorization = "Bearer " + token
+		return nil
+	default:
+		return fmt.Errorf("%s asked for unsupported authentication %q", c.host, challenge)
+	}
+}
+
+func (c *registryClient) fetchToken(ctx context.Context, params map[string]string) (string, error) {
+	realm := params["realm"]
+	if realm == "" {
+		return "", fmt.Errorf("%s sent a bearer challenge without a realm", c.host)
+	}
+
+	query := url.Values{}
+	if service := params["service"]; service != "" {
+		query.Set("service", service)
+	}
+	if scope := params["scope"]; scope != "" {
+		query.Set("scope", scope)
+	}
+
+	var req *http.Request
+	var err error
+	if c.credentials.IdentityToken != "" {
+		// Identity tokens are exchanged through the OAuth2 refresh token grant
+		query.Set("grant_type", "refresh_token")
+		query.Set("refresh_token", c.credentials.IdentityToken)
+		query.Set("client_id", "nodevin")
+		req, err = http.NewRequestWithContext(ctx, http.MethodPost, realm, strings.NewReader(query.Encode()))
+		if err == nil {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+	} else {
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, realm+"?"+query.Encode(), nil)
+		if err == nil && c.credentials.Username != "" {
+			req.SetBasicAuth(c.credentials.Username, c.credentials.Password)
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to get a token for %s: %s", c.host, resp.Status)
+	}
+
+	var result struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to parse token for %s: %w", c.host, err)
+	}
+	if result.Token != "" {
+		return result.Token, nil
+	}
+	if result.AccessToken != "" {
+		return result.AccessToken, nil
+	}
+	return "", fmt.Errorf("no token received for %s", c.host)
+}
+
+// Splits a WWW-Authenticate header (ex: Bearer realm="...",service="...",scope="...") into its scheme and parameters
+func parseAuthChallenge(challenge string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(challenge), " ")
+	params := map[string]string{}
+
+	for rest != "" {
+		rest = strings.TrimLeft(rest, ", ")
+		key, value, found := strings.Cut(rest, "=")
+		if !found {
+			break
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+
+		if strings.HasPrefix(value, `"`) {
+			end := strings.Index(value[1:], `"`)
+			if end < 0 {
+				params[key] = value[1:]
+				break
+			}
+			params[key] = value[1 : end+1]
+			rest = value[end+2:]
+		} else {
+			next := strings.Index(value, ",")
+			if next < 0 {
+				params[key] = value
+				break
+			}
+			params[key] = value[:next]
+			rest = value[next:]
+		}
+	}
+	return scheme, params
+}
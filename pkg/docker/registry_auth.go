@@ -0,0 +1,176 @@
+/*
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 The Nodevin Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/registry"
+)
+
+const (
+	// Docker Hub's key in the auths of the Docker config
+	dockerHubAuthKey = "https://index.docker.io/v1/"
+
+	credentialHelperTimeout = 30 * time.Second
+)
+
+// The parts of ~/.docker/config.json that hold registry credentials
+type dockerConfigFile struct {
+	Auths map[string]struct {
+		Auth          string `json:"auth"`
+		Username      string `json:"username"`
+		Password      string `json:"password"`
+		IdentityToken string `json:"identitytoken"`
+	} `json:"auths"`
+	CredsStore  string            `json:"credsStore"`
+	CredHelpers map[string]string `json:"credHelpers"`
+}
+
+// Returns the credentials `docker login` stored for a registry host, from a credential helper or
+// the config's auths. Registries without credentials return an empty config.
+func getRegistryCredentials(host string) (registry.AuthConfig, error) {
+	config, err := readDockerConfig()
+	if err != nil || config == nil {
+		return registry.AuthConfig{}, err
+	}
+
+	key := host
+	if isDockerHubHost(host) {
+		key = dockerHubAuthKey
+	}
+
+	helper := config.CredsStore
+	if credHelper, exists := config.CredHelpers[key]; exists {
+		helper = credHelper
+	}
+	if helper != "" {
+		credentials, found, err := runCredentialHelper(helper, key)
+		if err != nil {
+			return registry.AuthConfig{}, err
+		}
+		if found {
+			return credentials, nil
+		}
+	}
+
+	for _, candidate := range []string{key, "https://" + host, "http://" + host, "https://" + host + "/v1/", "https://" + host + "/v2/"} {
+		auth, exists := config.Auths[candidate]
+		if !exists {
+			continue
+		}
+
+		credentials := registry.AuthConfig{
+			Username:      auth.Username,
+			Password:      auth.Password,
+			IdentityToken: auth.IdentityToken,
+			ServerAddress: key,
+		}
+		if auth.Auth != "" {
+			decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+			if err != nil {
+				return registry.AuthConfig{}, fmt.Errorf("invalid auth for %s in Docker config: %w", candidate, err)
+			}
+			username, password, _ := strings.Cut(string(decoded), ":")
+			credentials.Username, credentials.Password = username, password
+		}
+		return credentials, nil
+	}
+
+	return registry.AuthConfig{}, nil
+}
+
+// Returns the X-Registry-Auth value the daemon needs to pull an image from a private registry
+func getRegistryAuth(host string) (string, error) {
+	credentials, err := getRegistryCredentials(host)
+	if err != nil {
+		return "", err
+	}
+	if credentials.Username == "" && credentials.IdentityToken == "" {
+		return "", nil
+	}
+	return registry.EncodeAuthConfig(credentials)
+}
+
+func readDockerConfig() (*dockerConfigFile, error) {
+	configDir := os.Getenv("DOCKER_CONFIG")
+	if configDir == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return nil, nil
+		}
+		configDir = filepath.Join(homeDir, ".docker")
+	}
+
+	data, err := os.ReadFile(filepath.Join(configDir, "config.json"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read Docker config: %w", err)
+	}
+
+	var config dockerConfigFile
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse Docker config: %w", err)
+	}
+	return &config, nil
+}
+
+// Asks a docker-credential-<helper> for the credentials of a registry. Reports false if it has none.
+func runCredentialHelper(helper string, serverURL string) (registry.AuthConfig, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), credentialHelperTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "docker-credential-"+helper, "get")
+	cmd.Stdin = strings.NewReader(serverURL)
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		if strings.Contains(stdout.String(), "credentials not found") {
+			return registry.AuthConfig{}, false, nil
+		}
+		return registry.AuthConfig{}, false, fmt.Errorf("credential helper %s failed: %w", helper, err)
+	}
+
+	var result struct {
+		Username string `json:"Username"`
+		Secret   string `json:"Secret"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &result); err != nil {
+		return registry.AuthConfig{}, false, fmt.Errorf("failed to parse output of credential helper %s: %w", helper, err)
+	}
+
+	credentials := registry.AuthConfig{ServerAddress: serverURL}
+	// Helpers return identity tokens with this placeholder username
+	if result.Username == "<token>" {
+		credentials.IdentityToken = result.Secret
+	} else {
+		credentials.Username, credentials.Password = result.Username, result.Secret
+	}
+	return credentials, true, nil
+}
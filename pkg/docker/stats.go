@@ -0,0 +1,105 @@
+/*
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 The Nodevin Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// ResourceStats is a point-in-time resource snapshot of a running container.
+type ResourceStats struct {
+	CPUUsageSeconds float64
+	CPUPercent      float64
+	MemoryUsage     uint64
+	MemoryLimit     uint64
+	RestartCount    int
+}
+
+// Returns CPU, memory and restart information for a container (same data as `docker stats`)
+func GetContainerResourceStats(ctx context.Context, containerName string) (*ResourceStats, error) {
+	if dockerClient == nil {
+		if err := InitDockerClient(); err != nil {
+			return nil, fmt.Errorf("failed to initialize Docker client: %w", err)
+		}
+	}
+
+	inspect, err := dockerClient.ContainerInspect(ctx, containerName)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := dockerClient.ContainerStats(ctx, containerName, false)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	var raw struct {
+		CPUStats struct {
+			CPUUsage struct {
+				TotalUsage  uint64   `json:"total_usage"`
+				PercpuUsage []uint64 `json:"percpu_usage"`
+			} `json:"cpu_usage"`
+			SystemUsage uint64 `json:"system_cpu_usage"`
+			OnlineCPUs  uint32 `json:"online_cpus"`
+		} `json:"cpu_stats"`
+		PreCPUStats struct {
+			CPUUsage struct {
+				TotalUsage uint64 `json:"total_usage"`
+			} `json:"cpu_usage"`
+			SystemUsage uint64 `json:"system_cpu_usage"`
+		} `json:"precpu_stats"`
+		MemoryStats struct {
+			Usage uint64            `json:"usage"`
+			Limit uint64            `json:"limit"`
+			Stats map[string]uint64 `json:"stats"`
+		} `json:"memory_stats"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("failed to decode container stats: %w", err)
+	}
+
+	stats := &ResourceStats{
+		CPUUsageSeconds: float64(raw.CPUStats.CPUUsage.TotalUsage) / 1e9,
+		MemoryUsage:     raw.MemoryStats.Usage,
+		MemoryLimit:     raw.MemoryStats.Limit,
+		RestartCount:    inspect.RestartCount,
+	}
+
+	// Match the docker CLI: page cache is not counted as used memory
+	if cache, ok := raw.MemoryStats.Stats["inactive_file"]; ok && cache < stats.MemoryUsage {
+		stats.MemoryUsage -= cache
+	} else if cache, ok := raw.MemoryStats.Stats["total_inactive_file"]; ok && cache < stats.MemoryUsage {
+		stats.MemoryUsage -= cache
+	}
+
+	cpuDelta := float64(raw.CPUStats.CPUUsage.TotalUsage) - float64(raw.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(raw.CPUStats.SystemUsage) - float64(raw.PreCPUStats.SystemUsage)
+	onlineCPUs := float64(raw.CPUStats.OnlineCPUs)
+	if onlineCPUs == 0 {
+		onlineCPUs = float64(len(raw.CPUStats.CPUUsage.PercpuUsage))
+	}
+	if cpuDelta > 0 && systemDelta > 0 {
+		stats.CPUPercent = cpuDelta / systemDelta * onlineCPUs * 100
+	}
+
+	return stats, nil
+}
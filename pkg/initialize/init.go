@@ -39,7 +39,7 @@ func runInit() {
 	fmt.Println("")
 
 	// inspection
-	fmt.Println("Nodevin will now inspect your system for docker and docker compose versions...\n")
+	fmt.Print("Nodevin will now inspect your system for docker and docker compose versions...\n\n")
 	if err := performInspection(); err != nil {
 		fmt.Println("")
 		logger.LogError("System inspection failed: " + err.Error())
@@ -76,7 +76,7 @@ func runInit() {
 	fmt.Println("")
 
 	// outro
-	fmt.Println("It's time to start your own Bitcoin node. Run `nodevin start bitcoin` to get started.")
+	fmt.Println("It's time to start your own Bitcoin node. Run `nodevin bitcoin start` to get started.")
 	fmt.Println("Thank you for using nodevin!")
 }
 
@@ -19,79 +19,33 @@
 package bitcoin
 
 import (
-	"runtime"
-
-	"github.com/fiftysixcrypto/nodevin/internal/logger"
 	"github.com/fiftysixcrypto/nodevin/internal/utils"
-	"github.com/fiftysixcrypto/nodevin/pkg/docker"
 	"github.com/fiftysixcrypto/nodevin/pkg/docker/compose"
-	"github.com/spf13/viper"
 )
 
 func CreateBitcoinComposeFile(cwd string) (string, error) {
-	var network string
-
-	if utils.CheckIfTestnetOrTestnetNetworkFlag() {
-		network = "bitcoin-testnet"
-	} else {
-		network = "bitcoin"
-	}
+	network := utils.GetVariantNetwork("bitcoin")
 
 	bitcoinBaseComposeConfig, err := compose.GetBitcoinNetworkComposeConfig(network)
 	if err != nil {
 		return "", err
 	}
 
-	composeFilePath, err := compose.CreateComposeFile(
-		bitcoinBaseComposeConfig.ContainerName,
-		bitcoinBaseComposeConfig,
-		[]string{},
-		[]compose.NetworkConfig{},
-		cwd)
-
+	// Add-ons (ex: ord) are written as extra services of the node's compose file
+	addons, err := compose.GetEnabledAddons("bitcoin")
 	if err != nil {
 		return "", err
 	}
 
-	if viper.GetBool("ord") {
-		if runtime.GOARCH == "arm64" {
-			logger.LogError("Running on ARM architecture: ord functionality is not supported on ARM builds. Skipping ord.")
-			return composeFilePath, nil
-		}
-
-		var ordNetwork string
-
-		if utils.CheckIfTestnetOrTestnetNetworkFlag() {
-			ordNetwork = "ord-testnet"
-		} else {
-			ordNetwork = "ord"
-		}
-
-		// Pull the ord Docker image
-		image := viper.GetString("ord-image") + ":" + viper.GetString("ord-version")
-		if err := docker.PullImage(image); err != nil {
-			logger.LogError("Failed to pull Docker image: " + err.Error())
-			return "", err
-		}
-
-		ordComposeConfig, err := compose.GetOrdNetworkComposeConfig(ordNetwork)
-		if err != nil {
-			return "", err
-		}
-
-		composeFilePath, err := compose.CreateComposeFile(
-			bitcoinBaseComposeConfig.ContainerName,
-			bitcoinBaseComposeConfig,
-			[]string{"ord"},
-			[]compose.NetworkConfig{ordComposeConfig},
-			cwd)
-
-		if err != nil {
-			return "", err
-		}
-
-		return composeFilePath, nil
-	} else {
-		return composeFilePath, nil
+	addonNames, addonConfigs, err := compose.GetAddonComposeConfigs(addons, &bitcoinBaseComposeConfig)
+	if err != nil {
+		return "", err
 	}
+
+	return compose.CreateComposeFile(
+		bitcoinBaseComposeConfig.ContainerName,
+		bitcoinBaseComposeConfig,
+		addonNames,
+		addonConfigs,
+		cwd)
 }
@@ -0,0 +1,428 @@
+/*
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 The Nodevin Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package nodes
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fiftysixcrypto/nodevin/internal/bundle"
+	"github.com/fiftysixcrypto/nodevin/internal/lockfile"
+	"github.com/fiftysixcrypto/nodevin/internal/logger"
+	"github.com/fiftysixcrypto/nodevin/internal/state"
+	"github.com/fiftysixcrypto/nodevin/internal/utils"
+	"github.com/fiftysixcrypto/nodevin/internal/version"
+	"github.com/fiftysixcrypto/nodevin/pkg/docker"
+	"github.com/fiftysixcrypto/nodevin/pkg/docker/compose"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	dockerSaveTimeout = time.Hour
+	dockerLoadTimeout = time.Hour
+)
+
+var bundleCmd = &cobra.Command{
+	Use:   "bundle",
+	Short: "Move images and nodevin to hosts without internet access",
+}
+
+var bundleCreateCmd = &cobra.Command{
+	Use:   "create <network>...",
+	Short: "Save the images of one or more networks, nodevin and checksums into one archive",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return createBundle(args)
+	},
+}
+
+var bundleInstallCmd = &cobra.Command{
+	Use:   "install <archive>",
+	Short: "Verify and load a bundle so its networks start without pulling images",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return installBundle(args[0])
+	},
+}
+
+func createBundle(networks []string) error {
+	if err := docker.InitDockerClient(); err != nil {
+		return fmt.Errorf("failed to initialize Docker client: %w", err)
+	}
+
+	manifest := &bundle.Manifest{
+		CreatedAt:      time.Now().UTC(),
+		NodevinVersion: version.Version,
+		Platform:       "linux/" + runtime.GOARCH,
+		Deployments:    map[string]bundle.Deployment{},
+	}
+
+	for _, arg := range networks {
+		network, err := resolveNetworkArg(arg)
+		if err != nil {
+			return err
+		}
+		if _, exists := utils.GetNetworkImageRepository(network); !exists {
+			return fmt.Errorf("unsupported network: %s", network)
+		}
+
+		deploymentName := getDeploymentName(network)
+		images, err := bundleImageTags(network, deploymentName)
+		if err != nil {
+			return err
+		}
+
+		deployment := bundle.Deployment{Network: getSoftwareNetwork(network), Images: map[string]bundle.Image{}, Snapshots: map[string]string{}}
+		for serviceNetwork, image := range images {
+			saved, err := prepareBundleImage(image)
+			if err != nil {
+				return err
+			}
+			deployment.Images[serviceNetwork] = saved
+
+			if cid, exists := utils.GetSnapshotCIDByNetwork(serviceNetwork); exists && cid != "" {
+				deployment.Snapshots[serviceNetwork] = cid
+			}
+		}
+		manifest.Deployments[deploymentName] = deployment
+	}
+
+	tmpDir, err := os.MkdirTemp("", "nodevin-bundle-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	imageTags := manifest.ImageTags()
+	logger.LogInfo(fmt.Sprintf("Saving %d images: %s", len(imageTags), strings.Join(imageTags, ", ")))
+
+	imagesPath := filepath.Join(tmpDir, bundle.ImagesName)
+	if err := saveBundleImages(imageTags, imagesPath); err != nil {
+		return err
+	}
+	files := []bundle.File{{Name: bundle.ImagesName, Path: imagesPath}}
+
+	executable := viper.GetString("bundle-executable")
+	if executable == "" {
+		executable, err = os.Executable()
+		if err != nil {
+			return fmt.Errorf("failed to find nodevin executable: %w", err)
+		}
+	}
+	if !viper.GetBool("bundle-no-executable") {
+		manifest.Executable = "nodevin"
+		if strings.HasSuffix(executable, ".exe") {
+			manifest.Executable = "nodevin.exe"
+		}
+		files = append(files, bundle.File{Name: manifest.Executable, Path: executable})
+	}
+
+	path := viper.GetString("bundle-file")
+	if path == "" {
+		path = fmt.Sprintf("nodevin-bundle-%s.tar.gz", manifest.CreatedAt.Format("20060102-150405"))
+	}
+
+	logger.LogInfo("Writing " + path + "...")
+	if err := bundle.Write(path, manifest, files); err != nil {
+		return err
+	}
+
+	displayBundle(manifest)
+	logger.LogInfo(fmt.Sprintf("Wrote %s and %s.sha256", path, path))
+	logger.LogInfo(fmt.Sprintf("On the offline host run `tar -xzf %s %s && ./%s bundle install %s`", filepath.Base(path), manifest.Executable, manifest.Executable, filepath.Base(path)))
+	return nil
+}
+
+// Returns the image tags a deployment needs keyed by service network: the recorded deployment's,
+// otherwise the node image from --image/--version and the add-ons given
+func bundleImageTags(network string, deploymentName string) (map[string]string, error) {
+	images := map[string]string{}
+
+	deployment, exists, err := state.Get(deploymentName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read nodevin state: %w", err)
+	}
+	if exists {
+		for _, service := range deployment.Services {
+			if strings.Contains(service.Image, "@") {
+				return nil, fmt.Errorf("%s runs %s by digest, bundles need tagged images (start it without --locked)", deploymentName, service.Image)
+			}
+			images[service.Network] = service.Image
+		}
+		return images, nil
+	}
+
+	repository, _ := utils.GetNetworkImageRepository(network)
+	if image := viper.GetString("image"); image != "" {
+		repository = image
+	}
+	tag := viper.GetString("version")
+	if tag == "" {
+		tag = "latest"
+	}
+	images[getSoftwareNetwork(network)] = repository + ":" + tag
+
+	addons, err := compose.GetEnabledAddons(network)
+	if err != nil {
+		return nil, err
+	}
+	for sidecarNetwork, image := range getAddonImages(addons) {
+		images[sidecarNetwork] = image
+	}
+
+	return images, nil
+}
+
+// Pulls an image (or uses the local one if the registry is unreachable) and records its ID and digest
+func prepareBundleImage(image string) (bundle.Image, error) {
+	logger.LogInfo("Pulling " + image + "...")
+	pullCtx, cancelPull := context.WithTimeout(context.Background(), dockerPullTimeout)
+	err := docker.PullRemoteImage(pullCtx, image)
+	cancelPull()
+	if err != nil {
+		logger.LogError(fmt.Sprintf("Failed to pull %s, bundling the local image: %v", image, err))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), dockerInspectTimeout)
+	defer cancel()
+
+	imageID, err := docker.GetImageID(ctx, image)
+	if err != nil {
+		return bundle.Image{}, fmt.Errorf("image %s is not available locally: %w", image, err)
+	}
+
+	saved := bundle.Image{Image: image, ID: imageID}
+	if digest, err := docker.GetImageDigest(ctx, image); err == nil {
+		saved.Digest = docker.DigestOf(digest)
+	}
+	return saved, nil
+}
+
+func saveBundleImages(images []string, path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to save images: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), dockerSaveTimeout)
+	defer cancel()
+
+	if err := docker.SaveImages(ctx, images, file); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to save images: %w", err)
+	}
+	return file.Close()
+}
+
+func installBundle(path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("failed to open bundle: %w", err)
+	}
+
+	if checked, err := bundle.VerifyArchive(path); err != nil {
+		return err
+	} else if checked {
+		logger.LogInfo("Bundle matches " + path + ".sha256")
+	} else {
+		logger.LogInfo(fmt.Sprintf("No %s.sha256 found next to the bundle, verifying its contents only", path))
+	}
+
+	// Extract next to the archive: images can be larger than a tmpfs /tmp
+	tmpDir, err := os.MkdirTemp(filepath.Dir(path), ".nodevin-bundle-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	logger.LogInfo("Extracting and verifying " + path + "...")
+	manifest, err := bundle.Extract(path, tmpDir)
+	if err != nil {
+		return err
+	}
+
+	if platform := "linux/" + runtime.GOARCH; manifest.Platform != platform {
+		logger.LogError(fmt.Sprintf("Bundle images are for %s, this host is %s", manifest.Platform, platform))
+	}
+	if manifest.NodevinVersion != version.Version {
+		logger.LogInfo(fmt.Sprintf("Bundle was created by nodevin v%s, this is v%s", manifest.NodevinVersion, version.Version))
+	}
+
+	if err := docker.InitDockerClient(); err != nil {
+		return fmt.Errorf("failed to initialize Docker client: %w", err)
+	}
+
+	logger.LogInfo(fmt.Sprintf("Loading %d images...", len(manifest.ImageTags())))
+	images, err := os.Open(filepath.Join(tmpDir, bundle.ImagesName))
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), dockerLoadTimeout)
+	err = docker.LoadImages(ctx, images)
+	cancel()
+	images.Close()
+	if err != nil {
+		return fmt.Errorf("failed to load images: %w", err)
+	}
+
+	// The tags must now point to exactly the images that were saved
+	for name, deployment := range manifest.Deployments {
+		for _, image := range deployment.Images {
+			if err := checkBundledImage(image.Image, image.ID); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+		}
+	}
+
+	if err := lockBundledImages(manifest); err != nil {
+		return fmt.Errorf("failed to update nodevin.lock: %w", err)
+	}
+
+	displayBundle(manifest)
+
+	var names []string
+	for name := range manifest.Deployments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		logger.LogInfo(fmt.Sprintf("Start %s with `%s start %s`; it will not pull any images", name, utils.GetNodevinExecutable(), startArgs(name, manifest.Deployments[name].Network)))
+	}
+	return nil
+}
+
+// Returns the start arguments for a bundled deployment name, ex: bitcoin --testnet --instance pruned
+func startArgs(deploymentName string, softwareNetwork string) string {
+	_, instance := utils.SplitNetworkInstance(deploymentName)
+
+	args := softwareNetwork
+	switch base, variant := utils.SplitNetworkVariant(softwareNetwork); variant {
+	case "testnet":
+		args = base + " --testnet"
+	case "regtest":
+		args = base + " --network regtest"
+	}
+	if instance != "" {
+		args += " --instance " + instance
+	}
+	return args
+}
+
+// Records the bundle's images in nodevin.lock so start deploys them by tag and never pulls
+func lockBundledImages(manifest *bundle.Manifest) error {
+	path, err := lockfile.Path(viper.GetString("lock-file"))
+	if err != nil {
+		return err
+	}
+
+	lock, _, err := lockfile.Load(path)
+	if err != nil {
+		return err
+	}
+
+	for name, deployment := range manifest.Deployments {
+		locked := &lockfile.LockedDeployment{Services: map[string]lockfile.LockedImage{}, ResolvedAt: manifest.CreatedAt}
+		for network, image := range deployment.Images {
+			locked.Services[network] = lockfile.LockedImage{Image: image.Image, Digest: image.ID, Source: lockfile.SourceBundle}
+		}
+		lock.Deployments[name] = locked
+	}
+
+	if err := lockfile.Save(path, lock); err != nil {
+		return err
+	}
+	logger.LogInfo("Pinned the bundled images in " + path)
+	return nil
+}
+
+// Checks that the bundled images of a deployment are still loaded under their tags, returning
+// the tags keyed by service network for the compose file
+func verifyBundledImages(images map[string]lockfile.LockedImage) (map[string]string, error) {
+	tags := map[string]string{}
+	for network, image := range images {
+		if err := checkBundledImage(image.Image, image.Digest); err != nil {
+			return nil, fmt.Errorf("%w, install the bundle again or run `%s lock update` to use the registry", err, utils.GetNodevinExecutable())
+		}
+		tags[network] = image.Image
+	}
+	return tags, nil
+}
+
+func checkBundledImage(image string, expectedID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dockerInspectTimeout)
+	defer cancel()
+
+	imageID, err := docker.GetImageID(ctx, image)
+	if err != nil {
+		return fmt.Errorf("bundled image %s is not loaded: %w", image, err)
+	}
+	if imageID != expectedID {
+		return fmt.Errorf("bundled image %s now points to %s instead of %s", image, shortDigest(imageID), shortDigest(expectedID))
+	}
+	return nil
+}
+
+func displayBundle(manifest *bundle.Manifest) {
+	var names []string
+	for name := range manifest.Deployments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', tabwriter.Debug)
+	fmt.Fprintln(w, "| DEPLOYMENT\t SERVICE\t IMAGE\t ID")
+
+	for _, name := range names {
+		deployment := manifest.Deployments[name]
+
+		var networks []string
+		for network := range deployment.Images {
+			networks = append(networks, network)
+		}
+		sort.Strings(networks)
+
+		for _, network := range networks {
+			image := deployment.Images[network]
+			fmt.Fprintf(w, "| %s\t %s\t %s\t %s\n", name, network, image.Image, shortDigest(image.ID))
+		}
+	}
+	w.Flush()
+}
+
+func init() {
+	bundleCreateCmd.Flags().StringP("file", "f", "", "Path of the bundle to write (default nodevin-bundle-<time>.tar.gz)")
+	bundleCreateCmd.Flags().String("executable", "", "nodevin executable to include, ex: one built for the offline host's platform (default this one)")
+	bundleCreateCmd.Flags().Bool("no-executable", false, "Do not include a nodevin executable")
+	addImageFlags(bundleCreateCmd.Flags())
+	addAllAddonFlags(bundleCreateCmd.Flags())
+
+	viper.BindPFlag("bundle-file", bundleCreateCmd.Flags().Lookup("file"))
+	viper.BindPFlag("bundle-executable", bundleCreateCmd.Flags().Lookup("executable"))
+	viper.BindPFlag("bundle-no-executable", bundleCreateCmd.Flags().Lookup("no-executable"))
+
+	bundleCmd.AddCommand(bundleCreateCmd)
+	bundleCmd.AddCommand(bundleInstallCmd)
+}
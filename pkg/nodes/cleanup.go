@@ -2,24 +2,26 @@ package nodes
 
 import (
 	"bytes"
+	"fmt"
 	"os"
 	"os/exec"
 	"strings"
 
 	"github.com/fiftysixcrypto/nodevin/internal/logger"
+	"github.com/fiftysixcrypto/nodevin/internal/utils"
 	"github.com/spf13/cobra"
 )
 
 var cleanupCmd = &cobra.Command{
 	Use:   "cleanup",
-	Short: "Cleanup all Docker images starting with 'fiftysix/'",
-	Run: func(cmd *cobra.Command, args []string) {
-		cleanupAllImages()
+	Short: "Cleanup all nodevin Docker images (those from fiftysix/ or --registry)",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return cleanupAllImages()
 	},
 }
 
-func cleanupAllImages() {
-	logger.LogInfo("Removing all Docker images starting with 'fiftysix/'...")
+func cleanupAllImages() error {
+	logger.LogInfo(fmt.Sprintf("Removing all Docker images starting with '%s'...", utils.GetImageRegistry()))
 
 	// Get Docker images
 	listCmd := exec.Command("docker", "images", "--format", "{{.Repository}}:{{.Tag}}")
@@ -28,15 +30,14 @@ func cleanupAllImages() {
 	listCmd.Stderr = os.Stderr
 
 	if err := listCmd.Run(); err != nil {
-		logger.LogError("Failed to list Docker images: " + err.Error())
-		return
+		return fmt.Errorf("failed to list Docker images: %w", err)
 	}
 
-	// Filter images that start with 'fiftysix/'
+	// Filter images from the configured registry base (fiftysix/ by default)
 	images := strings.Split(out.String(), "\n")
 	var filteredImages []string
 	for _, image := range images {
-		if strings.HasPrefix(image, "fiftysix/") {
+		if utils.IsNodevinImage(image) {
 			filteredImages = append(filteredImages, image)
 		}
 	}
@@ -48,12 +49,12 @@ func cleanupAllImages() {
 		removeCmd.Stdout = os.Stdout
 		removeCmd.Stderr = os.Stderr
 		if err := removeCmd.Run(); err != nil {
-			logger.LogError("Failed to remove Docker images: " + err.Error())
-			return
+			return fmt.Errorf("failed to remove Docker images: %w", err)
 		}
 	} else {
-		logger.LogInfo("No Docker images starting with 'fiftysix/' found.")
+		logger.LogInfo(fmt.Sprintf("No Docker images starting with '%s' found.", utils.GetImageRegistry()))
 	}
 
-	logger.LogInfo("Successfully removed all Docker images starting with 'fiftysix/'.")
+	logger.LogInfo(fmt.Sprintf("Successfully removed all Docker images starting with '%s'.", utils.GetImageRegistry()))
+	return nil
 }
@@ -0,0 +1,67 @@
+/*
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 The Nodevin Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package nodes
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Deadlines for each source `info`, `view` and `metrics` collect from. Sources
+// are queried concurrently and anything still missing at its deadline is
+// reported as "timeout" instead of holding up the rest of the output.
+const (
+	dockerListTimeout    = 10 * time.Second
+	dockerInspectTimeout = 5 * time.Second
+	nodeRPCTimeout       = 5 * time.Second
+	networkHeightTimeout = 5 * time.Second
+	directorySizeTimeout = 15 * time.Second
+)
+
+var errCollectTimeout = errors.New("timeout")
+
+type collectResult[T any] struct {
+	value T
+	err   error
+}
+
+// Runs fetch with a deadline. fetch should honour ctx, but if it does not
+// return in time its result is discarded and errCollectTimeout is returned.
+func collectWithTimeout[T any](timeout time.Duration, fetch func(ctx context.Context) (T, error)) (T, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	done := make(chan collectResult[T], 1)
+	go func() {
+		value, err := fetch(ctx)
+		done <- collectResult[T]{value: value, err: err}
+	}()
+
+	select {
+	case result := <-done:
+		if result.err != nil && errors.Is(result.err, context.DeadlineExceeded) {
+			return result.value, errCollectTimeout
+		}
+		return result.value, result.err
+	case <-ctx.Done():
+		var zero T
+		return zero, errCollectTimeout
+	}
+}
@@ -0,0 +1,166 @@
+/*
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 The Nodevin Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package nodes
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/go-units"
+	"github.com/fiftysixcrypto/nodevin/internal/utils"
+	"github.com/fiftysixcrypto/nodevin/pkg/docker"
+)
+
+// A running container found through its nodevin labels
+type managedContainer struct {
+	ContainerInfo
+	Network        string
+	Instance       string
+	Role           string
+	ComposeFile    string
+	DataDir        string
+	PublishedPorts []string
+}
+
+// Lists running nodevin containers with the given roles, whatever their image or container name
+func listNodevinContainers(roles ...string) ([]managedContainer, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dockerListTimeout)
+	defer cancel()
+
+	containers, err := docker.ListNodevinContainers(ctx, false, roles...)
+	if err != nil {
+		return nil, err
+	}
+
+	managed := make([]managedContainer, 0, len(containers))
+	for _, c := range containers {
+		managed = append(managed, managedContainer{
+			ContainerInfo:  toContainerInfo(c),
+			Network:        c.Labels[docker.LabelNetwork],
+			Instance:       c.Labels[docker.LabelInstance],
+			Role:           c.Labels[docker.LabelRole],
+			ComposeFile:    c.Labels[docker.LabelComposeFile],
+			DataDir:        c.Labels[docker.LabelDataDir],
+			PublishedPorts: publishedPorts(c.Ports),
+		})
+	}
+
+	// Keep output in a stable order
+	sort.Slice(managed, func(i, j int) bool {
+		return managed[i].Names < managed[j].Names
+	})
+
+	return managed, nil
+}
+
+// Returns the container's network, qualified with its instance if it has one (ex: bitcoin/pruned)
+func (c managedContainer) Target() string {
+	return utils.JoinNetworkInstance(c.Network, c.Instance)
+}
+
+// Matches a network argument (ex: bitcoin or bitcoin/pruned) against a container's network
+// (ex: bitcoin-testnet) or name. Without an instance every instance of the network matches.
+func matchesNetworkFilter(container managedContainer, networkFilter string) bool {
+	network, instance := utils.SplitNetworkInstance(networkFilter)
+	if instance != "" && container.Instance != instance {
+		return false
+	}
+
+	return container.Network == network ||
+		strings.HasPrefix(container.Network, network+"-") ||
+		strings.Contains(container.Names, network)
+}
+
+// Converts a Docker SDK container into the fields shown by `docker ps --format json`
+func toContainerInfo(c types.Container) ContainerInfo {
+	created := time.Unix(c.Created, 0)
+
+	id := c.ID
+	if len(id) > 12 {
+		id = id[:12]
+	}
+
+	command := c.Command
+	if len(command) > 20 {
+		command = command[:19] + "…"
+	}
+
+	var ports []string
+	for _, port := range c.Ports {
+		if port.PublicPort != 0 {
+			ports = append(ports, fmt.Sprintf("%s:%d->%d/%s", port.IP, port.PublicPort, port.PrivatePort, port.Type))
+		} else {
+			ports = append(ports, fmt.Sprintf("%d/%s", port.PrivatePort, port.Type))
+		}
+	}
+
+	return ContainerInfo{
+		ID:         id,
+		Image:      c.Image,
+		Command:    fmt.Sprintf("%q", command),
+		CreatedAt:  created.Format("2006-01-02 15:04:05 -0700 MST"),
+		RunningFor: units.HumanDuration(time.Since(created)) + " ago",
+		Status:     c.Status,
+		Ports:      strings.Join(ports, ", "),
+		Names:      docker.ContainerName(c),
+	}
+}
+
+// Returns the unique host ports a container publishes (or its exposed ports if none are published)
+func publishedPorts(ports []types.Port) []string {
+	var numbers []int
+	uniquePorts := make(map[uint16]bool)
+
+	for _, port := range ports {
+		number := port.PublicPort
+		if number == 0 {
+			number = port.PrivatePort
+		}
+
+		if !uniquePorts[number] {
+			uniquePorts[number] = true
+			numbers = append(numbers, int(number))
+		}
+	}
+	sort.Ints(numbers)
+
+	formattedPorts := []string{}
+	for _, number := range numbers {
+		formattedPorts = append(formattedPorts, strconv.Itoa(number))
+	}
+
+	return formattedPorts
+}
+
+// Splits an image reference into repository and tag (a registry port is not mistaken for a tag)
+func splitImageTag(image string) (string, string) {
+	image = strings.SplitN(image, "@", 2)[0]
+
+	lastColon := strings.LastIndex(image, ":")
+	if lastColon == -1 || lastColon < strings.LastIndex(image, "/") {
+		return image, ""
+	}
+
+	return image[:lastColon], image[lastColon+1:]
+}
@@ -25,6 +25,7 @@ import (
 	"strings"
 
 	"github.com/fiftysixcrypto/nodevin/internal/logger"
+	"github.com/fiftysixcrypto/nodevin/internal/state"
 	"github.com/fiftysixcrypto/nodevin/internal/utils"
 	"github.com/spf13/cobra"
 )
@@ -33,7 +34,7 @@ var deleteCmd = &cobra.Command{
 	Use:   "delete [network-name-or-all]",
 	Short: "Delete a directory associated with a network or delete all data",
 	Args:  cobra.MaximumNArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		// Get the nodevin data directory
 		nodevinDataDir, err := utils.GetNodevinDataDir()
 
@@ -41,70 +42,156 @@ var deleteCmd = &cobra.Command{
 		if strings.HasPrefix(nodevinDataDir, "~") {
 			homeDir, err := os.UserHomeDir()
 			if err != nil {
-				logger.LogError("Data directory specified had '~', but home directory not found.")
-				return
+				return fmt.Errorf("data directory specified had '~', but home directory not found")
 			}
 			nodevinDataDir = filepath.Join(homeDir, nodevinDataDir[1:])
 		}
 
 		if err != nil {
-			logger.LogError("Failed to find Nodevin data directory: " + err.Error())
-			return
+			return fmt.Errorf("failed to find Nodevin data directory: %w", err)
 		}
 
 		if len(args) == 0 {
-			logger.LogError("No network name provided. To delete network data, specify the name explicitly (for example: bitcoin, litecoin)")
 			logger.LogInfo(fmt.Sprintf("Example usage: `%s delete <network>`", utils.GetNodevinExecutable()))
 			logger.LogInfo(fmt.Sprintf("Example usage: `%s delete all`", utils.GetNodevinExecutable()))
-			return
+			return fmt.Errorf("no network name provided. To delete network data, specify the name explicitly (for example: bitcoin, litecoin)")
 		}
 
-		name := args[0]
+		if args[0] == "all" {
+			return deleteAllDirectories(nodevinDataDir)
+		}
 
-		if name == "all" {
-			deleteAllDirectories(nodevinDataDir)
-		} else {
-			deleteNetworkDirectory(nodevinDataDir, name)
+		name, err := resolveNetworkArg(args[0])
+		if err != nil {
+			return err
 		}
+
+		return deleteNetworkDirectory(nodevinDataDir, name)
 	},
 }
 
-func deleteNetworkDirectory(baseDir, networkName string) {
+func deleteNetworkDirectory(baseDir, networkName string) error {
 	containerName, exists := utils.GetDefaultLocalMappedContainerName(networkName)
 	if !exists {
-		logger.LogError("Unsupported blockchain network: " + networkName)
-		return
+		return fmt.Errorf("unsupported blockchain network: %s", networkName)
+	}
+
+	deploymentName := utils.JoinNetworkInstance(networkName, utils.GetInstance())
+	networkDir := filepath.Join(baseDir, utils.InstanceName(containerName))
+
+	// Deployments started with --data-dir or --local-path keep their data elsewhere
+	if _, service, exists := findDeploymentService(deploymentName); exists && service.DataDir != "" {
+		networkDir = service.DataDir
 	}
 
-	networkDir := filepath.Join(baseDir, containerName)
 	if _, err := os.Stat(networkDir); os.IsNotExist(err) {
-		logger.LogError("Data for network not found: " + networkDir)
-		return
+		return fmt.Errorf("data for network not found: %s", networkDir)
 	}
 
-	// Stop network docker container
-	stopNode(networkName)
+	// Look up separate block/index directories before the record of them is removed
+	extraPaths, err := utils.ReadDataLocations(networkDir)
+	if err != nil {
+		logger.LogError("Failed to read data locations for network " + networkName + ": " + err.Error())
+	}
+
+	// Stop network docker container, data is never removed from under a running node
+	if err := stopNode(networkName); err != nil {
+		return err
+	}
 
 	// Remove the network directory
-	err := os.RemoveAll(networkDir)
+	err = os.RemoveAll(networkDir)
 	if err != nil {
-		logger.LogError("Failed to remove data for network " + networkName + ": " + err.Error())
-		return
+		return fmt.Errorf("failed to remove data for network %s: %w", networkName, err)
 	}
 
-	logger.LogInfo(fmt.Sprintf("Successfully removed %s data directory", networkName))
+	removeExtraDataPaths(networkName, extraPaths)
+
+	if err := state.Remove(deploymentName); err != nil {
+		logger.LogError("Failed to update nodevin state: " + err.Error())
+	}
+
+	logger.LogInfo(fmt.Sprintf("Successfully removed %s data directory", deploymentName))
+	return nil
 }
 
-func deleteAllDirectories(baseDir string) {
+func deleteAllDirectories(baseDir string) error {
+	// Collect separate block/index directories for every network before removing the data dir
+	extraPathsByNetwork := make(map[string]map[string]string)
+	for network, containerName := range utils.NetworkContainerMap() {
+		extraPaths, err := utils.ReadDataLocations(filepath.Join(baseDir, containerName))
+		if err != nil {
+			logger.LogError("Failed to read data locations for network " + network + ": " + err.Error())
+			continue
+		}
+		extraPathsByNetwork[network] = extraPaths
+	}
+
+	// Include data of recorded deployments stored outside the nodevin data dir
+	deployments := listDeployments()
+	var deploymentPaths []deploymentDataPaths
+	for _, deployment := range deployments {
+		for _, service := range deployment.Services {
+			if service.DataDir == "" || isWithinDir(baseDir, service.DataDir) {
+				continue
+			}
+
+			extraPaths, err := utils.ReadDataLocations(service.DataDir)
+			if err != nil {
+				logger.LogError("Failed to read data locations for network " + service.Network + ": " + err.Error())
+				extraPaths = map[string]string{}
+			}
+			extraPaths["data"] = service.DataDir
+			deploymentPaths = append(deploymentPaths, deploymentDataPaths{network: service.Network, paths: extraPaths})
+		}
+	}
+
 	// Stop all docker containers
-	stopAllNodes()
+	if err := stopAllNodes(); err != nil {
+		return err
+	}
 
 	// Remove the entire nodevinDataDir directory
 	err := os.RemoveAll(baseDir)
 	if err != nil {
-		logger.LogError("Failed to remove all directories: " + err.Error())
-		return
+		return fmt.Errorf("failed to remove all directories: %w", err)
+	}
+
+	for network, extraPaths := range extraPathsByNetwork {
+		removeExtraDataPaths(network, extraPaths)
+	}
+	for _, deploymentPath := range deploymentPaths {
+		removeExtraDataPaths(deploymentPath.network, deploymentPath.paths)
+	}
+
+	for _, deployment := range deployments {
+		if err := state.Remove(deployment.Name); err != nil {
+			logger.LogError("Failed to update nodevin state: " + err.Error())
+		}
 	}
 
 	logger.LogInfo("Successfully removed all nodevin blockchain data")
+	return nil
+}
+
+func removeExtraDataPaths(networkName string, extraPaths map[string]string) {
+	for label, path := range extraPaths {
+		if err := os.RemoveAll(path); err != nil {
+			logger.LogError(fmt.Sprintf("Failed to remove %s %s directory %s: %s", networkName, label, path, err.Error()))
+			continue
+		}
+		logger.LogInfo(fmt.Sprintf("Removed %s %s directory: %s", networkName, label, path))
+	}
+}
+
+// Data directories of a recorded deployment service, keyed by label
+type deploymentDataPaths struct {
+	network string
+	paths   map[string]string
+}
+
+// Reports whether path is dir or inside it
+func isWithinDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
 }
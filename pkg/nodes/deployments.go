@@ -0,0 +1,271 @@
+/*
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 The Nodevin Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package nodes
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/go-units"
+	"github.com/fiftysixcrypto/nodevin/internal/config"
+	"github.com/fiftysixcrypto/nodevin/internal/logger"
+	"github.com/fiftysixcrypto/nodevin/internal/state"
+	"github.com/fiftysixcrypto/nodevin/internal/utils"
+	"github.com/fiftysixcrypto/nodevin/pkg/docker"
+	"github.com/fiftysixcrypto/nodevin/pkg/docker/compose"
+	"github.com/spf13/viper"
+)
+
+// Host ports of each named instance are shifted by a multiple of this
+const instancePortStep = 10
+
+// Maps a network argument (ex: bitcoin) to the software network selected by --testnet (ex: bitcoin-testnet)
+func getSoftwareNetwork(network string) string {
+	return utils.GetVariantNetwork(network)
+}
+
+// Splits a network/instance argument (ex: bitcoin/pruned), selecting the instance for the rest of the command
+func resolveNetworkArg(arg string) (string, error) {
+	network, instance := utils.SplitNetworkInstance(arg)
+	if instance != "" {
+		if flagInstance := utils.GetInstance(); flagInstance != "" && flagInstance != instance {
+			return "", fmt.Errorf("instance %q in %s conflicts with --instance %s", instance, arg, flagInstance)
+		}
+		viper.Set("instance", instance)
+	}
+
+	if err := utils.ValidateInstanceName(utils.GetInstance()); err != nil {
+		return "", err
+	}
+
+	// Settings of the network's config.yaml sections apply from here on
+	config.ApplyNetworkSettings(network, getSoftwareNetwork(network), getDeploymentName(network))
+
+	return network, nil
+}
+
+// Returns the state name of the deployment a network argument refers to, ex: bitcoin-testnet/pruned
+func getDeploymentName(network string) string {
+	return utils.JoinNetworkInstance(getSoftwareNetwork(network), utils.GetInstance())
+}
+
+// Picks the host port offset for a named instance: the one it was started with before,
+// otherwise the lowest multiple of instancePortStep not used by another instance of the network
+func allocatePortOffset(network string) (int, error) {
+	instance := utils.GetInstance()
+	if instance == "" {
+		return 0, nil
+	}
+
+	deployments, err := state.List()
+	if err != nil {
+		return 0, err
+	}
+
+	softwareNetwork := getSoftwareNetwork(network)
+	usedOffsets := map[int]bool{}
+	for _, deployment := range deployments {
+		deploymentNetwork, _ := utils.SplitNetworkInstance(deployment.Name)
+		if deploymentNetwork != softwareNetwork {
+			continue
+		}
+		if deployment.Instance == instance {
+			return deployment.PortOffset, nil
+		}
+		usedOffsets[deployment.PortOffset] = true
+	}
+
+	offset := instancePortStep
+	for usedOffsets[offset] {
+		offset += instancePortStep
+	}
+	return offset, nil
+}
+
+// Records a started compose project in the nodevin state file
+func recordDeployment(network string, composeFilePath string) error {
+	composeFile, err := compose.ReadComposeFile(composeFilePath)
+	if err != nil {
+		return err
+	}
+
+	nodevinDataDir, err := utils.GetNodevinDataDir()
+	if err != nil {
+		return err
+	}
+
+	deployment := state.Deployment{
+		Name:        getDeploymentName(network),
+		Network:     network,
+		Instance:    utils.GetInstance(),
+		Variant:     utils.GetNetworkVariant(),
+		Status:      state.StatusRunning,
+		ComposeFile: composeFilePath,
+		DataDir:     nodevinDataDir,
+		PortOffset:  viper.GetInt("port-offset"),
+		Credentials: deploymentCredentials(network),
+	}
+
+	var serviceNames []string
+	for name := range composeFile.Services {
+		serviceNames = append(serviceNames, name)
+	}
+	sort.Strings(serviceNames)
+
+	for _, name := range serviceNames {
+		service := composeFile.Services[name]
+		role := service.Labels[docker.LabelRole]
+		if role != docker.RoleNode && role != docker.RoleSidecar {
+			continue
+		}
+
+		if role == docker.RoleNode {
+			deployment.Name = utils.JoinNetworkInstance(service.Labels[docker.LabelNetwork], deployment.Instance)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), dockerInspectTimeout)
+		digest, err := docker.GetImageDigest(ctx, service.Image)
+		cancel()
+		if err != nil {
+			logger.LogError("Failed to find digest of image " + service.Image + ": " + err.Error())
+		}
+
+		deployment.Services = append(deployment.Services, state.Service{
+			Name:          name,
+			Network:       service.Labels[docker.LabelNetwork],
+			Role:          role,
+			ContainerName: service.ContainerName,
+			Image:         service.Image,
+			ImageDigest:   digest,
+			Ports:         service.Ports,
+			DataDir:       service.Labels[docker.LabelDataDir],
+		})
+	}
+
+	// The node service comes first so lookups by network find it before its sidecars
+	sort.SliceStable(deployment.Services, func(i, j int) bool {
+		return deployment.Services[i].Role == docker.RoleNode && deployment.Services[j].Role != docker.RoleNode
+	})
+
+	if err := applyUpdateSettings(&deployment); err != nil {
+		return err
+	}
+
+	return state.Put(deployment)
+}
+
+// Sets a deployment's update policy and maintenance window from the flags, keeping
+// what it was recorded with before for any flag not given. The declaring stack file is kept too.
+func applyUpdateSettings(deployment *state.Deployment) error {
+	previous, exists, err := state.Get(deployment.Name)
+	if err != nil {
+		return err
+	}
+	if exists {
+		deployment.UpdatePolicy = previous.UpdatePolicy
+		deployment.MaintenanceWindow = previous.MaintenanceWindow
+		deployment.Stack = previous.Stack
+	}
+
+	if policy := viper.GetString("update-policy"); policy != "" {
+		deployment.UpdatePolicy = policy
+	}
+	if window := viper.GetString("maintenance-window"); window != "" {
+		deployment.MaintenanceWindow = window
+	}
+	deployment.UpdatePolicy = deploymentUpdatePolicy(deployment.UpdatePolicy)
+
+	return nil
+}
+
+// Describes how RPC clients authenticate to a network without recording the password itself
+func deploymentCredentials(network string) state.Credentials {
+	if !utils.IsSupportedExtendedInfoNetwork(network) {
+		return state.Credentials{Method: state.CredentialsNone}
+	}
+	if viper.GetBool("cookie-auth") {
+		return state.Credentials{Method: state.CredentialsCookie}
+	}
+	return state.Credentials{Method: state.CredentialsPassword, User: viper.GetString("rpc-user")}
+}
+
+// Marks a recorded deployment as stopped
+func markDeploymentStopped(name string) {
+	if _, err := state.Update(name, func(deployment *state.Deployment) {
+		deployment.Status = state.StatusStopped
+	}); err != nil {
+		logger.LogError("Failed to update nodevin state: " + err.Error())
+	}
+}
+
+// Returns the service recorded for a network or network/instance, logging (but otherwise ignoring) an unreadable state file
+func findDeploymentService(target string) (state.Deployment, state.Service, bool) {
+	network, instance := utils.SplitNetworkInstance(target)
+	deployment, service, exists, err := state.FindService(network, instance)
+	if err != nil {
+		logger.LogError("Failed to read nodevin state: " + err.Error())
+		return state.Deployment{}, state.Service{}, false
+	}
+	return deployment, service, exists
+}
+
+// Returns the recorded deployments, logging (but otherwise ignoring) an unreadable state file
+func listDeployments() []state.Deployment {
+	deployments, err := state.List()
+	if err != nil {
+		logger.LogError("Failed to read nodevin state: " + err.Error())
+		return []state.Deployment{}
+	}
+	return deployments
+}
+
+// Returns how long ago a deployment was created, ex: "3 days ago"
+func deploymentAge(deployment state.Deployment) string {
+	if deployment.CreatedAt.IsZero() {
+		return "-"
+	}
+	return units.HumanDuration(time.Since(deployment.CreatedAt)) + " ago"
+}
+
+// Returns the host port a container port is published on, from compose port
+// mappings such as "8332:8332", "127.0.0.1:18332:8332" or "8332:8332/tcp"
+func publishedHostPort(ports []string, containerPort int) (string, bool) {
+	for _, mapping := range ports {
+		mapping = strings.SplitN(mapping, "/", 2)[0]
+		parts := strings.Split(mapping, ":")
+		if len(parts) < 2 || parts[len(parts)-1] != strconv.Itoa(containerPort) {
+			continue
+		}
+		return parts[len(parts)-2], true
+	}
+	return "", false
+}
+
+// Matches a network argument (ex: bitcoin or bitcoin/pruned) against a recorded deployment
+func matchesDeploymentFilter(deployment state.Deployment, networkFilter string) bool {
+	network, instance := utils.SplitNetworkInstance(networkFilter)
+	if instance != "" && deployment.Instance != instance {
+		return false
+	}
+	return deployment.Network == network
+}
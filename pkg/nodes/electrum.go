@@ -0,0 +1,136 @@
+/*
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 The Nodevin Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package nodes
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os/exec"
+	"regexp"
+	"strconv"
+
+	"github.com/fiftysixcrypto/nodevin/internal/utils"
+	"github.com/fiftysixcrypto/nodevin/pkg/docker/compose"
+)
+
+// Port Electrum clients connect to without TLS
+const electrumPort = 50001
+
+// Heights in the index progress electrs (ex: "indexing 2000 blocks: [1..2000]") and
+// Fulcrum (ex: "Processed height: 2000, 0.2%") log while they build their index
+var electrumLogHeight = regexp.MustCompile(`(?:\[\d+\.\.|Processed height: )(\d+)`)
+
+// ElectrumStatus is how far an Electrum server add-on has indexed the node's chain
+type ElectrumStatus struct {
+	IndexHeight *int     `json:"index_height" yaml:"index_height"`
+	NodeHeight  *int     `json:"node_height" yaml:"node_height"`
+	Progress    *float64 `json:"progress" yaml:"progress"`
+	Serving     bool     `json:"serving" yaml:"serving"`
+}
+
+// Asks an Electrum server for the tip it serves, or reads its index progress from its logs
+// while it is still indexing, and compares it with its node's height
+func getElectrumStatus(ctx context.Context, container managedContainer) (ElectrumStatus, error) {
+	status := ElectrumStatus{}
+
+	if height, err := getElectrumTip(ctx, container.Target()); err == nil {
+		status.IndexHeight = &height
+		status.Serving = true
+	} else if ctx.Err() != nil {
+		return status, ctx.Err()
+	} else if height, found := getElectrumLogHeight(ctx, container.Names); found {
+		status.IndexHeight = &height
+	}
+
+	if nodeNetwork, exists := compose.GetAddonNodeNetwork(container.Network); exists {
+		height, err := getLocalLatestBlock(ctx, utils.JoinNetworkInstance(nodeNetwork, container.Instance))
+		if ctx.Err() != nil {
+			return status, ctx.Err()
+		}
+		if err == nil && height > 0 {
+			status.NodeHeight = &height
+		}
+	}
+
+	if status.IndexHeight != nil && status.NodeHeight != nil {
+		progress := min(float64(*status.IndexHeight)/float64(*status.NodeHeight), 1)
+		status.Progress = &progress
+	}
+	return status, nil
+}
+
+// Returns the height of the tip an Electrum server serves (blockchain.headers.subscribe)
+func getElectrumTip(ctx context.Context, target string) (int, error) {
+	port := strconv.Itoa(electrumPort)
+	if _, service, recorded := findDeploymentService(target); recorded {
+		if hostPort, published := publishedHostPort(service.Ports, electrumPort); published {
+			port = hostPort
+		}
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort("127.0.0.1", port))
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if _, err := fmt.Fprintln(conn, `{"jsonrpc": "2.0", "method": "blockchain.headers.subscribe", "params": [], "id": 0}`); err != nil {
+		return 0, err
+	}
+	line, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err != nil {
+		return 0, err
+	}
+
+	var response struct {
+		Result struct {
+			Height int `json:"height"`
+		} `json:"result"`
+		Error interface{} `json:"error"`
+	}
+	if err := json.Unmarshal(line, &response); err != nil {
+		return 0, err
+	}
+	if response.Error != nil {
+		return 0, fmt.Errorf("electrum server error: %v", response.Error)
+	}
+	return response.Result.Height, nil
+}
+
+// Returns the last index height an Electrum server logged
+func getElectrumLogHeight(ctx context.Context, containerName string) (int, bool) {
+	out, err := exec.CommandContext(ctx, "docker", "logs", "--tail", "200", containerName).CombinedOutput()
+	if err != nil {
+		return 0, false
+	}
+
+	matches := electrumLogHeight.FindAllSubmatch(out, -1)
+	if len(matches) == 0 {
+		return 0, false
+	}
+	height, err := strconv.Atoi(string(matches[len(matches)-1][1]))
+	return height, err == nil
+}
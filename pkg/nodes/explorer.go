@@ -0,0 +1,43 @@
+/*
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 The Nodevin Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package nodes
+
+import (
+	"net"
+	"strconv"
+
+	"github.com/fiftysixcrypto/nodevin/pkg/docker/compose"
+)
+
+// Returns the address a block explorer add-on serves its web interface on, ex: http://127.0.0.1:4080
+// for mempool-web. The host port is the one recorded for the service, as --port-offset shifts it.
+func getExplorerURL(container managedContainer) (string, bool) {
+	webPort, isExplorer := compose.GetExplorerWebPort(container.Network)
+	if !isExplorer {
+		return "", false
+	}
+
+	port := strconv.Itoa(webPort)
+	if _, service, recorded := findDeploymentService(container.Target()); recorded {
+		if hostPort, published := publishedHostPort(service.Ports, webPort); published {
+			port = hostPort
+		}
+	}
+	return "http://" + net.JoinHostPort("127.0.0.1", port), true
+}
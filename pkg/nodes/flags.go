@@ -0,0 +1,200 @@
+/*
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 The Nodevin Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package nodes
+
+import (
+	"strings"
+
+	"github.com/fiftysixcrypto/nodevin/internal/config"
+	"github.com/fiftysixcrypto/nodevin/pkg/docker/compose"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+const (
+	defaultRPCUser = "user"
+	defaultRPCPass = "fiftysix"
+)
+
+// Flags of the rpc group, only shown on networks that answer bitcoin-core style RPCs
+var rpcFlagNames = []string{"rpc-user", "rpc-pass"}
+
+func init() {
+	// Commands that never talk to a node have no --rpc-user, but still run with the defaults
+	viper.SetDefault("rpc-user", defaultRPCUser)
+	viper.SetDefault("rpc-pass", defaultRPCPass)
+}
+
+// Viper binds a setting to a single flag, so flags defined on several commands (ex: --image)
+// are annotated with their setting and bound here, when the command they belong to runs
+func BindCommandFlags(cmd *cobra.Command) {
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		if setting, exists := flag.Annotations[config.SettingAnnotation]; exists {
+			viper.BindPFlag(setting[0], flag)
+		}
+	})
+}
+
+// Annotates flags to be bound to the settings of the same name
+func bindOnRun(flags *pflag.FlagSet, names ...string) {
+	for _, name := range names {
+		flags.SetAnnotation(name, config.SettingAnnotation, []string{name})
+	}
+}
+
+// Docker image, container and compose flags of start
+func addContainerFlags(flags *pflag.FlagSet) {
+	flags.String("command", "", "Initial command to run the node")
+	flags.StringSlice("ports", []string{}, "Ports to bind to the node")
+	flags.StringSlice("volumes", []string{}, "Docker volumes to mount for compose file")
+	flags.StringSlice("volume-definitions", []string{}, "Docker volume definitions for compose file")
+	addImageFlags(flags)
+	flags.String("restart", "no", "Whether or not to restart software on failure (docker restart parameter -- ex: always, no)")
+	flags.String("container-name", "", "Docker container name for compose file")
+	flags.StringSlice("docker-networks", []string{}, "Docker networks to connect to for compose file")
+	flags.String("network-driver", "", "Docker network driver for compose file")
+	flags.StringToString("volume-labels", map[string]string{}, "Docker volume labels for compose file")
+
+	bindOnRun(flags, "command", "ports", "volumes", "volume-definitions", "restart", "container-name", "docker-networks", "network-driver", "volume-labels")
+}
+
+// --image and --version of the node
+func addImageFlags(flags *pflag.FlagSet) {
+	flags.String("image", "", "Docker image to use for the node (image name -- default: <registry>/bitcoin-core etc.)")
+	flags.String("version", "", "Version of Docker image to use for the node (tag -- ex: latest, 27.0)")
+
+	bindOnRun(flags, "image", "version")
+}
+
+// Resource limit and reservation flags of start
+func addResourceFlags(flags *pflag.FlagSet) {
+	flags.String("cpu-limit", "", "Maximum CPU limit of use (amount of CPUs -- ex: 1.5)")
+	flags.String("mem-limit", "", "Maximum memory limit of use (positive integer followed by 'b', 'k', 'm', 'g', to indicate bytes, kilobytes, megabytes, or gigabytes -- ex: 50m)")
+	flags.String("cpu-reservation", "", "Reserve a set amount of CPU for use (amount of CPUs -- ex: 1.5)")
+	flags.String("mem-reservation", "", "Reserve a set amount of memory for use (positive integer followed by 'b', 'k', 'm', 'g', to indicate bytes, kilobytes, megabytes, or gigabytes -- ex: 50m)")
+	flags.String("profile", "", "Size resource limits and daemon caches from host hardware (auto, low, standard, server)")
+
+	bindOnRun(flags, "cpu-limit", "mem-limit", "cpu-reservation", "mem-reservation", "profile")
+}
+
+// Preflight, snapshot, port and update flags of start
+func addDeployFlags(flags *pflag.FlagSet) {
+	flags.Bool("snapshot-sync", false, "Download chain data from a snapshot url -- (default: false)")
+	flags.String("snapshot-sync-command", "", "Init command to be ran to handle snapshot data and place in directory")
+	flags.Bool("force", false, "Start the node even if preflight disk, memory or CPU checks fail")
+	flags.Int("port-offset", 0, "Shift every published host port by this amount (default: automatic for named instances)")
+	flags.Bool("locked", false, "Deploy exactly the image digests recorded in nodevin.lock")
+	flags.String("update-policy", "", "Image update policy: auto, notify, manual or pin (default: the deployment's current policy, or auto)")
+	flags.String("maintenance-window", "", "Local time window auto updates may run in, ex: \"Sun 02:00-04:00\" or \"Mon-Fri 01:00-05:00\"")
+
+	bindOnRun(flags, "snapshot-sync", "snapshot-sync-command", "force", "port-offset", "locked", "update-policy", "maintenance-window")
+}
+
+// --set of start, overriding a field of any generated service
+func addSetFlag(flags *pflag.FlagSet) {
+	flags.StringArray("set", []string{}, "Override a field of a generated service, ex: ord.mem-limit=2g or bitcoin-core.ports=8332:8332,8333:8333 (repeatable)")
+
+	bindOnRun(flags, "set")
+}
+
+// Credentials nodevin authenticates to a node's JSON RPC with
+func addRPCFlags(flags *pflag.FlagSet) {
+	flags.String("rpc-user", defaultRPCUser, "Username passed in via command for JSON RPC")
+	flags.String("rpc-pass", defaultRPCPass, "Password passed in via command for JSON RPC")
+
+	bindOnRun(flags, rpcFlagNames...)
+}
+
+// --cookie-auth of bitcoin-core style nodes
+func addCookieAuthFlag(flags *pflag.FlagSet) {
+	flags.Bool("cookie-auth", false, "Use authentication directly with node cookie file -- (default: false)")
+
+	bindOnRun(flags, "cookie-auth")
+}
+
+// --blocks-dir of nodes that support a separate block files directory
+func addBlocksDirFlag(flags *pflag.FlagSet) {
+	flags.String("blocks-dir", "", "Separate local directory to store raw block files, ex: a large HDD")
+
+	bindOnRun(flags, "blocks-dir")
+}
+
+// --index-dir of ord, standalone or as a sidecar
+func addIndexDirFlag(flags *pflag.FlagSet) {
+	flags.String("index-dir", "", "Separate local directory to store the ord index (index.redb), ex: a fast SSD")
+
+	bindOnRun(flags, "index-dir")
+}
+
+// Settings of whichever Electrum server add-on runs, electrs or fulcrum
+func addElectrumFlags(flags *pflag.FlagSet) {
+	flags.String("electrum-tls-cert", "", "TLS certificate fulcrum serves on port 50002 (file path -- default: a self-signed certificate)")
+	flags.String("electrum-tls-key", "", "Private key of --electrum-tls-cert (file path)")
+	flags.Bool("electrum-wait-sync", false, "Start the Electrum server only once the node has synced")
+
+	bindOnRun(flags, "electrum-tls-cert", "electrum-tls-key", "electrum-wait-sync")
+}
+
+// --addon of start, naming add-ons to run alongside the node
+func addAddonFlag(flags *pflag.FlagSet) {
+	flags.StringSlice("addon", []string{}, "Add-on to run alongside the node, ex: --addon ord (repeatable)")
+
+	bindOnRun(flags, "addon")
+}
+
+// Adds a sidecar's flag and its image flags, ex: --cluster, --cluster-image and --cluster-version
+// for the ipfs-cluster sidecar. They set the sidecar's settings whatever the flags are named.
+func addSidecarFlags(flags *pflag.FlagSet, name string, addon compose.Addon) {
+	sidecar := addon.Service
+	repository := "<registry>/" + addon.Image
+	if strings.Contains(addon.Image, "/") {
+		repository = addon.Image
+	}
+
+	flags.Bool(name, false, addon.Usage)
+	flags.String(name+"-image", "", "Docker image to use for "+sidecar+" (image name -- default: "+repository+")")
+	flags.String(name+"-version", addon.DefaultVersion(), "Version of Docker image to use for "+sidecar+" (tag -- ex: "+addon.DefaultVersion()+")")
+
+	for _, suffix := range []string{"", "-image", "-version"} {
+		flags.SetAnnotation(name+suffix, config.SettingAnnotation, []string{sidecar + suffix})
+	}
+}
+
+// Adds the ipfs-cluster connection flags, ex: --cluster-secret
+func addClusterFlags(flags *pflag.FlagSet, name string) {
+	flags.String(name+"-peername", "", "The peername(s) to attach to (ex: cluster-peer-1)")
+	flags.String(name+"-secret", "", "The cluster secret required for connection (ex: ...)")
+	flags.String(name+"-bootstrap", "", "The bootstrap node address (ex: /ip4/172.20.0.2/tcp/4001/p2p/12D3KooWHUZ36WvuUBmz5aFLJ9PoNKrUJRMSA22i98BkoAaQPRzi)")
+
+	for _, suffix := range []string{"-peername", "-secret", "-bootstrap"} {
+		flags.SetAnnotation(name+suffix, config.SettingAnnotation, []string{"ipfs-cluster" + suffix})
+	}
+}
+
+// Returns a flag's value in the form it is given on the command line, ex: a,b for a string slice
+func flagArgValue(flag *pflag.Flag) string {
+	if slice, ok := flag.Value.(pflag.SliceValue); ok {
+		return strings.Join(slice.GetSlice(), ",")
+	}
+	if flag.Value.Type() == "stringToString" {
+		return strings.TrimSuffix(strings.TrimPrefix(flag.Value.String(), "["), "]")
+	}
+	return flag.Value.String()
+}
@@ -0,0 +1,546 @@
+/*
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 The Nodevin Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package nodes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fiftysixcrypto/nodevin/internal/logger"
+	"github.com/fiftysixcrypto/nodevin/internal/state"
+	"github.com/fiftysixcrypto/nodevin/internal/utils"
+	"github.com/fiftysixcrypto/nodevin/pkg/docker"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	registryCheckTimeout = 30 * time.Second
+	dockerPullTimeout    = 30 * time.Minute
+	composeUpTimeout     = 10 * time.Minute
+	// Nodes flush their chainstate on shutdown, which can take minutes on a large UTXO set
+	gracefulStopTimeout = 10 * time.Minute
+)
+
+// How long an updated deployment has to pass its health gate (--health-timeout)
+var updateHealthTimeout time.Duration
+
+var updateDockerCmd = &cobra.Command{
+	Use:   "docker [network]",
+	Short: "Check deployments for new images and apply them according to their update policy",
+	Long: `Checks every recorded deployment for new images of its node and sidecars.
+
+Without a network, deployments are handled by their update policy: auto deployments
+are updated (inside their maintenance window, if they have one), notify deployments
+only report new images, manual and pin deployments are skipped. Naming a network
+updates its deployments right away unless their policy is pin.
+
+Each update takes a health snapshot, stops the node through RPC, pulls the new
+images and restarts the deployment. If the node is not healthy again before
+--health-timeout, the previous images are restored.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		target := ""
+		if len(args) > 0 {
+			target = args[0]
+		}
+
+		logger.LogInfo("Checking for Docker image updates...")
+		if err := updateDockerImages(target); err != nil {
+			return fmt.Errorf("failed to check/update Docker images: %w", err)
+		}
+		logger.LogInfo("Image updates complete.")
+		return nil
+	},
+}
+
+var updateWatchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Keep checking deployments for new images and apply them according to their update policy",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		interval := viper.GetDuration("update-interval")
+		if interval < time.Minute {
+			return fmt.Errorf("--interval must be at least 1m")
+		}
+		if err := acquireUpdateControllerLock(); err != nil {
+			return err
+		}
+
+		logger.LogInfo(fmt.Sprintf("Checking for Docker image updates every %s", interval))
+		for {
+			if err := updateDockerImages(""); err != nil {
+				logger.LogError("Failed to check/update Docker images: " + err.Error())
+			}
+
+			wait := interval
+			if windowStart, ok := nextMaintenanceWindowStart(listDeployments(), time.Now()); ok && time.Until(windowStart) < wait {
+				wait = time.Until(windowStart)
+			}
+			time.Sleep(wait)
+		}
+	},
+}
+
+var updatePolicyCmd = &cobra.Command{
+	Use:   "policy [network] [auto|notify|manual|pin]",
+	Short: "Show or set the image update policy of deployments",
+	Args:  cobra.MaximumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			displayUpdatePolicies(listDeployments())
+			return nil
+		}
+
+		policy := ""
+		if len(args) > 1 {
+			policy = args[1]
+		}
+
+		window, windowChanged := "", cmd.Flags().Changed("maintenance-window")
+		if windowChanged {
+			window, _ = cmd.Flags().GetString("maintenance-window")
+		}
+
+		return setUpdatePolicy(args[0], policy, window, windowChanged)
+	},
+}
+
+// Checks the deployments matching target (every deployment if empty) for new images
+func updateDockerImages(target string) error {
+	var deployments []state.Deployment
+	for _, deployment := range listDeployments() {
+		if target == "" || deployment.Name == target || matchesDeploymentFilter(deployment, target) {
+			deployments = append(deployments, deployment)
+		}
+	}
+
+	if target == "" {
+		warnUnrecordedContainers()
+	}
+
+	if len(deployments) == 0 {
+		if target != "" {
+			return fmt.Errorf("no recorded deployment matches %s", target)
+		}
+		logger.LogInfo("No nodevin deployments found.")
+		return nil
+	}
+
+	var failed []string
+	for _, deployment := range deployments {
+		if err := updateDeployment(deployment, target != ""); err != nil {
+			logger.LogError(fmt.Sprintf("Failed to update %s: %v", deployment.Name, err))
+			failed = append(failed, deployment.Name)
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("update of %s failed", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// Logs running nodevin containers missing from the state file, whose images are no longer updated
+func warnUnrecordedContainers() {
+	containers, err := listNodevinContainers(docker.RoleNode)
+	if err != nil {
+		logger.LogError("Failed to list Docker containers: " + err.Error())
+		return
+	}
+
+	for _, container := range containers {
+		if _, _, recorded := findDeploymentService(container.Target()); !recorded {
+			logger.LogInfo(fmt.Sprintf("Skipping %s: it is not recorded in the nodevin state, restart it with `%s start %s` to manage its updates", container.Names, utils.GetNodevinExecutable(), container.Target()))
+		}
+	}
+}
+
+// A service whose image tag points to a newer digest upstream
+type imageUpdate struct {
+	Service      state.Service
+	LocalDigest  string
+	RemoteDigest string
+}
+
+// Applies a deployment's update policy. Explicit updates (the deployment was named on
+// the command line) ignore the maintenance window and the notify policy.
+func updateDeployment(deployment state.Deployment, explicit bool) error {
+	policy := deploymentUpdatePolicy(deployment.UpdatePolicy)
+
+	if policy == updatePolicyPin {
+		logger.LogInfo(fmt.Sprintf("Skipping %s: its images are pinned (update policy pin)", deployment.Name))
+		return nil
+	}
+	if policy == updatePolicyManual && !explicit {
+		logger.LogInfo(fmt.Sprintf("Skipping %s: update policy is manual, run `%s update docker %s` to update it", deployment.Name, utils.GetNodevinExecutable(), deployment.Name))
+		return nil
+	}
+	if deployment.Status != state.StatusRunning {
+		logger.LogInfo(fmt.Sprintf("Skipping %s: it is not running", deployment.Name))
+		return nil
+	}
+
+	updates, err := checkDeploymentImages(deployment)
+	if err != nil {
+		return err
+	}
+	if len(updates) == 0 {
+		logger.LogInfo(fmt.Sprintf("%s is running the latest images", deployment.Name))
+		return nil
+	}
+
+	for _, update := range updates {
+		logger.LogInfo(fmt.Sprintf("New image for %s: %s (%s -> %s)", deployment.Name, update.Service.Image, shortDigest(update.LocalDigest), shortDigest(update.RemoteDigest)))
+	}
+
+	if !explicit {
+		if policy == updatePolicyNotify {
+			logger.LogInfo(fmt.Sprintf("Not updating %s: update policy is notify, run `%s update docker %s` to apply", deployment.Name, utils.GetNodevinExecutable(), deployment.Name))
+			return nil
+		}
+
+		inWindow, err := inMaintenanceWindow(deployment.MaintenanceWindow, time.Now())
+		if err != nil {
+			return err
+		}
+		if !inWindow {
+			logger.LogInfo(fmt.Sprintf("Deferring update of %s until its maintenance window (%s)", deployment.Name, deployment.MaintenanceWindow))
+			return nil
+		}
+	}
+
+	return applyDeploymentUpdate(deployment, updates)
+}
+
+// Compares the digest each service runs with the digest its image tag points to upstream.
+// Services that cannot be checked are logged and skipped; it fails only if none could be.
+func checkDeploymentImages(deployment state.Deployment) ([]imageUpdate, error) {
+	var updates []imageUpdate
+	checked := map[string]bool{}
+	failed := 0
+
+	for _, service := range deployment.Services {
+		// Images referenced by digest never change
+		if checked[service.Image] || strings.Contains(service.Image, "@") {
+			continue
+		}
+		checked[service.Image] = true
+
+		ctx, cancel := context.WithTimeout(context.Background(), registryCheckTimeout)
+		remoteDigest, err := docker.GetRemoteImageDigest(ctx, service.Image)
+		cancel()
+		if err != nil {
+			logger.LogError(fmt.Sprintf("Failed to get latest digest for image %s: %v", service.Image, err))
+			failed++
+			continue
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), dockerInspectTimeout)
+		localDigest, err := docker.GetImageDigest(ctx, service.Image)
+		cancel()
+		if err != nil {
+			logger.LogError(fmt.Sprintf("Failed to get local digest for image %s: %v", service.Image, err))
+			failed++
+			continue
+		}
+
+		if docker.DigestOf(localDigest) != remoteDigest {
+			updates = append(updates, imageUpdate{
+				Service:      service,
+				LocalDigest:  docker.DigestOf(localDigest),
+				RemoteDigest: remoteDigest,
+			})
+		}
+	}
+
+	if failed > 0 && failed == len(checked) {
+		return nil, fmt.Errorf("could not check any image for updates")
+	}
+
+	return updates, nil
+}
+
+// Stops, pulls and restarts a deployment, restoring its previous images if it is not healthy afterwards
+func applyDeploymentUpdate(deployment state.Deployment, updates []imageUpdate) error {
+	composeFilePath, err := filepath.Abs(deployment.ComposeFile)
+	if err != nil {
+		return fmt.Errorf("failed to resolve compose file %s: %w", deployment.ComposeFile, err)
+	}
+	if _, err := os.Stat(composeFilePath); err != nil {
+		return fmt.Errorf("compose file of %s not found: %w", deployment.Name, err)
+	}
+
+	logger.LogInfo(fmt.Sprintf("Taking a health snapshot of %s...", deployment.Name))
+	before := takeHealthSnapshot(deployment)
+	logger.LogInfo(fmt.Sprintf("Health before update: %s", before))
+
+	// Image IDs outlive their tags, so the previous images can be tagged back on rollback
+	previousImageIDs := map[string]string{}
+	for _, update := range updates {
+		ctx, cancel := context.WithTimeout(context.Background(), dockerInspectTimeout)
+		imageID, err := docker.GetImageID(ctx, update.Service.Image)
+		cancel()
+		if err != nil {
+			return fmt.Errorf("failed to find current image of %s: %w", update.Service.Image, err)
+		}
+		previousImageIDs[update.Service.Image] = imageID
+	}
+
+	stopDeploymentGracefully(deployment, composeFilePath)
+
+	var images []string
+	for _, update := range updates {
+		images = append(images, update.Service.Image)
+	}
+	logger.LogInfo(fmt.Sprintf("Pulling %s...", strings.Join(images, ", ")))
+	ctx, cancel := context.WithTimeout(context.Background(), dockerPullTimeout)
+	err = docker.PullRemoteImages(ctx, images)
+	cancel()
+	if err != nil {
+		logger.LogError(err.Error())
+		rollbackDeployment(deployment, composeFilePath, previousImageIDs, before)
+		return fmt.Errorf("failed to pull %s, restarted on the previous images", strings.Join(images, ", "))
+	}
+
+	logger.LogInfo(fmt.Sprintf("Starting %s on the new images...", deployment.Name))
+	if err := runDockerCommand(composeUpTimeout, "docker-compose", "-f", composeFilePath, "up", "-d"); err != nil {
+		logger.LogError(fmt.Sprintf("Failed to start %s: %v", deployment.Name, err))
+		rollbackDeployment(deployment, composeFilePath, previousImageIDs, before)
+		return fmt.Errorf("failed to start %s on the new images, rolled back", deployment.Name)
+	}
+
+	logger.LogInfo(fmt.Sprintf("Waiting up to %s for %s to become healthy...", updateHealthTimeout, deployment.Name))
+	if err := waitForHealthy(deployment, before, updateHealthTimeout); err != nil {
+		logger.LogError(fmt.Sprintf("%s failed its post-update health check: %v", deployment.Name, err))
+		rollbackDeployment(deployment, composeFilePath, previousImageIDs, before)
+		return fmt.Errorf("post-update health check failed, rolled back to the previous images")
+	}
+
+	recordUpdatedDigests(deployment.Name, updates)
+	logger.LogInfo(fmt.Sprintf("Successfully updated %s", deployment.Name))
+
+	return nil
+}
+
+// Asks the node to shut down through RPC so it flushes its state, then takes the deployment down
+func stopDeploymentGracefully(deployment state.Deployment, composeFilePath string) {
+	node, hasNode := deploymentNodeService(deployment)
+
+	if hasNode && utils.IsSupportedExtendedInfoNetwork(node.Network) {
+		logger.LogInfo(fmt.Sprintf("Stopping %s through RPC...", node.ContainerName))
+
+		// Otherwise the restart policy brings the node straight back up once it exits
+		ctx, cancel := context.WithTimeout(context.Background(), dockerInspectTimeout)
+		err := docker.DisableContainerRestart(ctx, node.ContainerName)
+		cancel()
+		if err != nil {
+			logger.LogError(fmt.Sprintf("Failed to disable restart policy of %s: %v", node.ContainerName, err))
+		}
+
+		var result string
+		ctx, cancel = context.WithTimeout(context.Background(), nodeRPCTimeout)
+		err = callNodeRPC(ctx, deployment.Name, "stop", &result)
+		cancel()
+		if err != nil {
+			logger.LogError(fmt.Sprintf("RPC stop failed, falling back to docker stop: %v", err))
+		} else if err := waitForContainerExit(node.ContainerName, gracefulStopTimeout); err != nil {
+			logger.LogError(err.Error())
+		}
+	}
+
+	logger.LogInfo(fmt.Sprintf("Shutting down %s...", deployment.Name))
+	timeout := fmt.Sprintf("%d", int(gracefulStopTimeout.Seconds()))
+	if err := runDockerCommand(gracefulStopTimeout+time.Minute, "docker-compose", "-f", composeFilePath, "down", "-t", timeout); err != nil {
+		logger.LogError(fmt.Sprintf("Failed to stop Docker Compose services: %v", err))
+	}
+}
+
+// Tags the previous images back and restarts the deployment on them
+func rollbackDeployment(deployment state.Deployment, composeFilePath string, previousImageIDs map[string]string, before healthSnapshot) {
+	logger.LogInfo(fmt.Sprintf("Rolling %s back to its previous images...", deployment.Name))
+
+	if err := runDockerCommand(gracefulStopTimeout+time.Minute, "docker-compose", "-f", composeFilePath, "down", "-t", fmt.Sprintf("%d", int(gracefulStopTimeout.Seconds()))); err != nil {
+		logger.LogError(fmt.Sprintf("Failed to stop Docker Compose services: %v", err))
+	}
+
+	for image, imageID := range previousImageIDs {
+		ctx, cancel := context.WithTimeout(context.Background(), dockerInspectTimeout)
+		err := docker.TagImage(ctx, imageID, image)
+		cancel()
+		if err != nil {
+			logger.LogError(fmt.Sprintf("Failed to restore %s to %s: %v", image, shortDigest(imageID), err))
+		}
+	}
+
+	if err := runDockerCommand(composeUpTimeout, "docker-compose", "-f", composeFilePath, "up", "-d"); err != nil {
+		logger.LogError(fmt.Sprintf("Failed to restart %s on its previous images: %v", deployment.Name, err))
+		return
+	}
+
+	if err := waitForHealthy(deployment, before, updateHealthTimeout); err != nil {
+		logger.LogError(fmt.Sprintf("%s is still unhealthy after the rollback: %v", deployment.Name, err))
+		return
+	}
+	logger.LogInfo(fmt.Sprintf("Rolled %s back to its previous images", deployment.Name))
+}
+
+// Records the digests a deployment's services run after an update
+func recordUpdatedDigests(deploymentName string, updates []imageUpdate) {
+	newDigests := map[string]string{}
+	for _, update := range updates {
+		ctx, cancel := context.WithTimeout(context.Background(), dockerInspectTimeout)
+		digest, err := docker.GetImageDigest(ctx, update.Service.Image)
+		cancel()
+		if err != nil {
+			logger.LogError(fmt.Sprintf("Failed to find digest of image %s: %v", update.Service.Image, err))
+			continue
+		}
+		newDigests[update.Service.Image] = digest
+	}
+
+	_, err := state.Update(deploymentName, func(deployment *state.Deployment) {
+		deployment.Status = state.StatusRunning
+		for i, service := range deployment.Services {
+			if digest, updated := newDigests[service.Image]; updated {
+				deployment.Services[i].PreviousImageDigest = service.ImageDigest
+				deployment.Services[i].ImageDigest = digest
+			}
+		}
+	})
+	if err != nil {
+		logger.LogError("Failed to update nodevin state: " + err.Error())
+	}
+}
+
+// Sets the update policy and/or maintenance window of the deployments matching target
+func setUpdatePolicy(target string, policy string, window string, windowChanged bool) error {
+	if policy != "" {
+		if err := validateUpdatePolicy(policy); err != nil {
+			return err
+		}
+	}
+	if window != "" {
+		if _, err := parseMaintenanceWindow(window); err != nil {
+			return err
+		}
+	}
+
+	var matching []state.Deployment
+	for _, deployment := range listDeployments() {
+		if deployment.Name == target || matchesDeploymentFilter(deployment, target) {
+			matching = append(matching, deployment)
+		}
+	}
+	if len(matching) == 0 {
+		return fmt.Errorf("no recorded deployment matches %s", target)
+	}
+
+	if policy == "" && !windowChanged {
+		displayUpdatePolicies(matching)
+		return nil
+	}
+
+	for _, deployment := range matching {
+		_, err := state.Update(deployment.Name, func(deployment *state.Deployment) {
+			if policy != "" {
+				deployment.UpdatePolicy = policy
+			}
+			if windowChanged {
+				deployment.MaintenanceWindow = window
+			}
+		})
+		if err != nil {
+			return fmt.Errorf("failed to update nodevin state: %w", err)
+		}
+		logger.LogInfo(fmt.Sprintf("Updated the update policy of %s", deployment.Name))
+	}
+
+	return nil
+}
+
+func displayUpdatePolicies(deployments []state.Deployment) {
+	if len(deployments) == 0 {
+		logger.LogInfo("No nodevin deployments found.")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', tabwriter.Debug)
+	fmt.Fprintln(w, "| NAME\t POLICY\t MAINTENANCE WINDOW")
+
+	for _, deployment := range deployments {
+		fmt.Fprintf(w, "| %s\t %s\t %s\n",
+			deployment.Name,
+			deploymentUpdatePolicy(deployment.UpdatePolicy),
+			valueOrDash(deployment.MaintenanceWindow),
+		)
+	}
+	w.Flush()
+}
+
+// Returns a deployment's node service
+func deploymentNodeService(deployment state.Deployment) (state.Service, bool) {
+	for _, service := range deployment.Services {
+		if service.Role == docker.RoleNode {
+			return service, true
+		}
+	}
+	return state.Service{}, false
+}
+
+// Runs a docker or docker-compose command, including its output in the error
+func runDockerCommand(timeout time.Duration, name string, args ...string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, name, args...).CombinedOutput()
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("%s %s timed out after %s", name, args[0], timeout)
+		}
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// Shortens a digest or image ID for display, ex: sha256:4a7f1c0e9b2d
+func shortDigest(digest string) string {
+	if len(digest) > len("sha256:")+12 {
+		return digest[:len("sha256:")+12]
+	}
+	return valueOrDash(digest)
+}
+
+func init() {
+	addRPCFlags(updateDockerCmd.Flags())
+	addRPCFlags(updateWatchCmd.Flags())
+	updateDockerCmd.Flags().DurationVar(&updateHealthTimeout, "health-timeout", 10*time.Minute, "How long an updated deployment has to become healthy before it is rolled back")
+	updateWatchCmd.Flags().DurationVar(&updateHealthTimeout, "health-timeout", 10*time.Minute, "How long an updated deployment has to become healthy before it is rolled back")
+	updateWatchCmd.Flags().Duration("interval", time.Hour, "How often to check for new images")
+	updatePolicyCmd.Flags().String("maintenance-window", "", "Local time window auto updates may run in, ex: \"Sun 02:00-04:00\" (an empty value removes the window)")
+
+	viper.BindPFlag("update-interval", updateWatchCmd.Flags().Lookup("interval"))
+}
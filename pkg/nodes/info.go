@@ -19,32 +19,42 @@
 package nodes
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
-	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"text/tabwriter"
 
 	"github.com/fiftysixcrypto/nodevin/internal/logger"
+	"github.com/fiftysixcrypto/nodevin/internal/output"
+	"github.com/fiftysixcrypto/nodevin/internal/sizeindex"
+	"github.com/fiftysixcrypto/nodevin/internal/state"
 	"github.com/fiftysixcrypto/nodevin/internal/utils"
+	"github.com/fiftysixcrypto/nodevin/pkg/docker"
+	"github.com/fiftysixcrypto/nodevin/pkg/docker/compose"
+	"github.com/fiftysixcrypto/nodevin/pkg/profile"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
 type ContainerInfo struct {
-	ID         string `json:"ID"`
-	Image      string `json:"Image"`
-	Command    string `json:"Command"`
-	CreatedAt  string `json:"CreatedAt"`
-	RunningFor string `json:"RunningFor"`
-	Status     string `json:"Status"`
-	Ports      string `json:"Ports"`
-	Names      string `json:"Names"`
+	ID         string `json:"ID" yaml:"ID"`
+	Image      string `json:"Image" yaml:"Image"`
+	Command    string `json:"Command" yaml:"Command"`
+	CreatedAt  string `json:"CreatedAt" yaml:"CreatedAt"`
+	RunningFor string `json:"RunningFor" yaml:"RunningFor"`
+	Status     string `json:"Status" yaml:"Status"`
+	Ports      string `json:"Ports" yaml:"Ports"`
+	Names      string `json:"Names" yaml:"Names"`
 }
 
 type RPCResponse struct {
@@ -58,41 +68,301 @@ type RPCError struct {
 	Message string `json:"message"`
 }
 
+// InfoReport is the output of `nodevin info` (see docs/cli-commands.md for the schema)
+type InfoReport struct {
+	Nodes       []NodeStatus       `json:"nodes" yaml:"nodes"`
+	Deployments []state.Deployment `json:"deployments" yaml:"deployments"`
+	Data        []DataUsage        `json:"data" yaml:"data"`
+	Profiles    []NetworkProfile   `json:"profiles" yaml:"profiles"`
+}
+
+// NodeStatus describes a running nodevin container
+type NodeStatus struct {
+	Name      string           `json:"name" yaml:"name"`
+	Network   string           `json:"network" yaml:"network"`
+	Instance  string           `json:"instance,omitempty" yaml:"instance,omitempty"`
+	Role      string           `json:"role" yaml:"role"`
+	Image     string           `json:"image" yaml:"image"`
+	Version   string           `json:"version" yaml:"version"`
+	Command   string           `json:"command" yaml:"command"`
+	Status    string           `json:"status" yaml:"status"`
+	Ports     []string         `json:"ports" yaml:"ports"`
+	Peers     *int             `json:"peers" yaml:"peers"`
+	Sync      *SyncStatus      `json:"sync" yaml:"sync"`
+	Lightning *LightningStatus `json:"lightning,omitempty" yaml:"lightning,omitempty"`
+	Electrum  *ElectrumStatus  `json:"electrum,omitempty" yaml:"electrum,omitempty"`
+	URL       string           `json:"url,omitempty" yaml:"url,omitempty"`
+	Timeouts  []string         `json:"timeouts,omitempty" yaml:"timeouts,omitempty"`
+	Container ContainerInfo    `json:"container" yaml:"container"`
+}
+
+// SyncStatus compares a node's chain height against the public network
+type SyncStatus struct {
+	LocalHeight   *int `json:"local_height" yaml:"local_height"`
+	NetworkHeight *int `json:"network_height" yaml:"network_height"`
+}
+
+// DataUsage is the size of one directory holding a network's data
+type DataUsage struct {
+	Network   string `json:"network" yaml:"network"`
+	Location  string `json:"location" yaml:"location"`
+	SizeBytes int64  `json:"size_bytes" yaml:"size_bytes"`
+	Directory string `json:"directory" yaml:"directory"`
+	Timeout   bool   `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+}
+
+// NetworkProfile is the resource profile recorded for a network
+type NetworkProfile struct {
+	Network          string `json:"network" yaml:"network"`
+	profile.Settings `yaml:",inline"`
+}
+
 var infoCmd = &cobra.Command{
 	Use:   "info [network]",
 	Short: "Get information about running blockchain nodes",
 	Args:  cobra.MaximumNArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		var networkFilter string
 		if len(args) > 0 {
 			networkFilter = args[0]
 		}
-		displayInfo(networkFilter)
+
+		report, err := collectInfo(networkFilter)
+		if err != nil {
+			return err
+		}
+
+		if output.IsStructured() {
+			return output.Print(report)
+		}
+
+		displayInfo(report)
+		return nil
 	},
 }
 
-// Prepare the docker ps command
-func displayInfo(networkFilter string) {
-	args := []string{"ps", "--format", "{{json .}}"}
+// Gather running node, data directory and profile information. Containers and
+// data directories are collected concurrently, each source with its own deadline.
+func collectInfo(networkFilter string) (InfoReport, error) {
+	report := InfoReport{
+		Nodes:       []NodeStatus{},
+		Deployments: []state.Deployment{},
+		Profiles:    collectResourceProfiles(networkFilter),
+	}
 
-	// Execute the docker ps command
-	cmd := exec.Command("docker", args...)
-	output, err := cmd.CombinedOutput()
+	for _, deployment := range listDeployments() {
+		if networkFilter == "" || deployment.Name == networkFilter || matchesDeploymentFilter(deployment, networkFilter) {
+			report.Deployments = append(report.Deployments, deployment)
+		}
+	}
+
+	dataDone := make(chan []DataUsage, 1)
+	go func() {
+		dataDone <- collectDataUsage(networkFilter)
+	}()
+
+	containers, err := listNodevinContainers(docker.RoleNode, docker.RoleSidecar)
 	if err != nil {
-		logger.LogError("Failed to fetch Docker container information: " + err.Error())
-		return
+		report.Data = <-dataDone
+		return report, fmt.Errorf("failed to fetch Docker container information: %w", err)
+	}
+
+	var selected []managedContainer
+	for _, container := range containers {
+		if networkFilter != "" && !matchesNetworkFilter(container, networkFilter) {
+			continue
+		}
+
+		selected = append(selected, container)
+	}
+
+	report.Nodes = make([]NodeStatus, len(selected))
+	var wg sync.WaitGroup
+	for i, container := range selected {
+		wg.Add(1)
+		go func(i int, container managedContainer) {
+			defer wg.Done()
+			report.Nodes[i] = collectNodeStatus(container)
+		}(i, container)
+	}
+	wg.Wait()
+
+	report.Data = <-dataDone
+	return report, nil
+}
+
+func collectNodeStatus(container managedContainer) NodeStatus {
+	node := NodeStatus{
+		Name:      container.Names,
+		Network:   container.Network,
+		Instance:  container.Instance,
+		Role:      container.Role,
+		Image:     utils.TrimImageRegistry(container.Image),
+		Version:   "unknown",
+		Command:   container.Command,
+		Status:    container.Status,
+		Ports:     container.PublishedPorts,
+		Container: container.ContainerInfo,
+	}
+
+	if repository, tag := splitImageTag(node.Image); tag != "" {
+		node.Image = repository
+		node.Version = tag
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	collect := func(fetch func()) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fetch()
+		}()
+	}
+	markTimeout := func(source string) {
+		mu.Lock()
+		node.Timeouts = append(node.Timeouts, source)
+		mu.Unlock()
+	}
+
+	if node.Version == "latest" {
+		// Fetch the actual version from the container's environment
+		collect(func() {
+			version, err := collectWithTimeout(dockerInspectTimeout, func(ctx context.Context) (string, error) {
+				return getNodeVersionFromEnv(ctx, container.ID)
+			})
+			if errors.Is(err, errCollectTimeout) {
+				markTimeout("version")
+				version = "timeout"
+			} else if err != nil {
+				logger.LogError("Failed to inspect container: " + err.Error())
+				version = "unknown"
+			}
+
+			mu.Lock()
+			node.Version = version
+			mu.Unlock()
+		})
+	}
+
+	if utils.IsSupportedExtendedInfoNetwork(container.Network) {
+		node.Sync = &SyncStatus{}
+
+		collect(func() {
+			peers, err := collectWithTimeout(nodeRPCTimeout, func(ctx context.Context) (int, error) {
+				return getPeers(ctx, container.Target())
+			})
+			if errors.Is(err, errCollectTimeout) {
+				markTimeout("peers")
+				return
+			} else if err != nil {
+				// Unknown (ex: the node is still starting), not zero
+				return
+			}
+
+			mu.Lock()
+			node.Peers = &peers
+			mu.Unlock()
+		})
+
+		collect(func() {
+			height, err := collectWithTimeout(nodeRPCTimeout, func(ctx context.Context) (int, error) {
+				return getLocalLatestBlock(ctx, container.Target())
+			})
+			if errors.Is(err, errCollectTimeout) {
+				markTimeout("local_height")
+				return
+			} else if err != nil {
+				return
+			}
+
+			mu.Lock()
+			node.Sync.LocalHeight = &height
+			mu.Unlock()
+		})
+
+		collect(func() {
+			height, err := collectWithTimeout(networkHeightTimeout, func(ctx context.Context) (int, error) {
+				return getGlobalLatestBlock(ctx, container.Network)
+			})
+			if errors.Is(err, errCollectTimeout) {
+				markTimeout("network_height")
+				return
+			} else if err != nil {
+				if !errors.Is(err, errNoNetworkHeightSource) {
+					logger.LogError("Failed to fetch global latest block: " + err.Error())
+				}
+				return
+			}
+
+			mu.Lock()
+			node.Sync.NetworkHeight = &height
+			mu.Unlock()
+		})
+	}
+
+	if _, isLightning := compose.LightningCLI(container.Network); isLightning {
+		collect(func() {
+			type lightningResult struct {
+				status LightningStatus
+				peers  int
+			}
+			result, err := collectWithTimeout(nodeRPCTimeout, func(ctx context.Context) (lightningResult, error) {
+				status, peers, err := getLightningStatus(ctx, container.Names, container.Network)
+				return lightningResult{status, peers}, err
+			})
+			if errors.Is(err, errCollectTimeout) {
+				markTimeout("lightning")
+				return
+			} else if err != nil {
+				logger.LogError(err.Error())
+				return
+			}
+
+			mu.Lock()
+			node.Lightning = &result.status
+			node.Peers = &result.peers
+			mu.Unlock()
+		})
+	}
+
+	if compose.IsElectrumServer(container.Network) {
+		collect(func() {
+			status, err := collectWithTimeout(nodeRPCTimeout, func(ctx context.Context) (ElectrumStatus, error) {
+				return getElectrumStatus(ctx, container)
+			})
+			if errors.Is(err, errCollectTimeout) {
+				markTimeout("electrum")
+				return
+			}
+
+			mu.Lock()
+			node.Electrum = &status
+			mu.Unlock()
+		})
 	}
 
-	fmt.Println("\n-- Running Nodes:\n")
+	if url, isExplorer := getExplorerURL(container); isExplorer {
+		node.URL = url
+	}
+
+	wg.Wait()
+	sort.Strings(node.Timeouts)
+
+	return node
+}
 
-	// Parse the output
-	containers := strings.Split(string(output), "\n")
-	if len(containers) < 2 {
-		fmt.Println("No running blockchain nodes found.\n")
-		displayNodeDirectoryInfo(networkFilter)
-		fmt.Println("\n-- Helpful Commands:\n")
-		fmt.Printf("%s start <network>\n", utils.GetNodevinExecutable())
-		fmt.Printf("%s start <network> --testnet\n", utils.GetNodevinExecutable())
+func displayInfo(report InfoReport) {
+	fmt.Print("\n-- Running Nodes:\n\n")
+
+	if len(report.Nodes) == 0 {
+		fmt.Print("No running blockchain nodes found.\n\n")
+		displayDeployments(report.Deployments)
+		displayNodeDirectoryInfo(report.Data)
+		displayResourceProfiles(report.Profiles)
+		fmt.Print("\n-- Helpful Commands:\n\n")
+		fmt.Printf("%s <network> start\n", utils.GetNodevinExecutable())
+		fmt.Printf("%s <network> start --testnet\n", utils.GetNodevinExecutable())
 		fmt.Printf("%s stop <network>\n", utils.GetNodevinExecutable())
 		return
 	}
@@ -101,327 +371,553 @@ func displayInfo(networkFilter string) {
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', tabwriter.Debug)
 	fmt.Fprintln(w, "| BLOCKCHAIN\t VERSION\t COMMAND\t STATUS\t PORTS\t PEERS\t LATEST BLOCK")
 
-	for _, containerJSON := range containers {
-		if strings.TrimSpace(containerJSON) == "" {
-			continue
+	for _, node := range report.Nodes {
+		peers := "-"
+		if node.Peers != nil {
+			peers = fmt.Sprintf("%d", *node.Peers)
+		} else if node.timedOut("peers") {
+			peers = "timeout"
 		}
-		var container ContainerInfo
-		if err := json.Unmarshal([]byte(containerJSON), &container); err != nil {
-			logger.LogError("Failed to parse container JSON: " + err.Error())
-			continue
+
+		latestBlock := "-/-"
+		if node.Sync != nil {
+			latestBlock = fmt.Sprintf("%s/%s", node.heightDescription(node.Sync.LocalHeight, "local_height"), node.heightDescription(node.Sync.NetworkHeight, "network_height"))
 		}
 
-		imageName := container.Image
-		if strings.HasPrefix(container.Image, "fiftysix/") {
-			imageName = strings.TrimPrefix(container.Image, "fiftysix/")
-		} else {
-			// Skip if the image does not begin with fiftysix
-			continue
+		fmt.Fprintf(w, "| %s\t %s\t %s\t %s\t %s\t %s\t %s\n",
+			node.Name,
+			node.Version,
+			node.Command,
+			node.Status,
+			strings.Join(node.Ports, ", "),
+			peers,
+			latestBlock,
+		)
+	}
+	w.Flush()
+
+	fmt.Println("")
+
+	displayLightning(report.Nodes)
+
+	displayElectrum(report.Nodes)
+
+	displayExplorers(report.Nodes)
+
+	displayDeployments(report.Deployments)
+
+	displayNodeDirectoryInfo(report.Data)
+
+	displayResourceProfiles(report.Profiles)
+
+	fmt.Print("\n-- Helpful Commands:\n\n")
+
+	fmt.Printf("%s stop <network>\n", utils.GetNodevinExecutable())
+	fmt.Printf("%s shell <network>\n", utils.GetNodevinExecutable())
+	fmt.Printf("%s logs <network> --tail 20\n", utils.GetNodevinExecutable())
+}
+
+func displayLightning(nodes []NodeStatus) {
+	var lightning []NodeStatus
+	for _, node := range nodes {
+		if node.Lightning != nil {
+			lightning = append(lightning, node)
 		}
+	}
+	if len(lightning) == 0 {
+		return
+	}
 
-		if networkFilter != "" && !strings.Contains(container.Names, networkFilter) {
-			continue
+	fmt.Print("-- Lightning:\n\n")
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', tabwriter.Debug)
+	fmt.Fprintln(w, "| NODE\t CHANNELS\t PENDING\t PEERS\t SYNCED TO CHAIN")
+	for _, node := range lightning {
+		fmt.Fprintf(w, "| %s\t %d\t %d\t %d\t %t\n",
+			node.Name,
+			node.Lightning.Channels,
+			node.Lightning.PendingChannels,
+			*node.Peers,
+			node.Lightning.SyncedToChain,
+		)
+	}
+	w.Flush()
+
+	fmt.Println("")
+}
+
+func displayElectrum(nodes []NodeStatus) {
+	var electrum []NodeStatus
+	for _, node := range nodes {
+		if node.Electrum != nil {
+			electrum = append(electrum, node)
 		}
+	}
+	if len(electrum) == 0 {
+		return
+	}
 
-		version := "unknown"
-		if parts := strings.Split(imageName, ":"); len(parts) > 1 {
-			imageName = parts[0]
-			version = parts[1]
-			if version == "latest" {
-				// Fetch the actual version from the container's environment
-				version = getNodeVersionFromEnv(container.ID)
-			}
+	fmt.Print("-- Electrum Servers:\n\n")
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', tabwriter.Debug)
+	fmt.Fprintln(w, "| SERVER\t INDEXED\t PROGRESS\t SERVING")
+	for _, node := range electrum {
+		indexed := "-"
+		if node.Electrum.IndexHeight != nil {
+			indexed = strconv.Itoa(*node.Electrum.IndexHeight)
+		}
+		if node.Electrum.NodeHeight != nil {
+			indexed = fmt.Sprintf("%s/%d", indexed, *node.Electrum.NodeHeight)
 		}
 
-		formattedPorts := formatPorts(container.Ports)
+		progress := "-"
+		if node.Electrum.Progress != nil {
+			progress = fmt.Sprintf("%.1f%%", *node.Electrum.Progress*100)
+		}
 
-		if !utils.IsSupportedExtendedInfoSoftware(imageName) {
-			fmt.Fprintf(w, "| %s\t %s\t %s\t %s\t %s\t %s\t %s/%s\n",
-				container.Names,
-				version,
-				container.Command,
-				container.Status,
-				formattedPorts,
-				"-",
-				"-",
-				"-",
-			)
-			continue
+		fmt.Fprintf(w, "| %s\t %s\t %s\t %t\n", node.Name, indexed, progress, node.Electrum.Serving)
+	}
+	w.Flush()
+
+	fmt.Println("")
+}
+
+func displayExplorers(nodes []NodeStatus) {
+	var explorers []NodeStatus
+	for _, node := range nodes {
+		if node.URL != "" {
+			explorers = append(explorers, node)
 		}
+	}
+	if len(explorers) == 0 {
+		return
+	}
 
-		localLatestBlock, globalLatestBlock := getLatestBlocks(container.Names)
-		peers := getPeers(container.Names)
+	fmt.Print("-- Block Explorers:\n\n")
 
-		fmt.Fprintf(w, "| %s\t %s\t %s\t %s\t %s\t %d\t %d/%d\n",
-			container.Names,
-			version,
-			container.Command,
-			container.Status,
-			formattedPorts,
-			peers,
-			localLatestBlock,
-			globalLatestBlock,
-		)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', tabwriter.Debug)
+	fmt.Fprintln(w, "| EXPLORER\t URL\t STATUS")
+	for _, node := range explorers {
+		fmt.Fprintf(w, "| %s\t %s\t %s\n", node.Name, node.URL, node.Status)
 	}
 	w.Flush()
 
 	fmt.Println("")
+}
+
+func displayDeployments(deployments []state.Deployment) {
+	if len(deployments) == 0 {
+		return
+	}
 
-	displayNodeDirectoryInfo(networkFilter)
+	fmt.Print("-- Deployments:\n\n")
 
-	fmt.Println("\n-- Helpful Commands:\n")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', tabwriter.Debug)
+	fmt.Fprintln(w, "| NAME\t VARIANT\t STATUS\t UPDATES\t CREATED\t COMPOSE FILE")
+
+	for _, deployment := range deployments {
+		fmt.Fprintf(w, "| %s\t %s\t %s\t %s\t %s\t %s\n",
+			deployment.Name,
+			deployment.Variant,
+			deployment.Status,
+			deploymentUpdatePolicy(deployment.UpdatePolicy),
+			deploymentAge(deployment),
+			deployment.ComposeFile,
+		)
+	}
+	w.Flush()
 
-	fmt.Printf("%s stop <network>\n", utils.GetNodevinExecutable())
-	fmt.Printf("%s shell <network>\n", utils.GetNodevinExecutable())
-	fmt.Printf("%s logs <network> --tail 20\n", utils.GetNodevinExecutable())
+	fmt.Println("")
+}
+
+func (node NodeStatus) timedOut(source string) bool {
+	for _, timeout := range node.Timeouts {
+		if timeout == source {
+			return true
+		}
+	}
+	return false
+}
+
+func (node NodeStatus) heightDescription(height *int, source string) string {
+	if height != nil {
+		return fmt.Sprintf("%d", *height)
+	} else if node.timedOut(source) {
+		return "timeout"
+	}
+	return "-"
 }
 
-func getLatestBlocks(containerName string) (int, int) {
-	localLatestBlock := getLocalLatestBlock(containerName)
-	globalLatestBlock := getGlobalLatestBlock(containerName)
+func getLocalLatestBlock(ctx context.Context, network string) (int, error) {
+	var blockCount int
+	if err := callNodeRPC(ctx, network, "getblockcount", &blockCount); err != nil {
+		return 0, err
+	}
 
-	return localLatestBlock, globalLatestBlock
+	return blockCount, nil
 }
 
-func getLocalLatestBlock(containerName string) int {
-	url := getLocalEndpointByContainerName(containerName)
-	method := "getblockcount"
-	params := "[]"
+// Calls a JSON RPC method without params on the node's local endpoint and decodes its result
+func callNodeRPC(ctx context.Context, network string, method string, result interface{}) error {
+	url := getLocalEndpointByNetwork(network)
 	user := viper.GetString("rpc-user")
 	pass := viper.GetString("rpc-pass")
 
-	response, err := makeRequest("", url, method, params, "", user, pass)
+	// Use the RPC user the node was started with unless one was passed explicitly
+	if deployment, _, exists := findDeploymentService(network); exists && !viper.IsSet("rpc-user") && deployment.Credentials.User != "" {
+		user = deployment.Credentials.User
+	}
+
+	response, err := makeRequestWithContext(ctx, "", url, method, "[]", "", user, pass)
 	if err != nil {
-		//logger.LogError("Failed to get local latest block: " + err.Error())
-		return 0
+		return err
 	}
 
-	var rpcResponse RPCResponse
+	var rpcResponse struct {
+		Result json.RawMessage `json:"result"`
+		Error  *RPCError       `json:"error"`
+	}
 	if err := json.Unmarshal(response, &rpcResponse); err != nil {
-		//logger.LogError("Failed to parse RPC response: " + err.Error())
-		return 0
+		return fmt.Errorf("failed to parse RPC response: %w", err)
 	}
 
 	if rpcResponse.Error != nil {
-		//logger.LogError("RPC Error: " + rpcResponse.Error.Message)
-		return 0
+		return fmt.Errorf("RPC error: %s", rpcResponse.Error.Message)
 	}
 
-	blockCount, ok := rpcResponse.Result.(float64)
-	if !ok {
-		//logger.LogError("Failed to parse block count")
-		return 0
+	if err := json.Unmarshal(rpcResponse.Result, result); err != nil {
+		return fmt.Errorf("failed to parse %s result: %w", method, err)
 	}
 
-	return int(blockCount)
+	return nil
 }
 
-func getGlobalLatestBlock(containerName string) int {
-	globalFetchLink := getGlobalEndpointByContainerName(containerName)
+// Regtest and test networks without a public explorer have no network height
+var errNoNetworkHeightSource = errors.New("no public source for the network height")
+
+func getGlobalLatestBlock(ctx context.Context, network string) (int, error) {
+	globalFetchLink := getGlobalEndpointByNetwork(network)
+	if globalFetchLink == "" {
+		return 0, errNoNetworkHeightSource
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, globalFetchLink, nil)
+	if err != nil {
+		return 0, err
+	}
 
-	resp, err := http.Get(globalFetchLink)
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		logger.LogError("Failed to fetch global latest block: " + err.Error())
-		return 0
+		return 0, err
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		logger.LogError("Failed to read response body: " + err.Error())
-		return 0
+		return 0, fmt.Errorf("failed to read response body: %w", err)
 	}
 
 	var result map[string]interface{}
 	if err := json.Unmarshal(body, &result); err != nil {
-		logger.LogError("Failed to parse response body: " + err.Error())
-		return 0
+		return 0, fmt.Errorf("failed to parse response body: %w", err)
 	}
 
 	blockCount, ok := result["height"].(float64)
 	if !ok {
-		logger.LogError("Failed to parse global block count")
-		return 0
+		return 0, fmt.Errorf("failed to parse global block count")
 	}
 
-	return int(blockCount)
+	return int(blockCount), nil
 }
 
-func getGlobalEndpointByContainerName(containerName string) string {
+func getGlobalEndpointByNetwork(network string) string {
 	globalFetchLink := ""
 
-	if containerName == "bitcoin-core" {
+	if network == "bitcoin" {
 		globalFetchLink = "https://blockchain.info/latestblock"
-	} else if containerName == "bitcoin-core-testnet" {
+	} else if network == "bitcoin-testnet" {
 		globalFetchLink = "https://api.blockcypher.com/v1/btc/test3"
-	} else if containerName == "litecoin-core" {
+	} else if network == "litecoin" {
 		globalFetchLink = "https://api.blockcypher.com/v1/ltc/main"
-	} else if containerName == "litecoin-core-testnet" {
+	} else if network == "litecoin-testnet" {
 		globalFetchLink = ""
-	} else if containerName == "dogecoin-core" {
+	} else if network == "dogecoin" {
 		globalFetchLink = "https://api.blockcypher.com/v1/doge/main"
-	} else if containerName == "dogecoin-core-testnet" {
+	} else if network == "dogecoin-testnet" {
 		globalFetchLink = ""
 	}
 
 	return globalFetchLink
 }
 
-func getLocalEndpointByContainerName(containerName string) string {
+// Returns the local RPC endpoint of a network or network/instance (ex: bitcoin/pruned)
+func getLocalEndpointByNetwork(target string) string {
 	url := "http://127.0.0.1"
+	network, _ := utils.SplitNetworkInstance(target)
+
+	if port, exists := utils.NetworkDefaultRPCPorts()[network]; exists {
+		url = fmt.Sprintf("http://127.0.0.1:%d", port)
 
-	if containerName == "bitcoin-core" {
-		url = "http://127.0.0.1:8332"
-	} else if containerName == "bitcoin-core-testnet" {
-		url = "http://127.0.0.1:18332"
-	} else if containerName == "litecoin-core" {
-		url = "http://127.0.0.1:9332"
-	} else if containerName == "litecoin-core-testnet" {
-		url = "http://127.0.0.1:19332"
-	} else if containerName == "dogecoin-core" {
-		url = "http://127.0.0.1:22555"
-	} else if containerName == "dogecoin-core-testnet" {
-		url = "http://127.0.0.1:44555"
+		// Instances and deployments started with --ports publish the RPC port on another host port
+		if _, service, recorded := findDeploymentService(target); recorded {
+			if hostPort, published := publishedHostPort(service.Ports, port); published {
+				url = fmt.Sprintf("http://127.0.0.1:%s", hostPort)
+			}
+		}
 	}
 
 	return url
 }
 
-func getPeers(containerName string) int {
-	url := getLocalEndpointByContainerName(containerName)
-	method := "getconnectioncount"
-	params := "[]"
-	user := viper.GetString("rpc-user")
-	pass := viper.GetString("rpc-pass")
+func getPeers(ctx context.Context, network string) (int, error) {
+	var peerCount int
+	if err := callNodeRPC(ctx, network, "getconnectioncount", &peerCount); err != nil {
+		return 0, err
+	}
 
-	response, err := makeRequest("bitcoin", url, method, params, "", user, pass)
-	if err != nil {
-		//logger.LogError("Failed to get peer count: " + err.Error())
-		return 0
+	return peerCount, nil
+}
+
+func collectDataUsage(networkFilter string) []DataUsage {
+	// Fetch the list of supported networks
+	networks := utils.GetAllSupportedNetworks()
+	if networks == "" {
+		return []DataUsage{}
 	}
 
-	var rpcResponse RPCResponse
-	if err := json.Unmarshal(response, &rpcResponse); err != nil {
-		//logger.LogError("Failed to parse RPC response: " + err.Error())
-		return 0
+	recordedServices := map[string]state.Service{}
+	var instanceDeployments []state.Deployment
+	for _, deployment := range listDeployments() {
+		if deployment.Instance != "" {
+			instanceDeployments = append(instanceDeployments, deployment)
+			continue
+		}
+		for _, service := range deployment.Services {
+			if _, exists := recordedServices[service.Network]; !exists {
+				recordedServices[service.Network] = service
+			}
+		}
 	}
 
-	if rpcResponse.Error != nil {
-		//logger.LogError("RPC Error: " + rpcResponse.Error.Message)
-		return 0
+	filterNetwork, filterInstance := utils.SplitNetworkInstance(networkFilter)
+
+	var locations []DataUsage
+	for _, network := range strings.Split(networks, ", ") {
+		if networkFilter != "" && (filterNetwork != network || filterInstance != "") {
+			continue
+		}
+
+		networkLocations, err := utils.GetNetworkDataLocations(network)
+
+		// Deployments started with --data-dir or --local-path keep their data elsewhere
+		if service, exists := recordedServices[network]; exists && service.DataDir != "" {
+			networkLocations, err = utils.GetDataLocationsAt(service.DataDir)
+		}
+		if err != nil {
+			logger.LogError("Failed to find data locations for " + network + ": " + err.Error())
+		}
+
+		for _, location := range networkLocations {
+			locations = append(locations, DataUsage{Network: network, Location: location.Label, Directory: location.Path})
+		}
 	}
 
-	peerCount, ok := rpcResponse.Result.(float64)
-	if !ok {
-		//logger.LogError("Failed to parse peer count")
-		return 0
+	// Named instances keep their data in their own directories
+	for _, deployment := range instanceDeployments {
+		for _, service := range deployment.Services {
+			if service.DataDir == "" {
+				continue
+			}
+			if networkFilter != "" && (filterNetwork != service.Network || (filterInstance != "" && filterInstance != deployment.Instance)) {
+				continue
+			}
+
+			networkLocations, err := utils.GetDataLocationsAt(service.DataDir)
+			if err != nil {
+				logger.LogError("Failed to find data locations for " + service.Network + ": " + err.Error())
+			}
+
+			for _, location := range networkLocations {
+				locations = append(locations, DataUsage{Network: utils.JoinNetworkInstance(service.Network, deployment.Instance), Location: location.Label, Directory: location.Path})
+			}
+		}
+	}
+
+	// Calculate the size of every directory concurrently, dropping directories that don't exist
+	found := make([]bool, len(locations))
+	var wg sync.WaitGroup
+	for i := range locations {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			size, err := collectWithTimeout(directorySizeTimeout, func(ctx context.Context) (int64, error) {
+				return getDirectorySize(locations[i].Directory)
+			})
+			if errors.Is(err, errCollectTimeout) {
+				locations[i].Timeout = true
+				found[i] = true
+			} else if err == nil {
+				locations[i].SizeBytes = size
+				found[i] = true
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	usage := []DataUsage{}
+	for i, location := range locations {
+		if found[i] {
+			usage = append(usage, location)
+		}
+	}
+
+	return usage
+}
+
+func displayNodeDirectoryInfo(usage []DataUsage) {
+	fmt.Print("-- Blockchain Node Data:\n\n")
+
+	// Set up tabwriter for nicely formatted output
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', tabwriter.Debug)
+	fmt.Fprintln(w, "| NETWORK\t LOCATION\t SIZE\t DIRECTORY")
+
+	for _, entry := range usage {
+		// Output the formatted row with network name, location, size, and directory path
+		size := utils.GetSizeDescription(entry.SizeBytes)
+		if entry.Timeout {
+			size = "timeout"
+		}
+		fmt.Fprintf(w, "| %s\t %s\t %s\t %s\n", entry.Network, entry.Location, size, entry.Directory)
 	}
 
-	return int(peerCount)
+	if len(usage) == 0 {
+		fmt.Fprintf(w, "| -\t -\t -\t -\n")
+	}
+
+	w.Flush()
 }
 
-func displayNodeDirectoryInfo(networkFilter string) {
-	fmt.Println("-- Blockchain Node Data:\n")
+func collectResourceProfiles(networkFilter string) []NetworkProfile {
+	profiles := []NetworkProfile{}
 
-	// Get the nodevin data directory
 	nodevinDataDir, err := utils.GetNodevinDataDir()
 	if err != nil {
-		logger.LogError("Failed to find Nodevin data directory: " + err.Error())
-		return
+		return profiles
 	}
 
-	// Fetch the list of supported networks
-	networks := utils.GetAllSupportedNetworks()
-	if networks == "" {
-		fmt.Println("No data found.")
-		return
+	// The profile is kept next to the node's data, which deployments started with
+	// --data-dir, --local-path or --instance keep outside the default directory
+	recordedDirs := map[string]string{}
+	var instanceDeployments []state.Deployment
+	for _, deployment := range listDeployments() {
+		if deployment.Instance != "" {
+			instanceDeployments = append(instanceDeployments, deployment)
+			continue
+		}
+		for _, service := range deployment.Services {
+			if _, exists := recordedDirs[service.Network]; !exists && service.DataDir != "" {
+				recordedDirs[service.Network] = service.DataDir
+			}
+		}
 	}
 
-	// Set up tabwriter for nicely formatted output
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', tabwriter.Debug)
-	fmt.Fprintln(w, "| NETWORK\t SIZE\t DIRECTORY")
+	filterNetwork, filterInstance := utils.SplitNetworkInstance(networkFilter)
 
-	printed := 0
-
-	// Iterate over each supported network and calculate its directory size
-	for _, network := range strings.Split(networks, ", ") {
-		containerName, exists := utils.GetDefaultLocalMappedContainerName(network)
-		if !exists {
-			logger.LogError("Unsupported blockchain network: " + network)
+	for _, network := range strings.Split(utils.GetAllSupportedNetworks(), ", ") {
+		if networkFilter != "" && (filterNetwork != network || filterInstance != "") {
 			continue
 		}
 
-		if networkFilter != "" && networkFilter != network {
+		containerName, _ := utils.GetDefaultLocalMappedContainerName(network)
+		dataDir := filepath.Join(nodevinDataDir, containerName)
+		if recordedDir, exists := recordedDirs[network]; exists {
+			dataDir = recordedDir
+		}
+
+		settings, err := profile.Read(dataDir)
+		if err != nil || settings == nil {
 			continue
 		}
 
-		networkDir := filepath.Join(nodevinDataDir, containerName)
-		size, err := getDirectorySize(networkDir)
-		sizeDescription := "unknown"
-		if err == nil {
-			printed++
-			sizeDescription = utils.GetSizeDescription(size)
-			// Output the formatted row with network name, size, and directory path
-			fmt.Fprintf(w, "| %s\t %s\t %s\n", network, sizeDescription, networkDir)
+		profiles = append(profiles, NetworkProfile{Network: network, Settings: *settings})
+	}
+
+	for _, deployment := range instanceDeployments {
+		for _, service := range deployment.Services {
+			if service.DataDir == "" {
+				continue
+			}
+			if networkFilter != "" && (filterNetwork != service.Network || (filterInstance != "" && filterInstance != deployment.Instance)) {
+				continue
+			}
+
+			settings, err := profile.Read(service.DataDir)
+			if err != nil || settings == nil {
+				continue
+			}
+
+			profiles = append(profiles, NetworkProfile{Network: utils.JoinNetworkInstance(service.Network, deployment.Instance), Settings: *settings})
 		}
 	}
 
-	if printed == 0 {
-		fmt.Fprintf(w, "| -\t -\t -\n")
+	return profiles
+}
+
+func displayResourceProfiles(profiles []NetworkProfile) {
+	if len(profiles) == 0 {
+		return
+	}
+
+	fmt.Print("\n-- Resource Profiles:\n\n")
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', tabwriter.Debug)
+	fmt.Fprintln(w, "| NETWORK\t PROFILE\t PHASE\t CPU LIMIT\t MEM LIMIT\t DBCACHE\t PAR\t MAXMEMPOOL")
+
+	for _, entry := range profiles {
+		fmt.Fprintf(w, "| %s\t %s\t %s\t %s\t %s\t %d MB\t %d\t %d MB\n",
+			entry.Network,
+			entry.Profile,
+			entry.Phase,
+			valueOrDash(entry.CPULimit),
+			valueOrDash(entry.MemLimit),
+			entry.DBCacheMB,
+			entry.Par,
+			entry.MaxMempoolMB,
+		)
 	}
 
 	w.Flush()
 }
 
-func getDirectorySize(path string) (int64, error) {
-	var size int64
-	err := filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if !info.IsDir() {
-			size += info.Size()
-		}
-		return nil
-	})
-	return size, err
-}
-
-func formatPorts(ports string) string {
-	portSegments := strings.Split(ports, ",")
-	formattedPorts := []string{}
-	uniquePorts := make(map[string]bool)
-
-	for _, segment := range portSegments {
-		// Use a regex to extract the port numbers and ranges
-		re := regexp.MustCompile(`(\d+(-\d+)?)`)
-		matches := re.FindAllString(segment, -1)
-		for _, match := range matches {
-			if match != "0" && !uniquePorts[match] {
-				uniquePorts[match] = true
-				formattedPorts = append(formattedPorts, match)
-			}
-		}
+func valueOrDash(value string) string {
+	if value == "" {
+		return "-"
 	}
-	return strings.Join(formattedPorts, ", ")
+	return value
+}
+
+// Sizes come from the cached size index rather than walking every file
+func getDirectorySize(path string) (int64, error) {
+	return sizeindex.DirectorySize(path)
 }
 
-func getNodeVersionFromEnv(containerID string) string {
+func getNodeVersionFromEnv(ctx context.Context, containerID string) (string, error) {
 	// Prepare the docker inspect command
-	cmd := exec.Command("docker", "inspect", containerID)
+	cmd := exec.CommandContext(ctx, "docker", "inspect", containerID)
 
 	// Execute the command
 	output, err := cmd.CombinedOutput()
 	if err != nil {
-		logger.LogError("Failed to inspect container: " + err.Error())
-		return "unknown"
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return "", err
 	}
 
 	// Parse the output JSON
 	var inspectData []map[string]interface{}
 	if err := json.Unmarshal(output, &inspectData); err != nil {
-		logger.LogError("Failed to parse inspect data: " + err.Error())
-		return "unknown"
+		return "", fmt.Errorf("failed to parse inspect data: %w", err)
 	}
 
 	// Traverse to find environment variables
@@ -430,12 +926,16 @@ func getNodeVersionFromEnv(containerID string) string {
 			if envVars, ok := config["Env"].([]interface{}); ok {
 				for _, envVar := range envVars {
 					if envStr, ok := envVar.(string); ok && strings.HasPrefix(envStr, "NODE_VERSION=") {
-						return strings.TrimPrefix(envStr, "NODE_VERSION=")
+						return strings.TrimPrefix(envStr, "NODE_VERSION="), nil
 					}
 				}
 			}
 		}
 	}
 
-	return "unknown"
+	return "unknown", nil
+}
+
+func init() {
+	addRPCFlags(infoCmd.Flags())
 }
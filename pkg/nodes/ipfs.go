@@ -28,24 +28,23 @@ import (
 )
 
 var ipfsSupportCmd = &cobra.Command{
-	Use:   "ipfs support [network]",
+	Use:   "support [network]",
 	Short: "Support and pin a network snapshot in a local IPFS container",
 	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		name := args[0]
 		CID, exists := utils.GetSnapshotCIDByNetwork(name)
 		if !exists {
-			fmt.Printf("Unsupported name: %s\n", name)
-			return
+			return fmt.Errorf("unsupported name: %s", name)
 		}
 
 		containerName := "ipfs"
-		err := pinCIDInContainer(containerName, CID)
-		if err != nil {
-			fmt.Printf("Failed to pin CID in container %s: %v\n", containerName, err)
-		} else {
-			fmt.Printf("Successfully pinned CID %s for %s in container %s\n", CID, name, containerName)
+		if err := pinCIDInContainer(containerName, CID); err != nil {
+			return fmt.Errorf("failed to pin CID in container %s: %w", containerName, err)
 		}
+
+		fmt.Printf("Successfully pinned CID %s for %s in container %s\n", CID, name, containerName)
+		return nil
 	},
 }
 
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"sort"
 
+	"github.com/fiftysixcrypto/nodevin/internal/output"
 	"github.com/fiftysixcrypto/nodevin/internal/utils"
 	"github.com/spf13/cobra"
 )
@@ -11,12 +12,31 @@ import (
 var listCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all supported networks",
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if output.IsStructured() {
+			return output.Print(collectNetworks())
+		}
+
 		listAllNetworks()
+		return nil
 	},
 }
 
-func listAllNetworks() {
+// ListReport is the output of `nodevin list` in json or yaml mode
+type ListReport struct {
+	Networks []NetworkEntry `json:"networks" yaml:"networks"`
+}
+
+// NetworkEntry describes one network nodevin knows how to run
+type NetworkEntry struct {
+	Name             string `json:"name" yaml:"name"`
+	ContainerName    string `json:"container_name" yaml:"container_name"`
+	Image            string `json:"image" yaml:"image"`
+	RPCPort          int    `json:"rpc_port" yaml:"rpc_port"`
+	CommandSupported bool   `json:"command_supported" yaml:"command_supported"`
+}
+
+func collectNetworks() ListReport {
 	var networkNames []string
 	for network := range utils.NetworkContainerMap() {
 		networkNames = append(networkNames, network)
@@ -24,10 +44,29 @@ func listAllNetworks() {
 
 	sort.Strings(networkNames)
 
+	rpcPorts := utils.NetworkDefaultRPCPorts()
+	report := ListReport{Networks: []NetworkEntry{}}
+	for _, network := range networkNames {
+		containerName, _ := utils.GetDefaultLocalMappedContainerName(network)
+		image, _ := utils.GetNetworkImageRepository(network)
+
+		report.Networks = append(report.Networks, NetworkEntry{
+			Name:             network,
+			ContainerName:    containerName,
+			Image:            image,
+			RPCPort:          rpcPorts[network],
+			CommandSupported: utils.IsCommandSupportedNetwork(network),
+		})
+	}
+
+	return report
+}
+
+func listAllNetworks() {
 	fmt.Printf("Supported networks: %s\n", utils.GetCommandSupportedNetworks())
 	fmt.Print("\nHelpful Commands:\n")
-	fmt.Printf("%s start <network>\n", utils.GetNodevinExecutable())
-	fmt.Printf("%s start <network> --testnet\n", utils.GetNodevinExecutable())
-	fmt.Printf("%s start bitcoin --ord\n", utils.GetNodevinExecutable())
-	fmt.Printf("%s start litecoin --ord-litecoin\n", utils.GetNodevinExecutable())
+	fmt.Printf("%s <network> start\n", utils.GetNodevinExecutable())
+	fmt.Printf("%s <network> start --testnet\n", utils.GetNodevinExecutable())
+	fmt.Printf("%s bitcoin start --ord\n", utils.GetNodevinExecutable())
+	fmt.Printf("%s litecoin start --ord\n", utils.GetNodevinExecutable())
 }
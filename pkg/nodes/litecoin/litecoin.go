@@ -19,13 +19,8 @@
 package litecoin
 
 import (
-	"runtime"
-
-	"github.com/fiftysixcrypto/nodevin/internal/logger"
 	"github.com/fiftysixcrypto/nodevin/internal/utils"
-	"github.com/fiftysixcrypto/nodevin/pkg/docker"
 	"github.com/fiftysixcrypto/nodevin/pkg/docker/compose"
-	"github.com/spf13/viper"
 )
 
 func CreateLitecoinComposeFile(cwd string) (string, error) {
@@ -42,67 +37,21 @@ func CreateLitecoinComposeFile(cwd string) (string, error) {
 		return "", err
 	}
 
-	composeFilePath, err := compose.CreateComposeFile(
-		litecoinBaseComposeConfig.ContainerName,
-		litecoinBaseComposeConfig,
-		[]string{},
-		[]compose.NetworkConfig{},
-		cwd)
-
+	// Add-ons (ex: ord-litecoin) are written as extra services of the node's compose file
+	addons, err := compose.GetEnabledAddons("litecoin")
 	if err != nil {
 		return "", err
 	}
 
-	if viper.GetBool("ord") || viper.GetBool("ord-litecoin") {
-		if runtime.GOARCH == "arm64" {
-			logger.LogError("Running on ARM architecture: ord functionality is not supported on ARM builds. Skipping ord-litecoin.")
-			return composeFilePath, nil
-		}
-
-		var ordLitecoinNetwork string
-
-		if utils.CheckIfTestnetOrTestnetNetworkFlag() {
-			ordLitecoinNetwork = "ord-litecoin-testnet"
-		} else {
-			ordLitecoinNetwork = "ord-litecoin"
-		}
-
-		// Pull the ord Docker image
-		image := viper.GetString("ord-litecoin-image") + ":" + viper.GetString("ord-litecoin-version")
-		if err := docker.PullImage(image); err != nil {
-			logger.LogError("Failed to pull Docker image: " + err.Error())
-			return "", err
-		}
-
-		ordLitecoinComposeConfig, err := compose.GetOrdLitecoinNetworkComposeConfig(ordLitecoinNetwork)
-		if err != nil {
-			return "", err
-		}
-
-		composeFilePath, err := compose.CreateComposeFile(
-			litecoinBaseComposeConfig.ContainerName,
-			litecoinBaseComposeConfig,
-			[]string{"ord-litecoin"},
-			[]compose.NetworkConfig{ordLitecoinComposeConfig},
-			cwd)
-
-		if err != nil {
-			return "", err
-		}
-
-		return composeFilePath, nil
-	} else {
-		composeFilePath, err := compose.CreateComposeFile(
-			litecoinBaseComposeConfig.ContainerName,
-			litecoinBaseComposeConfig,
-			[]string{},
-			[]compose.NetworkConfig{},
-			cwd)
-
-		if err != nil {
-			return "", err
-		}
-
-		return composeFilePath, nil
+	addonNames, addonConfigs, err := compose.GetAddonComposeConfigs(addons, &litecoinBaseComposeConfig)
+	if err != nil {
+		return "", err
 	}
+
+	return compose.CreateComposeFile(
+		litecoinBaseComposeConfig.ContainerName,
+		litecoinBaseComposeConfig,
+		addonNames,
+		addonConfigs,
+		cwd)
 }
@@ -0,0 +1,124 @@
+/*
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 The Nodevin Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package nodes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/fiftysixcrypto/nodevin/internal/state"
+	"github.com/fiftysixcrypto/nodevin/internal/utils"
+	"github.com/fiftysixcrypto/nodevin/pkg/docker/compose"
+	"github.com/spf13/cobra"
+)
+
+// LightningStatus is what a Lightning add-on reports about its channels and chain sync
+type LightningStatus struct {
+	Channels        int  `json:"channels" yaml:"channels"`
+	PendingChannels int  `json:"pending_channels" yaml:"pending_channels"`
+	SyncedToChain   bool `json:"synced_to_chain" yaml:"synced_to_chain"`
+}
+
+var lnCmd = &cobra.Command{
+	Use:   "ln <command> [args...]",
+	Short: "Run lncli or lightning-cli in the Lightning add-on of the bitcoin node",
+	Long: "Runs a command of the Lightning add-on's own client, lncli for lnd and lightning-cli for cln,\n" +
+		"inside its container, ex: `nodevin ln getinfo` or `nodevin --network regtest ln newaddress`.",
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		service, cli, err := findLightningService()
+		if err != nil {
+			return err
+		}
+
+		execArgs := []string{"exec", "-i"}
+		if isInteractive() {
+			execArgs = append(execArgs, "-t")
+		}
+		execArgs = append(execArgs, service.ContainerName)
+		execArgs = append(execArgs, cli...)
+
+		ln := exec.Command("docker", append(execArgs, args...)...)
+		ln.Stdout = os.Stdout
+		ln.Stderr = os.Stderr
+		ln.Stdin = os.Stdin
+		return ln.Run()
+	},
+}
+
+// Returns the Lightning add-on of the bitcoin deployment selected by --testnet, --network
+// and --instance, with the client command that runs in its container
+func findLightningService() (state.Service, []string, error) {
+	name := getDeploymentName("bitcoin")
+	deployment, exists, err := state.Get(name)
+	if err != nil {
+		return state.Service{}, nil, fmt.Errorf("failed to read nodevin state: %w", err)
+	}
+	if exists {
+		for _, service := range deployment.Services {
+			if cli, ok := compose.LightningCLI(service.Network); ok {
+				return service, cli, nil
+			}
+		}
+	}
+	return state.Service{}, nil, fmt.Errorf("no Lightning add-on runs alongside %s, start one with `%s bitcoin start --addon lnd` (or cln)", name, utils.GetNodevinExecutable())
+}
+
+// Asks a Lightning add-on's client for its channel and sync status, and its number of peers
+func getLightningStatus(ctx context.Context, containerName string, network string) (LightningStatus, int, error) {
+	cli, ok := compose.LightningCLI(network)
+	if !ok {
+		return LightningStatus{}, 0, fmt.Errorf("%s is not a Lightning node", network)
+	}
+
+	out, err := exec.CommandContext(ctx, "docker", append([]string{"exec", containerName}, append(cli, "getinfo")...)...).Output()
+	if err != nil {
+		return LightningStatus{}, 0, fmt.Errorf("failed to get Lightning info from %s: %w", containerName, err)
+	}
+
+	// lncli and lightning-cli share the channel and peer counts, not how they report sync
+	var info struct {
+		NumActiveChannels   int    `json:"num_active_channels"`
+		NumPendingChannels  int    `json:"num_pending_channels"`
+		NumPeers            int    `json:"num_peers"`
+		SyncedToChain       *bool  `json:"synced_to_chain"`
+		WarningBitcoindSync string `json:"warning_bitcoind_sync"`
+		WarningLightningd   string `json:"warning_lightningd_sync"`
+	}
+	if err := json.Unmarshal(out, &info); err != nil {
+		return LightningStatus{}, 0, fmt.Errorf("failed to parse Lightning info from %s: %w", containerName, err)
+	}
+
+	status := LightningStatus{
+		Channels:        info.NumActiveChannels,
+		PendingChannels: info.NumPendingChannels,
+		SyncedToChain:   info.WarningBitcoindSync == "" && info.WarningLightningd == "",
+	}
+	if info.SyncedToChain != nil {
+		status.SyncedToChain = *info.SyncedToChain
+	}
+	return status, info.NumPeers, nil
+}
+
+func init() {
+	lnCmd.Flags().SetInterspersed(false)
+}
@@ -0,0 +1,313 @@
+/*
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 The Nodevin Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package nodes
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fiftysixcrypto/nodevin/internal/lockfile"
+	"github.com/fiftysixcrypto/nodevin/internal/logger"
+	"github.com/fiftysixcrypto/nodevin/internal/state"
+	"github.com/fiftysixcrypto/nodevin/internal/utils"
+	"github.com/fiftysixcrypto/nodevin/pkg/docker"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var lockCmd = &cobra.Command{
+	Use:   "lock",
+	Short: "Manage the image digests pinned in nodevin.lock",
+}
+
+var lockUpdateCmd = &cobra.Command{
+	Use:   "update [network]",
+	Short: "Resolve the image tags of deployments to their current digests and write them to nodevin.lock",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		target := ""
+		if len(args) > 0 {
+			target = args[0]
+		}
+		return updateLockfile(target)
+	},
+}
+
+// A change to one image in nodevin.lock, shown in the `lock update` summary
+type lockChange struct {
+	Deployment string
+	Service    string
+	Image      string
+	OldDigest  string
+	NewDigest  string
+}
+
+// Returns the locked image references of a deployment keyed by service network, for `start --locked`
+func getLockedImages(deploymentName string) (map[string]string, error) {
+	path, err := lockfile.Path(viper.GetString("lock-file"))
+	if err != nil {
+		return nil, err
+	}
+
+	lock, exists, err := lockfile.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("--locked was given but %s does not exist", path)
+	}
+
+	locked, exists := lock.Deployments[deploymentName]
+	if !exists || len(locked.Services) == 0 {
+		return nil, fmt.Errorf("%s has no images locked for %s, run `%s lock update %s` first", path, deploymentName, utils.GetNodevinExecutable(), deploymentName)
+	}
+
+	images := map[string]string{}
+	for network, image := range locked.Services {
+		images[network] = image.Reference()
+	}
+	return images, nil
+}
+
+// Returns the images of a deployment that `nodevin bundle install` loaded, keyed by service network
+func getBundledImages(deploymentName string) (map[string]lockfile.LockedImage, error) {
+	path, err := lockfile.Path(viper.GetString("lock-file"))
+	if err != nil {
+		return nil, err
+	}
+
+	lock, _, err := lockfile.Load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	images := map[string]lockfile.LockedImage{}
+	if locked, exists := lock.Deployments[deploymentName]; exists {
+		for network, image := range locked.Services {
+			if image.Bundled() {
+				images[network] = image
+			}
+		}
+	}
+	return images, nil
+}
+
+// Records the digests a freshly started deployment resolved its image tags to
+func lockDeploymentImages(deploymentName string) error {
+	deployment, exists, err := state.Get(deploymentName)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("deployment %s is not recorded", deploymentName)
+	}
+
+	path, err := lockfile.Path(viper.GetString("lock-file"))
+	if err != nil {
+		return err
+	}
+
+	lock, _, err := lockfile.Load(path)
+	if err != nil {
+		return err
+	}
+
+	var previous map[string]lockfile.LockedImage
+	if existing, exists := lock.Deployments[deployment.Name]; exists {
+		previous = existing.Services
+	}
+
+	locked := &lockfile.LockedDeployment{Services: map[string]lockfile.LockedImage{}, ResolvedAt: time.Now().UTC()}
+	for _, service := range deployment.Services {
+		// Images loaded from a bundle stay pinned to their local image IDs
+		if bundled, exists := previous[service.Network]; exists && bundled.Bundled() && bundled.Image == service.Image {
+			locked.Services[service.Network] = bundled
+			continue
+		}
+
+		// Locally built images have no registry digest another host could pull
+		if !strings.Contains(service.ImageDigest, "@") {
+			logger.LogInfo(fmt.Sprintf("Not locking %s: it has no registry digest", service.Image))
+			continue
+		}
+		locked.Services[service.Network] = lockfile.LockedImage{
+			Image:  service.Image,
+			Digest: docker.DigestOf(service.ImageDigest),
+		}
+	}
+
+	lock.Deployments[deployment.Name] = locked
+	if err := lockfile.Save(path, lock); err != nil {
+		return err
+	}
+
+	logger.LogInfo(fmt.Sprintf("Locked the images of %s in %s", deployment.Name, path))
+	return nil
+}
+
+// Resolves the image tags of the locked and recorded deployments matching target (all if empty)
+// to their current registry digests, and prints what changed
+func updateLockfile(target string) error {
+	path, err := lockfile.Path(viper.GetString("lock-file"))
+	if err != nil {
+		return err
+	}
+
+	lock, _, err := lockfile.Load(path)
+	if err != nil {
+		return err
+	}
+
+	// Image tags to resolve per deployment and service network: those recorded in the state
+	// (images of deployments started with --locked are digests and skipped), then the lockfile's
+	tags := map[string]map[string]string{}
+	for _, deployment := range listDeployments() {
+		tags[deployment.Name] = map[string]string{}
+		for _, service := range deployment.Services {
+			if !strings.Contains(service.Image, "@") {
+				tags[deployment.Name][service.Network] = service.Image
+			}
+		}
+	}
+	for name, locked := range lock.Deployments {
+		if _, exists := tags[name]; !exists {
+			tags[name] = map[string]string{}
+		}
+		for network, image := range locked.Services {
+			tags[name][network] = image.Image
+		}
+	}
+
+	var names []string
+	for name := range tags {
+		if target == "" || matchesLockTarget(name, target) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	if len(names) == 0 {
+		if target != "" {
+			return fmt.Errorf("no recorded or locked deployment matches %s", target)
+		}
+		return fmt.Errorf("no deployments to lock, start a node first")
+	}
+
+	var changes []lockChange
+	failed := false
+	for _, name := range names {
+		locked, exists := lock.Deployments[name]
+		if !exists {
+			locked = &lockfile.LockedDeployment{Services: map[string]lockfile.LockedImage{}}
+			lock.Deployments[name] = locked
+		}
+
+		var networks []string
+		for network := range tags[name] {
+			networks = append(networks, network)
+		}
+		sort.Strings(networks)
+
+		for _, network := range networks {
+			image := tags[name][network]
+
+			ctx, cancel := context.WithTimeout(context.Background(), registryCheckTimeout)
+			digest, err := docker.GetRemoteImageDigest(ctx, image)
+			cancel()
+			if err != nil {
+				logger.LogError(fmt.Sprintf("Failed to resolve %s: %v", image, err))
+				failed = true
+				continue
+			}
+
+			previous := locked.Services[network]
+			if previous.Digest != digest || previous.Image != image {
+				changes = append(changes, lockChange{
+					Deployment: name,
+					Service:    network,
+					Image:      image,
+					OldDigest:  previous.Digest,
+					NewDigest:  digest,
+				})
+			}
+			locked.Services[network] = lockfile.LockedImage{Image: image, Digest: digest}
+		}
+		locked.ResolvedAt = time.Now().UTC()
+	}
+
+	if err := lockfile.Save(path, lock); err != nil {
+		return err
+	}
+
+	displayLockChanges(changes)
+	logger.LogInfo(fmt.Sprintf("Wrote %s", path))
+
+	if failed {
+		return fmt.Errorf("some images could not be resolved and keep their previous digests")
+	}
+	return nil
+}
+
+// Matches a network argument (ex: bitcoin or bitcoin/pruned) against a lockfile deployment name
+// (ex: bitcoin-testnet/pruned)
+func matchesLockTarget(name string, target string) bool {
+	if name == target {
+		return true
+	}
+
+	network, instance := utils.SplitNetworkInstance(name)
+	targetNetwork, targetInstance := utils.SplitNetworkInstance(target)
+	if targetInstance != "" && instance != targetInstance {
+		return false
+	}
+	base, _ := utils.SplitNetworkVariant(network)
+	return network == targetNetwork || base == targetNetwork
+}
+
+func displayLockChanges(changes []lockChange) {
+	if len(changes) == 0 {
+		logger.LogInfo("All locked images are up to date.")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', tabwriter.Debug)
+	fmt.Fprintln(w, "| DEPLOYMENT\t SERVICE\t IMAGE\t LOCKED\t NOW")
+
+	for _, change := range changes {
+		fmt.Fprintf(w, "| %s\t %s\t %s\t %s\t %s\n",
+			change.Deployment,
+			change.Service,
+			change.Image,
+			shortDigest(change.OldDigest),
+			shortDigest(change.NewDigest),
+		)
+	}
+	w.Flush()
+
+	logger.LogInfo(fmt.Sprintf("%d locked image(s) changed.", len(changes)))
+}
+
+func init() {
+	lockCmd.AddCommand(lockUpdateCmd)
+}
@@ -19,11 +19,15 @@
 package nodes
 
 import (
+	"bufio"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"sync"
 
 	"github.com/fiftysixcrypto/nodevin/internal/logger"
+	"github.com/fiftysixcrypto/nodevin/internal/output"
 	"github.com/fiftysixcrypto/nodevin/internal/utils"
 	"github.com/spf13/cobra"
 )
@@ -35,37 +39,39 @@ var logsCmd = &cobra.Command{
 	Use:   "logs [network]",
 	Short: "Fetch logs from a running node",
 	Args:  cobra.MaximumNArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		if len(args) == 0 {
-			logger.LogError("No network specified. To fetch logs, specify the network explicitly.")
 			availableNetworks := utils.GetCommandSupportedNetworks()
 			logger.LogInfo("List of available networks: " + availableNetworks)
 			logger.LogInfo(fmt.Sprintf("Example usage: `%s logs <network>`", utils.GetNodevinExecutable()))
-			return
+			return fmt.Errorf("no network specified. To fetch logs, specify the network explicitly")
 		}
 
 		network := args[0]
-		fetchLogs(network)
+		return fetchLogs(network)
 	},
 }
 
-func fetchLogs(network string) {
-	logger.LogInfo("Fetching logs for node...")
+// LogLine is a single log line emitted by `nodevin logs` in json or yaml mode
+type LogLine struct {
+	Network   string `json:"network" yaml:"network"`
+	Container string `json:"container" yaml:"container"`
+	Stream    string `json:"stream" yaml:"stream"`
+	Line      string `json:"line" yaml:"line"`
+}
 
-	properNetwork := network
+func fetchLogs(network string) error {
+	logger.LogInfo("Fetching logs for node...")
 
-	if utils.CheckIfTestnetOrTestnetNetworkFlag() {
-		if network == "bitcoin" {
-			properNetwork = "bitcoin-testnet"
-		} else if network == "litecoin" {
-			properNetwork = "litecoin-testnet"
-		}
+	network, err := resolveNetworkArg(network)
+	if err != nil {
+		return err
 	}
+	properNetwork := getSoftwareNetwork(network)
 
-	containerName, exists := getOutputLogsContainerName(properNetwork)
+	containerName, exists := getNodeContainerName(properNetwork)
 	if !exists {
-		logger.LogError("Unsupported blockchain network: " + network)
-		return
+		return fmt.Errorf("unsupported blockchain network: %s", network)
 	}
 
 	args := []string{"logs"}
@@ -78,21 +84,71 @@ func fetchLogs(network string) {
 	args = append(args, containerName)
 
 	cmd := exec.Command("docker", args...)
+
+	if output.IsStructured() {
+		return streamStructuredLogs(cmd, properNetwork, containerName)
+	}
+
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
 	if err := cmd.Run(); err != nil {
-		logger.LogError("Failed to fetch Docker logs: " + err.Error())
+		return fmt.Errorf("failed to fetch Docker logs: %w", err)
 	}
+	return nil
 }
 
-func getOutputLogsContainerName(network string) (string, bool) {
-	for net, container := range utils.NetworkContainerMap() {
-		if net == network {
-			return container, true
+// Emits each container log line as its own record, keeping stdout and stderr apart
+func streamStructuredLogs(cmd *exec.Cmd, network string, containerName string) error {
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to fetch Docker logs: %w", err)
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return fmt.Errorf("failed to fetch Docker logs: %w", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to fetch Docker logs: %w", err)
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	scan := func(r io.Reader, stream string) {
+		defer wg.Done()
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			mu.Lock()
+			output.PrintRecord(LogLine{Network: network, Container: containerName, Stream: stream, Line: scanner.Text()})
+			mu.Unlock()
 		}
 	}
-	return "", false
+
+	wg.Add(2)
+	go scan(stdout, "stdout")
+	go scan(stderr, "stderr")
+	wg.Wait()
+
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("failed to fetch Docker logs: %w", err)
+	}
+	return nil
+}
+
+// Returns the container running a network, for the instance selected with --instance
+func getNodeContainerName(network string) (string, bool) {
+	// Deployments started with --container-name are only found through the state
+	if _, service, exists := findDeploymentService(utils.JoinNetworkInstance(network, utils.GetInstance())); exists && service.ContainerName != "" {
+		return service.ContainerName, true
+	}
+
+	containerName, exists := utils.GetDefaultLocalMappedContainerName(network)
+	if !exists {
+		return "", false
+	}
+	return utils.InstanceName(containerName), true
 }
 
 func init() {
@@ -0,0 +1,405 @@
+/*
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 The Nodevin Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package nodes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fiftysixcrypto/nodevin/internal/logger"
+	"github.com/fiftysixcrypto/nodevin/internal/utils"
+	"github.com/fiftysixcrypto/nodevin/pkg/docker"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const metricsHTTPTimeout = 10 * time.Second
+
+var metricsCmd = &cobra.Command{
+	Use:   "metrics",
+	Short: "Export metrics for running nodes",
+}
+
+var metricsServeCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve Prometheus metrics for every running nodevin node",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return serveMetrics(viper.GetString("metrics-listen"))
+	},
+}
+
+type metricKind string
+
+const (
+	metricGauge   metricKind = "gauge"
+	metricCounter metricKind = "counter"
+)
+
+type metricDescription struct {
+	kind metricKind
+	help string
+}
+
+var metricDescriptions = map[string]metricDescription{
+	"nodevin_container_info":                    {metricGauge, "Running nodevin container (always 1), labelled with its image and version."},
+	"nodevin_node_up":                           {metricGauge, "Whether the node answered its RPC calls during the scrape."},
+	"nodevin_block_height":                      {metricGauge, "Height of the node's most-work fully validated chain."},
+	"nodevin_headers":                           {metricGauge, "Number of validated headers known to the node."},
+	"nodevin_verification_progress":             {metricGauge, "Estimated chain verification progress (0 to 1)."},
+	"nodevin_initial_block_download":            {metricGauge, "Whether the node is in initial block download."},
+	"nodevin_peers":                             {metricGauge, "Connected peers by direction."},
+	"nodevin_peers_connected":                   {metricGauge, "Total connected peers."},
+	"nodevin_mempool_transactions":              {metricGauge, "Transactions in the node's mempool."},
+	"nodevin_mempool_bytes":                     {metricGauge, "Virtual size of the node's mempool in bytes."},
+	"nodevin_chain_data_size_bytes":             {metricGauge, "Size of each data directory used by the network."},
+	"nodevin_container_cpu_usage_seconds_total": {metricCounter, "Cumulative CPU time consumed by the container."},
+	"nodevin_container_cpu_percent":             {metricGauge, "CPU usage of the container as reported by docker stats."},
+	"nodevin_container_memory_usage_bytes":      {metricGauge, "Memory used by the container, excluding page cache."},
+	"nodevin_container_memory_limit_bytes":      {metricGauge, "Memory limit of the container."},
+	"nodevin_container_restarts":                {metricGauge, "Number of times Docker restarted the container."},
+	"nodevin_ord_index_height":                  {metricGauge, "Block height indexed by ord."},
+	"nodevin_ipfs_peers":                        {metricGauge, "Swarm peers connected to the IPFS node."},
+	"nodevin_ipfs_repo_size_bytes":              {metricGauge, "Size of the IPFS repository."},
+	"nodevin_ipfs_repo_storage_max_bytes":       {metricGauge, "Configured maximum size of the IPFS repository."},
+	"nodevin_scrape_duration_seconds":           {metricGauge, "Time taken to collect all nodevin metrics."},
+}
+
+type metricSample struct {
+	labels []string
+	value  float64
+}
+
+// Collects samples and writes them in the Prometheus text exposition format
+type metricSet struct {
+	samples map[string][]metricSample
+}
+
+func newMetricSet() *metricSet {
+	return &metricSet{samples: map[string][]metricSample{}}
+}
+
+// Adds a sample, labels are given as name/value pairs
+func (m *metricSet) add(name string, value float64, labels ...string) {
+	m.samples[name] = append(m.samples[name], metricSample{labels: labels, value: value})
+}
+
+func (m *metricSet) write(w io.Writer) error {
+	var names []string
+	for name := range m.samples {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		description := metricDescriptions[name]
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, description.help, name, description.kind); err != nil {
+			return err
+		}
+
+		for _, sample := range m.samples[name] {
+			if _, err := fmt.Fprintf(w, "%s%s %s\n", name, formatMetricLabels(sample.labels), formatMetricValue(sample.value)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func formatMetricLabels(labels []string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	escaper := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+	var pairs []string
+	for i := 0; i+1 < len(labels); i += 2 {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, labels[i], escaper.Replace(labels[i+1])))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatMetricValue(value float64) string {
+	if math.IsNaN(value) {
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+type metricsExporter struct {
+	httpClient *http.Client
+}
+
+func serveMetrics(listen string) error {
+	exporter := &metricsExporter{
+		httpClient: &http.Client{Timeout: metricsHTTPTimeout},
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", exporter)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, "<html><head><title>nodevin exporter</title></head><body><h1>nodevin exporter</h1><p><a href=\"/metrics\">Metrics</a></p></body></html>\n")
+	})
+
+	logger.LogInfo(fmt.Sprintf("Serving Prometheus metrics on %s/metrics", listen))
+
+	server := &http.Server{
+		Addr:              listen,
+		Handler:           mux,
+		ReadHeaderTimeout: metricsHTTPTimeout,
+	}
+	if err := server.ListenAndServe(); err != nil {
+		return fmt.Errorf("metrics server failed: %w", err)
+	}
+	return nil
+}
+
+func (e *metricsExporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	metrics, err := e.collect(r.Context())
+	if err != nil {
+		logger.LogError("Failed to collect metrics: " + err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	metrics.add("nodevin_scrape_duration_seconds", time.Since(start).Seconds())
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := metrics.write(w); err != nil {
+		logger.LogError("Failed to write metrics: " + err.Error())
+	}
+}
+
+func (e *metricsExporter) collect(ctx context.Context) (*metricSet, error) {
+	containers, err := listNodevinContainers(docker.RoleNode, docker.RoleSidecar)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch Docker container information: %w", err)
+	}
+
+	metrics := newMetricSet()
+	for _, container := range containers {
+		network := container.Network
+
+		// Instances of the same network only differ by their instance and container labels
+		labels := []string{"network", network, "instance", container.Instance, "container", container.Names}
+
+		image, version := splitImageTag(utils.TrimImageRegistry(container.Image))
+		if version == "" {
+			version = "unknown"
+		}
+		metrics.add("nodevin_container_info", 1, append(labels, "image", image, "version", version)...)
+
+		e.collectContainerStats(ctx, metrics, container.Names, labels)
+		e.collectChainSize(metrics, container, labels)
+
+		switch {
+		case utils.IsSupportedExtendedInfoNetwork(network):
+			e.collectNodeRPC(ctx, metrics, container.Target(), labels)
+		case strings.HasPrefix(network, "ord"):
+			e.collectOrd(ctx, metrics, container.Target(), labels)
+		case network == "ipfs":
+			e.collectKubo(ctx, metrics, container.Target(), labels)
+		}
+	}
+
+	return metrics, nil
+}
+
+func (e *metricsExporter) collectContainerStats(ctx context.Context, metrics *metricSet, containerName string, labels []string) {
+	statsCtx, cancel := context.WithTimeout(ctx, metricsHTTPTimeout)
+	defer cancel()
+
+	stats, err := docker.GetContainerResourceStats(statsCtx, containerName)
+	if err != nil {
+		logger.LogError("Failed to get container stats for " + containerName + ": " + err.Error())
+		return
+	}
+
+	metrics.add("nodevin_container_cpu_usage_seconds_total", stats.CPUUsageSeconds, labels...)
+	metrics.add("nodevin_container_cpu_percent", stats.CPUPercent, labels...)
+	metrics.add("nodevin_container_memory_usage_bytes", float64(stats.MemoryUsage), labels...)
+	metrics.add("nodevin_container_memory_limit_bytes", float64(stats.MemoryLimit), labels...)
+	metrics.add("nodevin_container_restarts", float64(stats.RestartCount), labels...)
+}
+
+// Sizes the directories the container was started with (its nodevin.data-dir label), containers
+// from before nodevin labelled its services can only be using the network's default directory
+func (e *metricsExporter) collectChainSize(metrics *metricSet, container managedContainer, labels []string) {
+	var locations []utils.DataLocation
+	var err error
+	if container.DataDir != "" {
+		locations, err = utils.GetDataLocationsAt(container.DataDir)
+	} else {
+		locations, err = utils.GetNetworkDataLocations(container.Network)
+	}
+	if err != nil && len(locations) == 0 {
+		return
+	}
+
+	for _, location := range locations {
+		size, err := getDirectorySize(location.Path)
+		if err != nil {
+			continue
+		}
+		metrics.add("nodevin_chain_data_size_bytes", float64(size), append(labels, "location", location.Label, "directory", location.Path)...)
+	}
+}
+
+// Uses the same RPC endpoint and credentials as `nodevin info`
+// target is the container's network, qualified with its instance if it has one (ex: bitcoin/pruned)
+func (e *metricsExporter) collectNodeRPC(ctx context.Context, metrics *metricSet, target string, labels []string) {
+
+	ctx, cancel := context.WithTimeout(ctx, nodeRPCTimeout)
+	defer cancel()
+
+	var blockchainInfo struct {
+		Blocks               int     `json:"blocks"`
+		Headers              int     `json:"headers"`
+		VerificationProgress float64 `json:"verificationprogress"`
+		InitialBlockDownload *bool   `json:"initialblockdownload"`
+	}
+	if err := callNodeRPC(ctx, target, "getblockchaininfo", &blockchainInfo); err != nil {
+		metrics.add("nodevin_node_up", 0, labels...)
+		return
+	}
+	metrics.add("nodevin_node_up", 1, labels...)
+	metrics.add("nodevin_block_height", float64(blockchainInfo.Blocks), labels...)
+	metrics.add("nodevin_headers", float64(blockchainInfo.Headers), labels...)
+	metrics.add("nodevin_verification_progress", blockchainInfo.VerificationProgress, labels...)
+	if blockchainInfo.InitialBlockDownload != nil {
+		metrics.add("nodevin_initial_block_download", boolToFloat(*blockchainInfo.InitialBlockDownload), labels...)
+	}
+
+	// Older daemons (ex: dogecoin 1.14) only report the total connection count
+	var networkInfo struct {
+		Connections    int  `json:"connections"`
+		ConnectionsIn  *int `json:"connections_in"`
+		ConnectionsOut *int `json:"connections_out"`
+	}
+	if err := callNodeRPC(ctx, target, "getnetworkinfo", &networkInfo); err == nil {
+		metrics.add("nodevin_peers_connected", float64(networkInfo.Connections), labels...)
+		if networkInfo.ConnectionsIn != nil && networkInfo.ConnectionsOut != nil {
+			metrics.add("nodevin_peers", float64(*networkInfo.ConnectionsIn), append(labels, "direction", "inbound")...)
+			metrics.add("nodevin_peers", float64(*networkInfo.ConnectionsOut), append(labels, "direction", "outbound")...)
+		}
+	}
+
+	var mempoolInfo struct {
+		Size  int `json:"size"`
+		Bytes int `json:"bytes"`
+	}
+	if err := callNodeRPC(ctx, target, "getmempoolinfo", &mempoolInfo); err == nil {
+		metrics.add("nodevin_mempool_transactions", float64(mempoolInfo.Size), labels...)
+		metrics.add("nodevin_mempool_bytes", float64(mempoolInfo.Bytes), labels...)
+	}
+}
+
+func (e *metricsExporter) collectOrd(ctx context.Context, metrics *metricSet, target string, labels []string) {
+	url := getLocalEndpointByNetwork(target) + "/blockheight"
+
+	body, err := e.get(ctx, http.MethodGet, url)
+	if err != nil {
+		metrics.add("nodevin_node_up", 0, labels...)
+		return
+	}
+
+	height, err := strconv.ParseFloat(strings.TrimSpace(string(body)), 64)
+	if err != nil {
+		metrics.add("nodevin_node_up", 0, labels...)
+		return
+	}
+
+	metrics.add("nodevin_node_up", 1, labels...)
+	metrics.add("nodevin_ord_index_height", height, labels...)
+}
+
+// Kubo only accepts POST on its RPC API
+func (e *metricsExporter) collectKubo(ctx context.Context, metrics *metricSet, target string, labels []string) {
+	endpoint := getLocalEndpointByNetwork(target) + "/api/v0"
+
+	var peers struct {
+		Peers []json.RawMessage `json:"Peers"`
+	}
+	body, err := e.get(ctx, http.MethodPost, endpoint+"/swarm/peers")
+	if err != nil || json.Unmarshal(body, &peers) != nil {
+		metrics.add("nodevin_node_up", 0, labels...)
+		return
+	}
+	metrics.add("nodevin_node_up", 1, labels...)
+	metrics.add("nodevin_ipfs_peers", float64(len(peers.Peers)), labels...)
+
+	var repoStat struct {
+		RepoSize   uint64 `json:"RepoSize"`
+		StorageMax uint64 `json:"StorageMax"`
+	}
+	body, err = e.get(ctx, http.MethodPost, endpoint+"/repo/stat?size-only=true")
+	if err == nil && json.Unmarshal(body, &repoStat) == nil {
+		metrics.add("nodevin_ipfs_repo_size_bytes", float64(repoStat.RepoSize), labels...)
+		metrics.add("nodevin_ipfs_repo_storage_max_bytes", float64(repoStat.StorageMax), labels...)
+	}
+}
+
+func (e *metricsExporter) get(ctx context.Context, method string, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("request failed with status code %d", resp.StatusCode)
+	}
+
+	return io.ReadAll(resp.Body)
+}
+
+func boolToFloat(value bool) float64 {
+	if value {
+		return 1
+	}
+	return 0
+}
+
+func init() {
+	addRPCFlags(metricsServeCmd.Flags())
+	metricsServeCmd.Flags().String("listen", ":9456", "Address to serve Prometheus metrics on")
+	viper.BindPFlag("metrics-listen", metricsServeCmd.Flags().Lookup("listen"))
+
+	metricsCmd.AddCommand(metricsServeCmd)
+}
@@ -0,0 +1,199 @@
+/*
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 The Nodevin Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package nodes
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/fiftysixcrypto/nodevin/internal/config"
+	"github.com/fiftysixcrypto/nodevin/internal/utils"
+	"github.com/fiftysixcrypto/nodevin/pkg/docker/compose"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Flags only some networks' start commands take, besides those of their add-ons
+var networkStartFlags = map[string]func(*pflag.FlagSet){
+	"bitcoin": func(flags *pflag.FlagSet) {
+		addBlocksDirFlag(flags)
+		addIndexDirFlag(flags)
+		addElectrumFlags(flags)
+	},
+	"litecoin": func(flags *pflag.FlagSet) {
+		addBlocksDirFlag(flags)
+		addIndexDirFlag(flags)
+		addElectrumFlags(flags)
+	},
+	"ipfs": func(flags *pflag.FlagSet) {
+		addClusterFlags(flags, "cluster")
+	},
+	"ord":          addIndexDirFlag,
+	"ord-litecoin": addIndexDirFlag,
+}
+
+// Commands only a network's tree has, ex: nodevin ipfs support
+var networkOnlyCmds = map[string][]*cobra.Command{
+	"ipfs": {ipfsSupportCmd},
+}
+
+// Start command of each network, ex: nodevin bitcoin start
+var networkStartCmds = map[string]*cobra.Command{}
+
+func init() {
+	for _, network := range strings.Split(utils.GetCommandSupportedNetworks(), ", ") {
+		networkStartCmds[network] = newNetworkStartCmd(network)
+	}
+}
+
+// Returns a command tree per network (ex: nodevin bitcoin start), with the commands
+// that take a network argument (ex: nodevin logs bitcoin) as subcommands too
+func NetworkCmds() []*cobra.Command {
+	var cmds []*cobra.Command
+	for _, network := range strings.Split(utils.GetCommandSupportedNetworks(), ", ") {
+		cmd := &cobra.Command{
+			Use:   network,
+			Short: "Start and manage " + network + " nodes",
+		}
+
+		cmd.AddCommand(networkStartCmds[network])
+		for _, base := range []*cobra.Command{stopNodeCmd, logsCmd, infoCmd, shellCmd, requestCmd} {
+			cmd.AddCommand(newNetworkSubcommand(network, base))
+		}
+		cmd.AddCommand(networkOnlyCmds[network]...)
+
+		cmds = append(cmds, cmd)
+	}
+	return cmds
+}
+
+// Returns `nodevin <network> start`, with only the flags the network takes
+func newNetworkStartCmd(network string) *cobra.Command {
+	article := "a"
+	if strings.ContainsAny(network[:1], "aeiou") {
+		article = "an"
+	}
+
+	cmd := &cobra.Command{
+		Use:   "start",
+		Short: "Start " + article + " " + network + " node",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return startNode([]string{network})
+		},
+	}
+
+	flags := cmd.Flags()
+	addContainerFlags(flags)
+	addResourceFlags(flags)
+	addDeployFlags(flags)
+	addSetFlag(flags)
+	if utils.IsSupportedExtendedInfoNetwork(network) {
+		addRPCFlags(flags)
+		addCookieAuthFlag(flags)
+	}
+	if addFlags, exists := networkStartFlags[network]; exists {
+		addFlags(flags)
+	}
+
+	// Add-ons are named after what they are to the network (ex: --cluster for ipfs),
+	// their setting names are accepted too (ex: --ipfs-cluster)
+	if addons := compose.GetNetworkAddons(network); len(addons) > 0 {
+		addAddonFlag(flags)
+		for _, addon := range addons {
+			addSidecarFlags(flags, addon.Name, addon)
+		}
+	}
+
+	// Accept the setting names of renamed flags, ex: --ipfs-cluster-secret for --cluster-secret
+	aliases := map[string]string{}
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if setting, exists := flag.Annotations[config.SettingAnnotation]; exists && setting[0] != flag.Name {
+			aliases[setting[0]] = flag.Name
+		}
+	})
+	flags.SetNormalizeFunc(func(f *pflag.FlagSet, name string) pflag.NormalizedName {
+		if alias, exists := aliases[name]; exists {
+			name = alias
+		}
+		return pflag.NormalizedName(name)
+	})
+
+	return cmd
+}
+
+// Returns a command that takes a network argument (ex: nodevin logs bitcoin) as a
+// subcommand of the network's tree (ex: nodevin bitcoin logs), sharing its flags
+func newNetworkSubcommand(network string, base *cobra.Command) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   base.Name(),
+		Short: base.Short,
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			args = []string{network}
+			if base.RunE != nil {
+				return base.RunE(cmd, args)
+			}
+			base.Run(cmd, args)
+			return nil
+		},
+	}
+
+	rpc := utils.IsSupportedExtendedInfoNetwork(network)
+	base.LocalFlags().VisitAll(func(flag *pflag.Flag) {
+		if !rpc && slices.Contains(rpcFlagNames, flag.Name) {
+			return
+		}
+		cmd.Flags().AddFlag(flag)
+	})
+	return cmd
+}
+
+// Adds --addon and every add-on's flags under their setting names, for commands that take any network
+func addAllAddonFlags(flags *pflag.FlagSet) {
+	addAddonFlag(flags)
+	for _, addon := range compose.GetAddons() {
+		addSidecarFlags(flags, addon.Service, addon)
+	}
+}
+
+// Sets the flags given to `nodevin start <network>` on the network's start command,
+// rejecting those the network does not take instead of ignoring them
+func forwardStartFlags(from *cobra.Command, to *cobra.Command, network string) error {
+	var err error
+	from.LocalFlags().VisitAll(func(flag *pflag.Flag) {
+		if err != nil || !flag.Changed {
+			return
+		}
+		target := to.Flags().Lookup(flag.Name)
+		if target == nil {
+			err = fmt.Errorf("--%s is not supported by %s, see `%s %s start --help`", flag.Name, network, utils.GetNodevinExecutable(), network)
+			return
+		}
+		// Entries of --set may contain commas, so lists are copied as they are
+		if slice, ok := flag.Value.(pflag.SliceValue); ok {
+			err = target.Value.(pflag.SliceValue).Replace(slice.GetSlice())
+			target.Changed = true
+			return
+		}
+		err = to.Flags().Set(flag.Name, flagArgValue(flag))
+	})
+	return err
+}
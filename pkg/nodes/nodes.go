@@ -19,15 +19,28 @@
 package nodes
 
 var (
-	RequestCmd     = requestCmd
-	ShellCmd       = shellCmd
-	StartNodeCmd   = startNodeCmd
-	StopNodeCmd    = stopNodeCmd
-	DeleteCmd      = deleteCmd
-	CleanupCmd     = cleanupCmd
-	LogsCmd        = logsCmd
-	InfoCmd        = infoCmd
-	ListCmd        = listCmd
-	ViewCmd        = viewCmd
-	IpfsSupportCmd = ipfsSupportCmd
+	RequestCmd   = requestCmd
+	ShellCmd     = shellCmd
+	StartNodeCmd = startNodeCmd
+	StopNodeCmd  = stopNodeCmd
+	DeleteCmd    = deleteCmd
+	CleanupCmd   = cleanupCmd
+	LogsCmd      = logsCmd
+	InfoCmd      = infoCmd
+	ListCmd      = listCmd
+	ViewCmd      = viewCmd
+	MetricsCmd   = metricsCmd
+	LockCmd      = lockCmd
+	VersionsCmd  = versionsCmd
+	UpgradeCmd   = upgradeCmd
+	DowngradeCmd = downgradeCmd
+	BundleCmd    = bundleCmd
+	UpCmd        = upCmd
+	DownCmd      = downCmd
+	StatusCmd    = statusCmd
+	LnCmd        = lnCmd
+
+	UpdateDockerCmd = updateDockerCmd
+	UpdatePolicyCmd = updatePolicyCmd
+	UpdateWatchCmd  = updateWatchCmd
 )
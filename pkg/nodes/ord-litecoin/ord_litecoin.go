@@ -25,9 +25,7 @@ import (
 
 	"github.com/fiftysixcrypto/nodevin/internal/logger"
 	"github.com/fiftysixcrypto/nodevin/internal/utils"
-	"github.com/fiftysixcrypto/nodevin/pkg/docker"
 	"github.com/fiftysixcrypto/nodevin/pkg/docker/compose"
-	"github.com/spf13/viper"
 )
 
 func CreateOrdLitecoinComposeFile(cwd string) (string, error) {
@@ -39,7 +37,7 @@ func CreateOrdLitecoinComposeFile(cwd string) (string, error) {
 		return "", err
 	}
 
-	fmt.Printf("WARNING: It isn't reccomended to start ord-litecoin individually. Most cases would require starting ord-litecoin alongside Litecoin with command `%s start litecoin --ord-litecoin`. You may run into unintentional errors or require additional configuration.", utils.GetNodevinExecutable())
+	fmt.Printf("WARNING: It isn't reccomended to start ord-litecoin individually. Most cases would require starting ord-litecoin alongside Litecoin with command `%s litecoin start --ord`. You may run into unintentional errors or require additional configuration.", utils.GetNodevinExecutable())
 
 	if utils.CheckIfTestnetOrTestnetNetworkFlag() {
 		network = "ord-litecoin-testnet"
@@ -47,18 +45,11 @@ func CreateOrdLitecoinComposeFile(cwd string) (string, error) {
 		network = "ord=litecoin"
 	}
 
-	ordLitecoinBaseComposeConfig, err := compose.GetOrdLitecoinNetworkComposeConfig(network)
+	ordLitecoinBaseComposeConfig, err := compose.GetAddonComposeConfig("ord-litecoin", network)
 	if err != nil {
 		return "", err
 	}
 
-	// Pull the ord Docker image
-	image := viper.GetString("ord-litecoin-image") + ":" + viper.GetString("ord-litecoin-version")
-	if err := docker.PullImage(image); err != nil {
-		logger.LogError("Failed to pull Docker image: " + err.Error())
-		return "", err
-	}
-
 	composeFilePath, err := compose.CreateComposeFile(
 		ordLitecoinBaseComposeConfig.ContainerName,
 		ordLitecoinBaseComposeConfig,
@@ -25,9 +25,7 @@ import (
 
 	"github.com/fiftysixcrypto/nodevin/internal/logger"
 	"github.com/fiftysixcrypto/nodevin/internal/utils"
-	"github.com/fiftysixcrypto/nodevin/pkg/docker"
 	"github.com/fiftysixcrypto/nodevin/pkg/docker/compose"
-	"github.com/spf13/viper"
 )
 
 func CreateOrdComposeFile(cwd string) (string, error) {
@@ -39,7 +37,7 @@ func CreateOrdComposeFile(cwd string) (string, error) {
 		return "", err
 	}
 
-	fmt.Printf("WARNING: It isn't reccomended to start ord individually. Most cases would require starting ord alongside Bitcoin with command `%s start bitcoin --ord`. You may run into unintentional errors or require additional configuration.", utils.GetNodevinExecutable())
+	fmt.Printf("WARNING: It isn't reccomended to start ord individually. Most cases would require starting ord alongside Bitcoin with command `%s bitcoin start --ord`. You may run into unintentional errors or require additional configuration.", utils.GetNodevinExecutable())
 
 	if utils.CheckIfTestnetOrTestnetNetworkFlag() {
 		network = "ord-testnet"
@@ -47,18 +45,11 @@ func CreateOrdComposeFile(cwd string) (string, error) {
 		network = "ord"
 	}
 
-	ordBaseComposeConfig, err := compose.GetOrdNetworkComposeConfig(network)
+	ordBaseComposeConfig, err := compose.GetAddonComposeConfig("ord", network)
 	if err != nil {
 		return "", err
 	}
 
-	// Pull the ord Docker image
-	image := viper.GetString("ord-image") + ":" + viper.GetString("ord-version")
-	if err := docker.PullImage(image); err != nil {
-		logger.LogError("Failed to pull Docker image: " + err.Error())
-		return "", err
-	}
-
 	composeFilePath, err := compose.CreateComposeFile(
 		ordBaseComposeConfig.ContainerName,
 		ordBaseComposeConfig,
@@ -20,13 +20,13 @@ package nodes
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
 
-	"github.com/fiftysixcrypto/nodevin/internal/logger"
 	"github.com/fiftysixcrypto/nodevin/internal/utils"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -36,8 +36,11 @@ var requestCmd = &cobra.Command{
 	Use:   "request [network]",
 	Short: "Make an RPC request to a node",
 	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		network := args[0]
+	RunE: func(cmd *cobra.Command, args []string) error {
+		network, err := resolveNetworkArg(args[0])
+		if err != nil {
+			return err
+		}
 
 		method := viper.GetString("method")
 		params := viper.GetString("params")
@@ -48,24 +51,28 @@ var requestCmd = &cobra.Command{
 		pass := viper.GetString("rpc-pass")
 
 		if method == "" {
-			logger.LogError("HTTP method is required.")
 			printUsageAndExample()
-			return
+			return fmt.Errorf("HTTP method is required")
 		}
 
-		if endpoint == "" {
-			endpoint = "http://127.0.0.1"
-		}
+		// Without an explicit endpoint, use the port the node (or instance) was published on
+		url := getLocalEndpointByNetwork(utils.JoinNetworkInstance(network, utils.GetInstance()))
+		if endpoint != "" || port != 0 {
+			if endpoint == "" {
+				endpoint = "http://127.0.0.1"
+			}
 
-		if port == 0 {
-			port = utils.NetworkDefaultRPCPorts()[network]
-		}
+			if port == 0 {
+				port = utils.NetworkDefaultRPCPorts()[network]
+			}
 
-		url := fmt.Sprintf("%s:%d", endpoint, port)
+			url = fmt.Sprintf("%s:%d", endpoint, port)
+		}
 
 		if _, err := makeRequest(network, url, method, params, headers, user, pass); err != nil {
-			logger.LogError("Failed to make request: " + err.Error())
+			return fmt.Errorf("failed to make request: %w", err)
 		}
+		return nil
 	},
 }
 
@@ -76,6 +83,10 @@ func printUsageAndExample() {
 }
 
 func makeRequest(network, url, method, params, headers, user, pass string) ([]byte, error) {
+	return makeRequestWithContext(context.Background(), network, url, method, params, headers, user, pass)
+}
+
+func makeRequestWithContext(ctx context.Context, network, url, method, params, headers, user, pass string) ([]byte, error) {
 	jsonData := map[string]interface{}{
 		"jsonrpc": "1.0",
 		"id":      "nodevin",
@@ -94,7 +105,7 @@ func makeRequest(network, url, method, params, headers, user, pass string) ([]by
 	jsonData["params"] = jsonParams
 
 	jsonValue, _ := json.Marshal(jsonData)
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonValue))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonValue))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -133,6 +144,7 @@ func makeRequest(network, url, method, params, headers, user, pass string) ([]by
 }
 
 func init() {
+	addRPCFlags(requestCmd.Flags())
 	requestCmd.Flags().StringP("method", "m", "", "HTTP method to use for the request")
 	requestCmd.Flags().StringP("params", "p", "", "JSON data to send in the request body")
 	requestCmd.Flags().StringP("header", "H", "", "Optional extra headers")
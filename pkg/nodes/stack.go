@@ -0,0 +1,621 @@
+/*
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 The Nodevin Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package nodes
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/fiftysixcrypto/nodevin/internal/logger"
+	"github.com/fiftysixcrypto/nodevin/internal/output"
+	"github.com/fiftysixcrypto/nodevin/internal/stackfile"
+	"github.com/fiftysixcrypto/nodevin/internal/state"
+	"github.com/fiftysixcrypto/nodevin/internal/utils"
+	"github.com/fiftysixcrypto/nodevin/pkg/docker"
+	"github.com/fiftysixcrypto/nodevin/pkg/docker/compose"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// What `nodevin up` does about a deployment
+const (
+	stackActionNone     = "none"
+	stackActionStart    = "start"
+	stackActionRecreate = "recreate"
+	stackActionUpdate   = "update"
+	stackActionStop     = "stop"
+)
+
+var (
+	stackFilePath string
+	stackDryRun   bool
+)
+
+var upCmd = &cobra.Command{
+	Use:   "up",
+	Short: "Start, recreate or stop deployments to match nodevin.yaml",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return stackUp(inheritedStartFlags(cmd))
+	},
+}
+
+var downCmd = &cobra.Command{
+	Use:   "down",
+	Short: "Stop every deployment declared in nodevin.yaml",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return stackDown()
+	},
+}
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show how the running deployments differ from nodevin.yaml",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return stackStatus()
+	},
+}
+
+// A node of a stack file with the names it is deployed under
+type stackNode struct {
+	stackfile.Node
+	// State name of the deployment, ex: bitcoin-testnet/pruned
+	Name            string
+	SoftwareNetwork string
+}
+
+// StackChange is a deployment of `nodevin status` in json or yaml mode: the action
+// `nodevin up` takes for it and why
+type StackChange struct {
+	Deployment string   `json:"deployment" yaml:"deployment"`
+	Action     string   `json:"action" yaml:"action"`
+	Reasons    []string `json:"reasons,omitempty" yaml:"reasons,omitempty"`
+
+	node       *stackNode
+	deployment state.Deployment
+}
+
+// StackReport is the output of `nodevin status` in json or yaml mode
+type StackReport struct {
+	File        string        `json:"file" yaml:"file"`
+	Deployments []StackChange `json:"deployments" yaml:"deployments"`
+}
+
+// Reads the stack file and the deployments it declares
+func loadStack() (string, []stackNode, error) {
+	path, err := stackfile.Path(stackFilePath)
+	if err != nil {
+		return "", nil, err
+	}
+
+	stack, err := stackfile.Load(path)
+	if err != nil {
+		return "", nil, err
+	}
+
+	nodes, err := resolveStackNodes(stack, filepath.Dir(path))
+	if err != nil {
+		return "", nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return path, nodes, nil
+}
+
+// Checks the nodes of a stack file and names their deployments. Relative data
+// directories are resolved against the directory of the stack file.
+func resolveStackNodes(stack *stackfile.Stack, dir string) ([]stackNode, error) {
+	var nodes []stackNode
+	names := map[string]bool{}
+
+	for _, node := range stack.Nodes {
+		if _, exists := utils.GetNetworkImageRepository(node.Network); !exists {
+			return nil, fmt.Errorf("unsupported network %s, nodevin supports: %s", node.Network, utils.GetCommandSupportedNetworks())
+		}
+		if err := utils.ValidateInstanceName(node.Instance); err != nil {
+			return nil, err
+		}
+
+		softwareNetwork := node.Network
+		if node.GetVariant() == stackfile.VariantTestnet {
+			if _, exists := utils.GetDefaultLocalMappedContainerName(node.Network + "-testnet"); !exists {
+				return nil, fmt.Errorf("%s has no testnet variant", node.Network)
+			}
+			softwareNetwork = node.Network + "-testnet"
+		}
+
+		// Sidecars are add-ons, named by their service from here on (ex: ord-litecoin for ord)
+		sidecars := make([]stackfile.Sidecar, 0, len(node.Sidecars))
+		for _, sidecar := range node.Sidecars {
+			addon, supported := compose.FindAddon(node.Network, sidecar.Name)
+			if !supported {
+				return nil, fmt.Errorf("%s cannot run sidecar %s (supported: %s)", node.Network, sidecar.Name, valueOrDash(strings.Join(compose.GetNetworkAddonNames(node.Network), ", ")))
+			}
+			sidecar.Name = addon.Service
+			sidecars = append(sidecars, sidecar)
+		}
+		node.Sidecars = sidecars
+
+		if node.UpdatePolicy != "" {
+			if err := validateUpdatePolicy(node.UpdatePolicy); err != nil {
+				return nil, err
+			}
+		}
+		if node.MaintenanceWindow != "" {
+			if _, err := parseMaintenanceWindow(node.MaintenanceWindow); err != nil {
+				return nil, err
+			}
+		}
+
+		if node.DataDir != "" && !filepath.IsAbs(node.DataDir) {
+			node.DataDir = filepath.Join(dir, node.DataDir)
+		}
+
+		name := utils.JoinNetworkInstance(softwareNetwork, node.Instance)
+		if names[name] {
+			return nil, fmt.Errorf("%s is declared more than once", name)
+		}
+		names[name] = true
+
+		nodes = append(nodes, stackNode{Node: node, Name: name, SoftwareNetwork: softwareNetwork})
+	}
+	return nodes, nil
+}
+
+// Compares the declared deployments with the recorded ones. Running deployments that were started
+// from this stack file but are no longer declared in it are stopped.
+func planStack(path string, nodes []stackNode) ([]StackChange, error) {
+	deployments, err := state.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read nodevin state: %w", err)
+	}
+
+	recorded := map[string]state.Deployment{}
+	for _, deployment := range deployments {
+		recorded[deployment.Name] = deployment
+	}
+
+	var changes []StackChange
+	declared := map[string]bool{}
+	for i := range nodes {
+		node := &nodes[i]
+		declared[node.Name] = true
+
+		deployment, exists := recorded[node.Name]
+		change := diffStackNode(node, deployment, exists)
+		change.node = node
+		change.deployment = deployment
+		changes = append(changes, change)
+	}
+
+	for _, deployment := range deployments {
+		if declared[deployment.Name] || deployment.Stack != path || deployment.Status != state.StatusRunning {
+			continue
+		}
+		changes = append(changes, StackChange{
+			Deployment: deployment.Name,
+			Action:     stackActionStop,
+			Reasons:    []string{"no longer declared in " + filepath.Base(path)},
+			deployment: deployment,
+		})
+	}
+	return changes, nil
+}
+
+// Lists how a recorded deployment differs from its declaration. Settings the stack
+// file leaves out are not compared, so defaults chosen at start don't count as drift.
+func diffStackNode(node *stackNode, deployment state.Deployment, exists bool) StackChange {
+	change := StackChange{Deployment: node.Name, Action: stackActionNone}
+	if !exists {
+		change.Action = stackActionStart
+		change.Reasons = []string{"not deployed"}
+		return change
+	}
+	if deployment.Status != state.StatusRunning {
+		change.Action = stackActionStart
+		change.Reasons = []string{"stopped"}
+		return change
+	}
+
+	var reasons []string
+	nodeService, _ := deploymentNodeService(deployment)
+
+	repository := node.Image
+	if repository == "" {
+		repository, _ = utils.GetNetworkImageRepository(node.Network)
+	}
+	if image := repository + ":" + tagOrLatest(node.Version); !strings.Contains(nodeService.Image, "@") && nodeService.Image != image {
+		reasons = append(reasons, fmt.Sprintf("runs %s, declared %s", nodeService.Image, image))
+	}
+
+	sidecars := map[string]state.Service{}
+	for _, service := range deployment.Services {
+		// Companions (ex: mempool-db) are declared by their add-on
+		if service.Role == docker.RoleSidecar && !compose.IsAddonCompanion(service.Network) {
+			name, _ := utils.SplitNetworkVariant(service.Network)
+			sidecars[name] = service
+		}
+	}
+	for _, sidecar := range node.Sidecars {
+		service, running := sidecars[sidecar.Name]
+		delete(sidecars, sidecar.Name)
+		if !running {
+			reasons = append(reasons, "sidecar "+sidecar.Name+" is not deployed")
+			continue
+		}
+
+		addon, _ := compose.FindAddon(node.Network, sidecar.Name)
+		repository, version := sidecar.Image, sidecar.Version
+		if repository == "" {
+			repository = addon.Repository()
+		}
+		if version == "" {
+			version = addon.DefaultVersion()
+		}
+		if image := repository + ":" + version; !strings.Contains(service.Image, "@") && service.Image != image {
+			reasons = append(reasons, fmt.Sprintf("sidecar %s runs %s, declared %s", sidecar.Name, service.Image, image))
+		}
+	}
+	for name := range sidecars {
+		reasons = append(reasons, "sidecar "+name+" is not declared")
+	}
+
+	if node.DataDir != "" {
+		if dataDir := filepath.Join(node.DataDir, ".nodevin", "data"); filepath.Clean(deployment.DataDir) != dataDir {
+			reasons = append(reasons, fmt.Sprintf("data directory is %s, declared %s", deployment.DataDir, dataDir))
+		}
+	}
+
+	composeFile, err := compose.ReadComposeFile(deployment.ComposeFile)
+	if err != nil {
+		reasons = append(reasons, err.Error())
+	} else if service, exists := composeFile.Services[nodeService.Name]; exists {
+		reasons = append(reasons, diffStackResources(node.Resources, service.Deploy)...)
+
+		if len(node.Ports) > 0 {
+			var declaredPorts []string
+			for _, port := range node.Ports {
+				declaredPorts = append(declaredPorts, compose.OffsetHostPort(port, deployment.PortOffset))
+			}
+			if !sameStrings(declaredPorts, service.Ports) {
+				reasons = append(reasons, fmt.Sprintf("publishes %s, declared %s", strings.Join(service.Ports, ","), strings.Join(declaredPorts, ",")))
+			}
+		}
+	}
+
+	if len(reasons) > 0 {
+		change.Action = stackActionRecreate
+		change.Reasons = reasons
+		return change
+	}
+
+	// Containers that exited are brought back by starting the deployment again
+	for _, service := range deployment.Services {
+		ctx, cancel := context.WithTimeout(context.Background(), dockerInspectTimeout)
+		containerState, err := docker.GetContainerState(ctx, service.ContainerName)
+		cancel()
+		if err != nil || !containerState.Running {
+			reasons = append(reasons, service.ContainerName+" is not running")
+		}
+	}
+	if len(reasons) > 0 {
+		change.Action = stackActionStart
+		change.Reasons = reasons
+		return change
+	}
+
+	// Update settings only live in the nodevin state, changing them needs no restart
+	if node.UpdatePolicy != "" && node.UpdatePolicy != deploymentUpdatePolicy(deployment.UpdatePolicy) {
+		reasons = append(reasons, fmt.Sprintf("update policy is %s, declared %s", deploymentUpdatePolicy(deployment.UpdatePolicy), node.UpdatePolicy))
+	}
+	if node.MaintenanceWindow != "" && node.MaintenanceWindow != deployment.MaintenanceWindow {
+		reasons = append(reasons, fmt.Sprintf("maintenance window is %s, declared %s", valueOrDash(deployment.MaintenanceWindow), node.MaintenanceWindow))
+	}
+	if len(reasons) > 0 {
+		change.Action = stackActionUpdate
+		change.Reasons = reasons
+	}
+	return change
+}
+
+func diffStackResources(declared stackfile.Resources, deploy *compose.Deploy) []string {
+	var current compose.Resources
+	if deploy != nil {
+		current = deploy.Resources
+	}
+
+	var reasons []string
+	compare := func(name string, declared string, current string) {
+		if declared != "" && declared != current {
+			reasons = append(reasons, fmt.Sprintf("%s is %s, declared %s", name, valueOrDash(current), declared))
+		}
+	}
+	compare("cpu limit", declared.CPULimit, current.Limits.CPUs)
+	compare("memory limit", declared.MemLimit, current.Limits.Memory)
+	compare("cpu reservation", declared.CPUReservation, current.Reservations.CPUs)
+	compare("memory reservation", declared.MemReservation, current.Reservations.Memory)
+	return reasons
+}
+
+func tagOrLatest(tag string) string {
+	if tag == "" {
+		return "latest"
+	}
+	return tag
+}
+
+// Reports whether two lists hold the same values in any order
+func sameStrings(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a, b = append([]string{}, a...), append([]string{}, b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func stackUp(inherited []string) error {
+	path, nodes, err := loadStack()
+	if err != nil {
+		return err
+	}
+
+	changes, err := planStack(path, nodes)
+	if err != nil {
+		return err
+	}
+
+	displayStackChanges(changes)
+	if stackDryRun {
+		return nil
+	}
+
+	failed := 0
+	for _, change := range changes {
+		if err := applyStackChange(path, change, inherited); err != nil {
+			logger.LogError(fmt.Sprintf("Failed to %s %s: %v", change.Action, change.Deployment, err))
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d deployments could not be brought in line with %s", failed, len(changes), path)
+	}
+	logger.LogInfo("Every deployment matches " + path)
+	return nil
+}
+
+func applyStackChange(path string, change StackChange, inherited []string) error {
+	switch change.Action {
+	case stackActionStop:
+		stopStackDeployment(change.deployment)
+		return nil
+	case stackActionRecreate:
+		stopStackDeployment(change.deployment)
+		fallthrough
+	case stackActionStart:
+		logger.LogInfo(fmt.Sprintf("Starting %s...", change.Deployment))
+		if err := runStackStart(*change.node, inherited); err != nil {
+			return err
+		}
+	}
+
+	// Adopt the deployment, so it is stopped by up once it is removed from the stack file
+	_, err := state.Update(change.Deployment, func(deployment *state.Deployment) {
+		deployment.Stack = path
+		if change.Action == stackActionUpdate {
+			if change.node.UpdatePolicy != "" {
+				deployment.UpdatePolicy = change.node.UpdatePolicy
+			}
+			if change.node.MaintenanceWindow != "" {
+				deployment.MaintenanceWindow = change.node.MaintenanceWindow
+			}
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update nodevin state: %w", err)
+	}
+	if change.Action == stackActionUpdate {
+		logger.LogInfo("Updated the update settings of " + change.Deployment)
+	}
+	return nil
+}
+
+func stopStackDeployment(deployment state.Deployment) {
+	stopDeploymentGracefully(deployment, deployment.ComposeFile)
+	markDeploymentStopped(deployment.Name)
+}
+
+// Starts a declared node with `nodevin <network> start` in its own process, so the flags of
+// one node never leak into the next
+func runStackStart(node stackNode, inherited []string) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to find the nodevin executable: %w", err)
+	}
+
+	args := stackStartArgs(node)
+	for _, flag := range inherited {
+		name, _, _ := strings.Cut(strings.TrimPrefix(flag, "--"), "=")
+		if !hasFlag(args, name) {
+			args = append(args, flag)
+		}
+	}
+
+	cmd := exec.Command(executable, args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// Returns the `nodevin <network> start` arguments that deploy a declared node
+func stackStartArgs(node stackNode) []string {
+	args := []string{node.Network, "start"}
+	add := func(name string, value string) {
+		if value != "" {
+			args = append(args, "--"+name+"="+value)
+		}
+	}
+
+	if node.GetVariant() == stackfile.VariantTestnet {
+		args = append(args, "--testnet")
+	}
+	add("instance", node.Instance)
+	add("image", node.Image)
+	add("version", node.Version)
+
+	for _, sidecar := range node.Sidecars {
+		add("addon", sidecar.Name)
+		add(sidecar.Name+"-image", sidecar.Image)
+		add(sidecar.Name+"-version", sidecar.Version)
+	}
+
+	add("cpu-limit", node.Resources.CPULimit)
+	add("mem-limit", node.Resources.MemLimit)
+	add("cpu-reservation", node.Resources.CPUReservation)
+	add("mem-reservation", node.Resources.MemReservation)
+	add("ports", strings.Join(node.Ports, ","))
+	add("data-dir", node.DataDir)
+	add("update-policy", node.UpdatePolicy)
+	add("maintenance-window", node.MaintenanceWindow)
+
+	var names []string
+	for name := range node.Flags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		args = append(args, "--"+strings.TrimPrefix(name, "--")+"="+node.Flags[name])
+	}
+	return args
+}
+
+func hasFlag(args []string, name string) bool {
+	for _, arg := range args {
+		if arg == "--"+name || strings.HasPrefix(arg, "--"+name+"=") {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the global flags given to up (ex: --registry), passed on to every start
+func inheritedStartFlags(cmd *cobra.Command) []string {
+	var flags []string
+	cmd.InheritedFlags().VisitAll(func(flag *pflag.Flag) {
+		if !flag.Changed {
+			return
+		}
+		flags = append(flags, "--"+flag.Name+"="+flagArgValue(flag))
+	})
+	return flags
+}
+
+func stackDown() error {
+	path, nodes, err := loadStack()
+	if err != nil {
+		return err
+	}
+
+	declared := map[string]bool{}
+	for _, node := range nodes {
+		declared[node.Name] = true
+	}
+
+	stopped := 0
+	for _, deployment := range listDeployments() {
+		if deployment.Status != state.StatusRunning || (!declared[deployment.Name] && deployment.Stack != path) {
+			continue
+		}
+		stopStackDeployment(deployment)
+		stopped++
+	}
+
+	if stopped == 0 {
+		logger.LogInfo("No running deployments declared in " + path)
+		return nil
+	}
+	logger.LogInfo(fmt.Sprintf("Stopped %d deployments declared in %s", stopped, path))
+	return nil
+}
+
+func stackStatus() error {
+	path, nodes, err := loadStack()
+	if err != nil {
+		return err
+	}
+
+	changes, err := planStack(path, nodes)
+	if err != nil {
+		return err
+	}
+
+	if output.IsStructured() {
+		return output.Print(StackReport{File: path, Deployments: changes})
+	}
+
+	fmt.Println("Stack file: " + path)
+	displayStackChanges(changes)
+	return nil
+}
+
+func displayStackChanges(changes []StackChange) {
+	if len(changes) == 0 {
+		logger.LogInfo("The stack file declares no deployments.")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', tabwriter.Debug)
+	fmt.Fprintln(w, "| DEPLOYMENT\t STATUS\t ACTION\t DETAILS")
+
+	drifted := 0
+	for _, change := range changes {
+		status, action := "in sync", "-"
+		if change.Action != stackActionNone {
+			status, action = "drifted", change.Action
+			drifted++
+		}
+		fmt.Fprintf(w, "| %s\t %s\t %s\t %s\n", change.Deployment, status, action, valueOrDash(strings.Join(change.Reasons, "; ")))
+	}
+	w.Flush()
+
+	if drifted > 0 {
+		logger.LogInfo(fmt.Sprintf("%d of %d deployments differ from the stack file, `%s up` brings them in line", drifted, len(changes), utils.GetNodevinExecutable()))
+	}
+}
+
+func init() {
+	for _, cmd := range []*cobra.Command{upCmd, downCmd, statusCmd} {
+		cmd.Flags().StringVarP(&stackFilePath, "file", "f", "", "Path of the stack file (default: nodevin.yaml in the working directory)")
+	}
+	upCmd.Flags().BoolVar(&stackDryRun, "dry-run", false, "Show what up would change without changing anything")
+	addRPCFlags(downCmd.Flags())
+}
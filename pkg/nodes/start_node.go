@@ -19,9 +19,11 @@
 package nodes
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
+	"runtime"
 
 	"github.com/fiftysixcrypto/nodevin/internal/logger"
 	"github.com/fiftysixcrypto/nodevin/internal/utils"
@@ -33,66 +35,169 @@ import (
 	"github.com/fiftysixcrypto/nodevin/pkg/nodes/litecoin"
 	"github.com/fiftysixcrypto/nodevin/pkg/nodes/ord"
 	ord_litecoin "github.com/fiftysixcrypto/nodevin/pkg/nodes/ord-litecoin"
+	"github.com/fiftysixcrypto/nodevin/pkg/preflight"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
+// Same as `nodevin <network> start`, taking the flags of every network
 var startNodeCmd = &cobra.Command{
 	Use:   "start [network]",
-	Short: "Start a blockchain node",
+	Short: "Start a blockchain node (same as nodevin <network> start)",
 	Args:  cobra.MaximumNArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		startNode(args)
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			logger.LogInfo(fmt.Sprintf("Example usage: `%s <network> start`", utils.GetNodevinExecutable()))
+			return errors.New("no network provided. Nodevin supports any of the following: " + utils.GetCommandSupportedNetworks())
+		}
+
+		network, _ := utils.SplitNetworkInstance(args[0])
+		networkStartCmd, exists := networkStartCmds[network]
+		if !exists {
+			return errors.New("unsupported blockchain network: " + network)
+		}
+
+		if err := forwardStartFlags(cmd, networkStartCmd, network); err != nil {
+			return err
+		}
+		BindCommandFlags(networkStartCmd)
+
+		return startNode(args)
 	},
 }
 
-func startNode(args []string) {
-	if len(args) == 0 {
-		logger.LogError("No network provided. Nodevin supports any of the following: " + utils.GetCommandSupportedNetworks())
-		logger.LogInfo(fmt.Sprintf("Example usage: `%s start <network>`", utils.GetNodevinExecutable()))
+func startNode(args []string) error {
+	network, err := resolveNetworkArg(args[0])
+	if err != nil {
+		return err
+	}
 
-		return
+	containerName, exists := utils.GetNetworkImageRepository(network)
+	if !exists {
+		return errors.New("unsupported blockchain network: " + network)
 	}
 
-	network := args[0]
+	if err := validateUpdateFlags(); err != nil {
+		return err
+	}
 
-	containerName, exists := utils.GetFiftysixDockerhubContainerName(network)
-	if !exists {
-		logger.LogError("Unsupported blockchain network: " + network)
-		return
+	// --network regtest would otherwise start the mainnet node
+	if variant := utils.GetNetworkVariant(); variant == "regtest" && getSoftwareNetwork(network) == network {
+		return fmt.Errorf("%s has no %s network", network, variant)
+	}
+
+	// Check the add-ons and --set before anything is pulled
+	addons, err := getStartAddons(network)
+	if err != nil {
+		return err
+	}
+	overrides, err := compose.GetServiceOverrides()
+	if err != nil {
+		return err
+	}
+	if err := compose.ValidateServiceOverrides(overrides, getStartServices(network, addons)); err != nil {
+		return err
+	}
+
+	logger.LogInfo("Starting blockchain node for network: " + utils.JoinNetworkInstance(network, utils.GetInstance()))
+
+	// Named instances publish their ports shifted so they don't collide with other instances
+	if !viper.IsSet("port-offset") {
+		portOffset, err := allocatePortOffset(network)
+		if err != nil {
+			return fmt.Errorf("failed to allocate instance ports: %w", err)
+		}
+		viper.Set("port-offset", portOffset)
+	}
+	if portOffset := viper.GetInt("port-offset"); portOffset != 0 {
+		logger.LogInfo(fmt.Sprintf("Publishing host ports with an offset of %d", portOffset))
 	}
 
-	logger.LogInfo("Starting blockchain node for network: " + network)
+	// Check disk space, filesystem, memory and CPU before pulling anything
+	if !runPreflight(network, addons) {
+		return errors.New("preflight checks failed")
+	}
 
 	// Initialize Docker client
 	if err := docker.InitDockerClient(); err != nil {
-		logger.LogError("Failed to initialize Docker client: " + err.Error())
-		return
+		return fmt.Errorf("failed to initialize Docker client: %w", err)
 	}
 
+	if customImage := viper.GetString("image"); customImage != "" {
+		containerName = customImage
+	}
 	version := viper.GetString("version")
 	if version == "" {
 		version = "latest"
 	}
 	image := containerName + ":" + version
 
-	if err := docker.PullImage(image); err != nil {
-		logger.LogError("Failed to pull Docker image: " + err.Error())
-		return
+	// Images loaded by `nodevin bundle install` are deployed by tag and never pulled
+	bundledImages, err := getBundledImages(getDeploymentName(network))
+	if err != nil {
+		return fmt.Errorf("failed to read nodevin.lock: %w", err)
+	}
+
+	// --locked deploys the digests in nodevin.lock instead of whatever the tags point to now
+	var lockedImages map[string]string
+	if len(bundledImages) > 0 {
+		lockedImages, err = verifyBundledImages(bundledImages)
+		if err != nil {
+			return err
+		}
+		if viper.GetString("update-policy") == "" {
+			viper.Set("update-policy", updatePolicyPin)
+		}
+	} else if viper.GetBool("locked") {
+		lockedImages, err = getLockedImages(getDeploymentName(network))
+		if err != nil {
+			return err
+		}
+	}
+	if lockedImage, exists := lockedImages[getSoftwareNetwork(network)]; exists {
+		image = lockedImage
+	}
+
+	// Pull the node and sidecar images together, before any compose file is written
+	if len(bundledImages) == 0 {
+		images := []string{image}
+		for sidecarNetwork, sidecarImage := range getAddonImages(addons) {
+			if lockedImage, exists := lockedImages[sidecarNetwork]; exists {
+				sidecarImage = lockedImage
+			}
+			images = append(images, sidecarImage)
+		}
+
+		if err := docker.PullImages(images); err != nil {
+			return errors.New("failed to pull Docker images, nothing was started")
+		}
 	}
 
 	// Get current working directory
 	cwd, err := os.Getwd()
 	if err != nil {
-		logger.LogError("failed to get current working directory: " + err.Error())
-		return
+		return fmt.Errorf("failed to get current working directory: %w", err)
 	}
 
 	// Create env file for chain compose
 	composeFilePath, err := createComposeFileForNetwork(network, cwd)
 	if err != nil {
-		logger.LogError("Failed to create node docker compose file: " + err.Error())
+		return fmt.Errorf("failed to create node docker compose file: %w", err)
+	}
+
+	if lockedImages != nil {
+		if err := compose.PinComposeImages(composeFilePath, lockedImages); err != nil {
+			return fmt.Errorf("failed to apply nodevin.lock: %w", err)
+		}
+		if len(bundledImages) > 0 {
+			if err := compose.SetComposePullPolicy(composeFilePath, "never"); err != nil {
+				return fmt.Errorf("failed to apply bundled images: %w", err)
+			}
+			logger.LogInfo("Deploying the images loaded from a nodevin bundle")
+		} else {
+			logger.LogInfo("Deploying the image digests locked in nodevin.lock")
+		}
 	}
 
 	// Print out warning info for chain size and snapshot sync timing
@@ -147,24 +252,15 @@ func startNode(args []string) {
 		logger.LogInfo("WARNING: Initial chain sync can take hours or days depending on your computer specs.")
 		logger.LogInfo(fmt.Sprintf("WARNING: This software requires %s amount of space. Ensure you have enough storage on disk.", utils.GetSizeDescription(int64(dataSize))))
 
-		if viper.GetBool("ord") {
-			dataSize, exists := utils.GetNetworkRequiredDataSize("ord")
-
-			if !exists {
-				logger.LogInfo("Cannot determine assumed size for ord.")
-				dataSize = 0
-			}
-
-			logger.LogInfo(fmt.Sprintf("WARNING: Ord software requires an additional %s amount of space. Ensure you have enough storage on disk for both.", utils.GetSizeDescription(int64(dataSize))))
-		} else if viper.GetBool("ord-litecoin") {
-			dataSize, exists := utils.GetNetworkRequiredDataSize("ord-litecoin")
+		for _, addon := range addons {
+			dataSize, exists := utils.GetNetworkRequiredDataSize(addon.Service)
 
 			if !exists {
-				logger.LogInfo("Cannot determine assumed size for ord-litecoin.")
+				logger.LogInfo(fmt.Sprintf("Cannot determine assumed size for %s.", addon.Service))
 				dataSize = 0
 			}
 
-			logger.LogInfo(fmt.Sprintf("WARNING: Ord-litecoin software requires an additional %s amount of space. Ensure you have enough storage on disk for both.", utils.GetSizeDescription(int64(dataSize))))
+			logger.LogInfo(fmt.Sprintf("WARNING: Add-on %s requires an additional %s amount of space. Ensure you have enough storage on disk for both.", addon.Service, utils.GetSizeDescription(int64(dataSize))))
 		}
 
 		logger.LogInfo("--")
@@ -176,8 +272,23 @@ func startNode(args []string) {
 	cmd.Stderr = os.Stderr
 
 	if err := cmd.Run(); err != nil {
-		logger.LogError("Failed to start Docker Compose services: " + err.Error())
-		return
+		return fmt.Errorf("failed to start Docker Compose services: %w", err)
+	}
+
+	// Record the deployment so stop, update, logs, info and delete can find it later
+	if err := recordDeployment(network, composeFilePath); err != nil {
+		logger.LogError("Failed to record deployment in nodevin state: " + err.Error())
+	} else if lockedImages == nil {
+		// Pin the digests the tags resolved to, so --locked can deploy them again later or elsewhere
+		if err := lockDeploymentImages(getDeploymentName(network)); err != nil {
+			logger.LogError("Failed to write nodevin.lock: " + err.Error())
+		}
+	}
+
+	// Images are updated by the host's update controller from now on, not by watchtower
+	removeLegacyUpdateControllers()
+	if err := ensureUpdateController(); err != nil {
+		logger.LogError("Failed to start the update controller: " + err.Error())
 	}
 
 	logger.LogInfo("Cleaning up excess containers and volumes...")
@@ -192,6 +303,53 @@ func startNode(args []string) {
 	startMessage, _ := utils.GetStartMessage(network)
 
 	fmt.Printf("\n%s\n", startMessage)
+	return nil
+}
+
+func runPreflight(network string, addons []compose.Addon) bool {
+	softwareNetwork, sidecars := getPreflightNetworks(network, addons)
+
+	targets, err := preflight.StorageTargets(softwareNetwork, sidecars)
+	if err != nil {
+		logger.LogError("Failed to determine storage requirements: " + err.Error())
+		return viper.GetBool("force")
+	}
+
+	report := preflight.Run(preflight.Requirements{
+		Targets:        targets,
+		CPULimit:       viper.GetString("cpu-limit"),
+		MemLimit:       viper.GetString("mem-limit"),
+		CPUReservation: viper.GetString("cpu-reservation"),
+		MemReservation: viper.GetString("mem-reservation"),
+	})
+
+	fmt.Print("\n-- Preflight Checks:\n\n")
+	report.Print()
+	fmt.Println("")
+
+	if report.Failed() {
+		if viper.GetBool("force") {
+			logger.LogError("Preflight checks failed. Continuing anyway because --force was passed.")
+			return true
+		}
+
+		logger.LogError("Preflight checks failed. Resolve the issues above or re-run with --force to start anyway.")
+		return false
+	}
+
+	return true
+}
+
+// Maps the start argument and add-ons to the software actually being started
+func getPreflightNetworks(network string, addons []compose.Addon) (string, []string) {
+	var sidecars []string
+	for _, addon := range addons {
+		if _, exists := utils.GetDefaultLocalMappedContainerName(addon.ServiceNetwork()); exists {
+			sidecars = append(sidecars, addon.ServiceNetwork())
+		}
+	}
+
+	return getSoftwareNetwork(network), sidecars
 }
 
 func createComposeFileForNetwork(network string, cwd string) (string, error) {
@@ -212,3 +370,58 @@ func createComposeFileForNetwork(network string, cwd string) (string, error) {
 		return "", fmt.Errorf("unsupported network: %s", network)
 	}
 }
+
+func init() {
+	flags := startNodeCmd.Flags()
+	addContainerFlags(flags)
+	addResourceFlags(flags)
+	addDeployFlags(flags)
+	addSetFlag(flags)
+	addRPCFlags(flags)
+	addCookieAuthFlag(flags)
+	addBlocksDirFlag(flags)
+	addIndexDirFlag(flags)
+	addAllAddonFlags(flags)
+	addClusterFlags(flags, "ipfs-cluster")
+	addElectrumFlags(flags)
+}
+
+// Returns the add-ons requested for a network that can run on this host. Those
+// without images for this architecture are skipped, as the node runs without them.
+func getStartAddons(network string) ([]compose.Addon, error) {
+	requested, err := compose.GetRequestedAddons(network)
+	if err != nil {
+		return nil, err
+	}
+
+	var addons []compose.Addon
+	for _, addon := range requested {
+		if !addon.IsSupportedArchitecture() {
+			logger.LogError(fmt.Sprintf("Running on %s architecture: %s is not supported on %s builds. Skipping %s.", runtime.GOARCH, addon.Service, runtime.GOARCH, addon.Service))
+			continue
+		}
+		addons = append(addons, addon)
+	}
+	return addons, nil
+}
+
+// Returns the compose services a start creates, ex: bitcoin-core and ord
+func getStartServices(network string, addons []compose.Addon) []string {
+	containerName, _ := utils.GetDefaultLocalMappedContainerName(getSoftwareNetwork(network))
+	services := []string{utils.InstanceName(containerName)}
+	for _, addon := range addons {
+		services = append(services, addon.Services()...)
+	}
+	return services
+}
+
+// Returns the images of add-ons and their companions keyed by service network, ex: ord-testnet
+func getAddonImages(addons []compose.Addon) map[string]string {
+	images := map[string]string{}
+	for _, addon := range addons {
+		for serviceNetwork, image := range addon.GetImages() {
+			images[serviceNetwork] = image
+		}
+	}
+	return images
+}
@@ -1,7 +1,7 @@
 package nodes
 
 import (
-	"bytes"
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
@@ -9,7 +9,9 @@ import (
 	"strings"
 
 	"github.com/fiftysixcrypto/nodevin/internal/logger"
+	"github.com/fiftysixcrypto/nodevin/internal/state"
 	"github.com/fiftysixcrypto/nodevin/internal/utils"
+	"github.com/fiftysixcrypto/nodevin/pkg/docker"
 	"github.com/spf13/cobra"
 )
 
@@ -17,42 +19,87 @@ var stopNodeCmd = &cobra.Command{
 	Use:   "stop [network]",
 	Short: "Stop a blockchain node",
 	Args:  cobra.MaximumNArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		if len(args) == 0 {
-			logger.LogError("No network specified. To stop a node, specify the network explicitly.")
-			logger.LogInfo("")
 			availableNetworks := utils.GetCommandSupportedNetworks()
 
 			logger.LogInfo("List of available networks: " + availableNetworks)
 			logger.LogInfo(fmt.Sprintf("Example usage: `%s stop <network>`", utils.GetNodevinExecutable()))
 			logger.LogInfo(fmt.Sprintf("Example usage: `%s stop <network> --testnet`", utils.GetNodevinExecutable()))
 			logger.LogInfo(fmt.Sprintf("Example usage: `%s stop all`", utils.GetNodevinExecutable()))
-			return
+			return fmt.Errorf("no network specified. To stop a node, specify the network explicitly")
 		}
 
-		network := args[0]
-		if network == "all" {
-			stopAllNodes()
-		} else {
-			stopNode(network)
+		if args[0] == "all" {
+			return stopAllNodes()
+		}
+
+		network, err := resolveNetworkArg(args[0])
+		if err != nil {
+			return err
 		}
+
+		return stopNode(network)
 	},
 }
 
-func stopNode(network string) {
+func stopNode(network string) error {
 	logger.LogInfo("Stopping blockchain node...")
 
 	containerName, exists := utils.GetDefaultLocalMappedContainerName(network)
 	if !exists {
-		logger.LogError("Unsupported blockchain network: " + network)
-		return
+		return fmt.Errorf("unsupported blockchain network: %s", network)
+	}
+
+	deploymentName := getDeploymentName(network)
+
+	composeFilePath, err := getDeploymentComposeFile(deploymentName, containerName)
+	if err != nil {
+		return err
+	}
+
+	// Check if there are any running containers for this compose file
+	psCmd := exec.Command("docker-compose", "-f", composeFilePath, "ps", "-q")
+	psOut, err := psCmd.Output()
+	if err != nil {
+		return fmt.Errorf("failed to find Docker Compose services: %w", err)
+	}
+
+	if len(psOut) == 0 {
+		logger.LogInfo("No running containers found for the specified network (did you mean to add --testnet?)")
+		return nil
+	}
+
+	cmd := exec.Command("docker-compose", "-f", composeFilePath, "down")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to stop Docker Compose services: %w", err)
+	}
+
+	markDeploymentStopped(deploymentName)
+
+	logger.LogInfo("Blockchain node stopped successfully.")
+
+	// The controller is shared by every stack, so it only goes away with the last one
+	return stopIdleUpdateController()
+}
+
+// Returns the compose file of a deployment from the nodevin state, falling back to
+// the default location for deployments started before the state file existed
+func getDeploymentComposeFile(deploymentName string, containerName string) (string, error) {
+	deployment, exists, err := state.Get(deploymentName)
+	if err != nil {
+		logger.LogError("Failed to read nodevin state: " + err.Error())
+	} else if exists {
+		return deployment.ComposeFile, nil
 	}
 
 	// Get the user's home directory in a cross-platform manner
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
-		logger.LogError(fmt.Sprintf("Failed to determine home directory: %s", err))
-		return
+		return "", fmt.Errorf("failed to determine home directory: %s", err)
 	}
 
 	var composeCreateDir string
@@ -71,8 +118,7 @@ func stopNode(network string) {
 		if err != nil {
 			cwd, wdErr := os.Getwd()
 			if wdErr != nil {
-				logger.LogError("Unable to determine executable or working directory")
-				return
+				return "", fmt.Errorf("unable to determine executable or working directory")
 			}
 			composeCreateDir = cwd
 		} else {
@@ -81,102 +127,58 @@ func stopNode(network string) {
 		}
 	}
 
-	composeFileName := fmt.Sprintf("docker-compose_%s.yml", containerName)
-	if utils.CheckIfTestnetOrTestnetNetworkFlag() {
-		composeFileName = fmt.Sprintf("docker-compose_%s.yml", containerName+"-testnet")
-	}
-
-	composeFilePath := filepath.Join(composeCreateDir, composeFileName)
-
-	// Check if there are any running containers for this compose file
-	psCmd := exec.Command("docker-compose", "-f", composeFilePath, "ps", "-q")
-	psOut, err := psCmd.Output()
-	if err != nil {
-		logger.LogError("Failed to find Docker Compose services: " + err.Error())
-		return
-	}
-
-	if len(psOut) == 0 {
-		logger.LogInfo("No running containers found for the specified network (did you mean to add --testnet?)")
-		return
+	if variant := utils.GetNetworkVariant(); variant != "mainnet" {
+		containerName = containerName + "-" + variant
 	}
+	composeFileName := fmt.Sprintf("docker-compose_%s.yml", utils.InstanceName(containerName))
 
-	cmd := exec.Command("docker-compose", "-f", composeFilePath, "down")
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-
-	if err := cmd.Run(); err != nil {
-		logger.LogError("Failed to stop Docker Compose services: " + err.Error())
-		return
-	}
-
-	logger.LogInfo("Blockchain node stopped successfully.")
+	return filepath.Join(composeCreateDir, composeFileName), nil
 }
 
-func stopAllNodes() {
+func stopAllNodes() error {
 	logger.LogInfo("Stopping Docker Compose containers...")
 
-	// Get the map of allowed network container names
-	networkContainerMap := utils.NetworkContainerMap()
-	allowedContainers := make(map[string]bool)
-	for _, containerName := range networkContainerMap {
-		allowedContainers[containerName] = true
+	// Stop the update controller (and any watchtower left by an older nodevin) so it doesn't act on containers being torn down
+	if err := stopUpdateController(); err != nil {
+		logger.LogError(err.Error())
 	}
+	removeLegacyUpdateControllers()
 
-	// Include watchtower in container shutdowns
-	allowedContainers["watchtower-nodevin"] = true
-
-	// Get a list of running Docker container IDs with their names
-	psCmd := exec.Command("docker", "ps", "--format", "{{.ID}} {{.Names}}")
-	var psOut bytes.Buffer
-	psCmd.Stdout = &psOut
-	psCmd.Stderr = os.Stderr
-
-	if err := psCmd.Run(); err != nil {
-		logger.LogError("Failed to list running Docker containers: " + err.Error())
-		return
+	// Find every container nodevin created (nodes, sidecars and init containers)
+	containers, err := docker.ListNodevinContainers(context.Background(), true, docker.RoleNode, docker.RoleSidecar, docker.RoleInit)
+	if err != nil {
+		return fmt.Errorf("failed to list Docker containers: %w", err)
 	}
 
-	// Filter containers that match allowed container names
-	var containerIDs []string
-	lines := strings.Split(strings.TrimSpace(psOut.String()), "\n")
-	for _, line := range lines {
-		parts := strings.Fields(line)
-		if len(parts) != 2 {
-			continue
-		}
-		containerID, containerName := parts[0], parts[1]
-		if allowedContainers[containerName] {
-			containerIDs = append(containerIDs, containerID)
-		}
+	if len(containers) == 0 {
+		logger.LogInfo("No matching Docker Compose containers found.")
+		return nil
 	}
 
-	if len(containerIDs) == 0 {
-		logger.LogInfo("No matching Docker Compose containers found.")
-		return
+	var containerNames []string
+	for _, container := range containers {
+		containerNames = append(containerNames, docker.ContainerName(container))
 	}
 
-	// Stop the containers
-	logger.LogInfo("Stopping containers: " + strings.Join(containerIDs, ", "))
-	stopCmd := exec.Command("docker", append([]string{"stop"}, containerIDs...)...)
-	stopCmd.Stdout = os.Stdout
-	stopCmd.Stderr = os.Stderr
+	// Stop and remove the containers
+	logger.LogInfo("Stopping and removing containers: " + strings.Join(containerNames, ", "))
 
-	if err := stopCmd.Run(); err != nil {
-		logger.LogError("Failed to stop Docker containers: " + err.Error())
-		return
+	var failed []string
+	for i, container := range containers {
+		if err := docker.StopAndRemoveContainer(context.Background(), container.ID); err != nil {
+			logger.LogError(fmt.Sprintf("Failed to stop %s: %v", containerNames[i], err))
+			failed = append(failed, containerNames[i])
+		}
 	}
 
-	// Remove the containers
-	logger.LogInfo("Removing containers: " + strings.Join(containerIDs, ", "))
-	rmCmd := exec.Command("docker", append([]string{"rm"}, containerIDs...)...)
-	rmCmd.Stdout = os.Stdout
-	rmCmd.Stderr = os.Stderr
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to stop %s", strings.Join(failed, ", "))
+	}
 
-	if err := rmCmd.Run(); err != nil {
-		logger.LogError("Failed to remove Docker containers: " + err.Error())
-		return
+	for _, deployment := range listDeployments() {
+		markDeploymentStopped(deployment.Name)
 	}
 
 	logger.LogInfo("Selected Docker Compose containers stopped and removed successfully.")
+	return nil
 }
@@ -0,0 +1,186 @@
+/*
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 The Nodevin Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package nodes
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fiftysixcrypto/nodevin/internal/state"
+	"github.com/fiftysixcrypto/nodevin/internal/utils"
+	"github.com/fiftysixcrypto/nodevin/pkg/docker"
+)
+
+const healthPollInterval = 10 * time.Second
+
+// The state of a deployment's containers and node RPC before an update, which the
+// deployment has to reach again afterwards to pass the health gate
+type healthSnapshot struct {
+	// Container name -> health, for containers that were running
+	Containers map[string]containerHealth
+	// Block height reported by the node's RPC, if it answered
+	BlockHeight int
+	HasRPC      bool
+}
+
+type containerHealth struct {
+	Running bool
+	// Docker healthcheck status (healthy, unhealthy, starting) or empty without a healthcheck
+	Health string
+}
+
+func (s healthSnapshot) String() string {
+	var parts []string
+
+	var names []string
+	for name := range s.Containers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		status := "running"
+		if health := s.Containers[name].Health; health != "" {
+			status = health
+		}
+		parts = append(parts, fmt.Sprintf("%s %s", name, status))
+	}
+
+	if s.HasRPC {
+		parts = append(parts, fmt.Sprintf("block height %d", s.BlockHeight))
+	}
+
+	if len(parts) == 0 {
+		return "no containers running"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Records which containers of a deployment are running and healthy, and the node's block height
+func takeHealthSnapshot(deployment state.Deployment) healthSnapshot {
+	snapshot := healthSnapshot{Containers: map[string]containerHealth{}}
+
+	for _, service := range deployment.Services {
+		health, err := inspectContainerHealth(service.ContainerName)
+		if err != nil || !health.Running {
+			continue
+		}
+		snapshot.Containers[service.ContainerName] = health
+	}
+
+	if node, exists := deploymentNodeService(deployment); exists && utils.IsSupportedExtendedInfoNetwork(node.Network) {
+		height, err := getNodeBlockHeight(deployment.Name)
+		if err == nil {
+			snapshot.BlockHeight = height
+			snapshot.HasRPC = true
+		}
+	}
+
+	return snapshot
+}
+
+// Polls a deployment until it is at least as healthy as before the update: every container
+// that ran is running (and healthy if it was), and the node answers RPC at no lower height
+func waitForHealthy(deployment state.Deployment, before healthSnapshot, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		err := checkHealthGate(deployment, before)
+		if err == nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return err
+		}
+		time.Sleep(healthPollInterval)
+	}
+}
+
+func checkHealthGate(deployment state.Deployment, before healthSnapshot) error {
+	for name, previous := range before.Containers {
+		health, err := inspectContainerHealth(name)
+		if err != nil {
+			return fmt.Errorf("failed to inspect %s: %w", name, err)
+		}
+		if !health.Running {
+			return fmt.Errorf("%s is not running", name)
+		}
+		if previous.Health == "healthy" && health.Health != "healthy" {
+			return fmt.Errorf("%s is %s", name, health.Health)
+		}
+	}
+
+	if before.HasRPC {
+		height, err := getNodeBlockHeight(deployment.Name)
+		if err != nil {
+			return fmt.Errorf("node RPC is not answering: %w", err)
+		}
+		if height < before.BlockHeight {
+			return fmt.Errorf("node is at block %d, below %d before the update", height, before.BlockHeight)
+		}
+	}
+
+	return nil
+}
+
+// Waits for a container to exit on its own, ex: after an RPC stop
+func waitForContainerExit(containerName string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+
+	for time.Now().Before(deadline) {
+		health, err := inspectContainerHealth(containerName)
+		if err != nil || !health.Running {
+			return nil
+		}
+		time.Sleep(time.Second)
+	}
+
+	return fmt.Errorf("%s did not stop within %s", containerName, timeout)
+}
+
+func inspectContainerHealth(containerName string) (containerHealth, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dockerInspectTimeout)
+	defer cancel()
+
+	containerState, err := docker.GetContainerState(ctx, containerName)
+	if err != nil {
+		return containerHealth{}, err
+	}
+
+	health := containerHealth{Running: containerState.Running && !containerState.Restarting}
+	if containerState.Health != nil {
+		health.Health = containerState.Health.Status
+	}
+	return health, nil
+}
+
+// Returns the block height reported by a deployment's node (getblockcount)
+func getNodeBlockHeight(target string) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), nodeRPCTimeout)
+	defer cancel()
+
+	var height int
+	if err := callNodeRPC(ctx, target, "getblockcount", &height); err != nil {
+		return 0, err
+	}
+	return height, nil
+}
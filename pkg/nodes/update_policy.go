@@ -0,0 +1,215 @@
+/*
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 The Nodevin Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package nodes
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fiftysixcrypto/nodevin/internal/state"
+	"github.com/spf13/viper"
+)
+
+// Image update policies a deployment can be started with (--update-policy)
+const (
+	// Apply new images on every `nodevin update docker` pass, inside the maintenance window if one is set
+	updatePolicyAuto = "auto"
+	// Report new images on every pass, apply them only when the deployment is named explicitly
+	updatePolicyNotify = "notify"
+	// Only check and apply new images when the deployment is named explicitly
+	updatePolicyManual = "manual"
+	// Never update the deployment's images
+	updatePolicyPin = "pin"
+)
+
+var updatePolicies = []string{updatePolicyAuto, updatePolicyNotify, updatePolicyManual, updatePolicyPin}
+
+// Returns the policy of a recorded deployment (deployments recorded before policies existed are auto)
+func deploymentUpdatePolicy(policy string) string {
+	if policy == "" {
+		return updatePolicyAuto
+	}
+	return policy
+}
+
+func validateUpdatePolicy(policy string) error {
+	for _, known := range updatePolicies {
+		if policy == known {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown update policy %q (use one of: %s)", policy, strings.Join(updatePolicies, ", "))
+}
+
+// Validates the --update-policy and --maintenance-window flags
+func validateUpdateFlags() error {
+	if policy := viper.GetString("update-policy"); policy != "" {
+		if err := validateUpdatePolicy(policy); err != nil {
+			return err
+		}
+	}
+	if window := viper.GetString("maintenance-window"); window != "" {
+		if _, err := parseMaintenanceWindow(window); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// A recurring local time window, ex: "Sun 02:00-04:00", "Sat,Sun 01:00-06:00",
+// "Mon-Fri 23:00-01:00" or "02:00-04:00" (every day). A window ending before
+// it starts runs past midnight into the next day.
+type maintenanceWindow struct {
+	days  [7]bool
+	start time.Duration
+	end   time.Duration
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+func parseMaintenanceWindow(window string) (maintenanceWindow, error) {
+	var parsed maintenanceWindow
+
+	fields := strings.Fields(window)
+	var days, hours string
+	switch len(fields) {
+	case 1:
+		days, hours = "daily", fields[0]
+	case 2:
+		days, hours = strings.ToLower(fields[0]), fields[1]
+	default:
+		return parsed, fmt.Errorf("invalid maintenance window %q (ex: \"Sun 02:00-04:00\")", window)
+	}
+
+	if days == "daily" || days == "*" {
+		for i := range parsed.days {
+			parsed.days[i] = true
+		}
+	} else {
+		for _, part := range strings.Split(days, ",") {
+			first, last, isRange := strings.Cut(part, "-")
+			from, ok := weekdayNames[first]
+			if !ok {
+				return parsed, fmt.Errorf("invalid day %q in maintenance window %q", first, window)
+			}
+			to := from
+			if isRange {
+				if to, ok = weekdayNames[last]; !ok {
+					return parsed, fmt.Errorf("invalid day %q in maintenance window %q", last, window)
+				}
+			}
+			for day := from; ; day = (day + 1) % 7 {
+				parsed.days[day] = true
+				if day == to {
+					break
+				}
+			}
+		}
+	}
+
+	startTime, endTime, found := strings.Cut(hours, "-")
+	if !found {
+		return parsed, fmt.Errorf("invalid maintenance window %q (ex: \"Sun 02:00-04:00\")", window)
+	}
+
+	var err error
+	if parsed.start, err = parseTimeOfDay(startTime); err != nil {
+		return parsed, fmt.Errorf("invalid maintenance window %q: %w", window, err)
+	}
+	if parsed.end, err = parseTimeOfDay(endTime); err != nil {
+		return parsed, fmt.Errorf("invalid maintenance window %q: %w", window, err)
+	}
+	if parsed.start == parsed.end {
+		return parsed, fmt.Errorf("maintenance window %q is empty", window)
+	}
+
+	return parsed, nil
+}
+
+// Parses HH:MM into the time since midnight
+func parseTimeOfDay(value string) (time.Duration, error) {
+	parsed, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q (use HH:MM)", value)
+	}
+	return time.Duration(parsed.Hour())*time.Hour + time.Duration(parsed.Minute())*time.Minute, nil
+}
+
+// Reports whether t (in local time) falls inside the window
+func (w maintenanceWindow) contains(t time.Time) bool {
+	t = t.Local()
+	timeOfDay := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+
+	if w.start < w.end {
+		return w.days[t.Weekday()] && timeOfDay >= w.start && timeOfDay < w.end
+	}
+
+	// The window wraps past midnight: it covers the evening of a listed day and the following early morning
+	previousDay := (t.Weekday() + 6) % 7
+	return (w.days[t.Weekday()] && timeOfDay >= w.start) || (w.days[previousDay] && timeOfDay < w.end)
+}
+
+// Returns the first start of the window after t
+func (w maintenanceWindow) nextStart(t time.Time) time.Time {
+	t = t.Local()
+	for offset := 0; offset <= 7; offset++ {
+		day := time.Date(t.Year(), t.Month(), t.Day()+offset, 0, 0, 0, 0, time.Local)
+		start := day.Add(w.start)
+		if w.days[day.Weekday()] && start.After(t) {
+			return start
+		}
+	}
+	return time.Time{}
+}
+
+// Returns the earliest upcoming start of the maintenance windows of auto deployments, so the
+// update controller checks them as soon as their window opens even if it is shorter than --interval
+func nextMaintenanceWindowStart(deployments []state.Deployment, now time.Time) (time.Time, bool) {
+	var next time.Time
+	for _, deployment := range deployments {
+		if deploymentUpdatePolicy(deployment.UpdatePolicy) != updatePolicyAuto || deployment.MaintenanceWindow == "" {
+			continue
+		}
+		window, err := parseMaintenanceWindow(deployment.MaintenanceWindow)
+		if err != nil {
+			continue
+		}
+		if start := window.nextStart(now); !start.IsZero() && (next.IsZero() || start.Before(next)) {
+			next = start
+		}
+	}
+	return next, !next.IsZero()
+}
+
+// Reports whether auto updates may run now for a deployment's window (no window means any time)
+func inMaintenanceWindow(window string, now time.Time) (bool, error) {
+	if window == "" {
+		return true, nil
+	}
+
+	parsed, err := parseMaintenanceWindow(window)
+	if err != nil {
+		return false, err
+	}
+	return parsed.contains(now), nil
+}
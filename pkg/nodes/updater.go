@@ -0,0 +1,230 @@
+/*
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 The Nodevin Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package nodes
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fiftysixcrypto/nodevin/internal/logger"
+	"github.com/fiftysixcrypto/nodevin/internal/state"
+	"github.com/fiftysixcrypto/nodevin/internal/utils"
+	"github.com/fiftysixcrypto/nodevin/pkg/docker"
+)
+
+// The update controller is a single `nodevin update watch` process per host, started by
+// `nodevin start` and shared by every stack. It holds updater.lock for as long as it runs
+// and records its pid in updater.pid, so later nodevin processes can find and stop it.
+const (
+	updateControllerLockFile = "updater.lock"
+	updateControllerPidFile  = "updater.pid"
+	updateControllerLogFile  = "updater.log"
+
+	// How long stop waits for a killed controller to release its lock
+	updateControllerStopTimeout = 10 * time.Second
+)
+
+// Kept open by the controller, closing it (or letting it be collected) would release the lock
+var updateControllerLock *os.File
+
+func updateControllerPath(name string) (string, error) {
+	nodevinDataDir, err := utils.GetNodevinDataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(nodevinDataDir), name), nil
+}
+
+// Marks the calling process as the host's update controller. The lock is held until the process exits.
+func acquireUpdateControllerLock() error {
+	lockPath, err := updateControllerPath(updateControllerLockFile)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(lockPath), 0755); err != nil {
+		return fmt.Errorf("failed to create nodevin directory: %w", err)
+	}
+
+	file, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open update controller lock: %w", err)
+	}
+
+	locked, err := tryLockFile(file)
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to lock update controller lock: %w", err)
+	}
+	if !locked {
+		file.Close()
+		return fmt.Errorf("an update controller is already running on this host")
+	}
+	updateControllerLock = file
+
+	pidPath, err := updateControllerPath(updateControllerPidFile)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(pidPath, []byte(strconv.Itoa(os.Getpid())), 0644)
+}
+
+// Returns the pid of the host's update controller, or 0 if none is running
+func runningUpdateController() (int, error) {
+	lockPath, err := updateControllerPath(updateControllerLockFile)
+	if err != nil {
+		return 0, err
+	}
+
+	file, err := os.Open(lockPath)
+	if os.IsNotExist(err) {
+		return 0, nil
+	} else if err != nil {
+		return 0, fmt.Errorf("failed to open update controller lock: %w", err)
+	}
+	defer file.Close()
+
+	locked, err := tryLockFile(file)
+	if err != nil {
+		return 0, fmt.Errorf("failed to check update controller lock: %w", err)
+	}
+	if locked {
+		unlockFile(file)
+		return 0, nil
+	}
+
+	pidPath, err := updateControllerPath(updateControllerPidFile)
+	if err != nil {
+		return 0, err
+	}
+	data, err := os.ReadFile(pidPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read update controller pid: %w", err)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, fmt.Errorf("invalid update controller pid %q", strings.TrimSpace(string(data)))
+	}
+	return pid, nil
+}
+
+// Starts the host's update controller in the background unless one is already running
+func ensureUpdateController() error {
+	pid, err := runningUpdateController()
+	if err != nil {
+		return err
+	}
+	if pid != 0 {
+		return nil
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to find the nodevin executable: %w", err)
+	}
+
+	logPath, err := updateControllerPath(updateControllerLogFile)
+	if err != nil {
+		return err
+	}
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open update controller log: %w", err)
+	}
+	defer logFile.Close()
+
+	cmd := exec.Command(executable, "update", "watch")
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	detachCommand(cmd)
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start the update controller: %w", err)
+	}
+	logger.LogInfo(fmt.Sprintf("Started the update controller (pid %d), logging to %s", cmd.Process.Pid, logPath))
+
+	return cmd.Process.Release()
+}
+
+// Stops the host's update controller if one is running
+func stopUpdateController() error {
+	pid, err := runningUpdateController()
+	if err != nil {
+		return err
+	}
+	if pid == 0 {
+		return nil
+	}
+
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return fmt.Errorf("failed to find the update controller (pid %d): %w", pid, err)
+	}
+	// An update in progress is abandoned, its deployment is being stopped anyway
+	if err := process.Kill(); err != nil {
+		return fmt.Errorf("failed to stop the update controller (pid %d): %w", pid, err)
+	}
+
+	for deadline := time.Now().Add(updateControllerStopTimeout); time.Now().Before(deadline); time.Sleep(200 * time.Millisecond) {
+		if pid, err := runningUpdateController(); err == nil && pid == 0 {
+			logger.LogInfo("Stopped the update controller.")
+			return nil
+		}
+	}
+	return fmt.Errorf("update controller (pid %d) did not exit within %s", pid, updateControllerStopTimeout)
+}
+
+// Stops the update controller once no recorded deployment is running anymore
+func stopIdleUpdateController() error {
+	for _, deployment := range listDeployments() {
+		if deployment.Status == state.StatusRunning {
+			return nil
+		}
+	}
+	return stopUpdateController()
+}
+
+// Stops and removes the watchtower containers older nodevin releases added to every
+// compose file. Images are now updated by the update controller, and a watchtower per
+// stack collides with the next stack's and would replace images behind its back.
+// Those containers carry no labels; ListNodevinContainers recognizes them by
+// their watchtower-nodevin name or watchtower image.
+func removeLegacyUpdateControllers() {
+	ctx, cancel := context.WithTimeout(context.Background(), dockerListTimeout)
+	defer cancel()
+
+	updaters, err := docker.ListNodevinContainers(ctx, true, docker.RoleUpdater)
+	if err != nil {
+		logger.LogError("Failed to list update controllers: " + err.Error())
+		return
+	}
+
+	for _, updater := range updaters {
+		if err := docker.StopAndRemoveContainer(context.Background(), updater.ID); err != nil {
+			logger.LogError(fmt.Sprintf("Failed to remove %s: %v", docker.ContainerName(updater), err))
+			continue
+		}
+		logger.LogInfo("Removed legacy update controller " + docker.ContainerName(updater))
+	}
+}
@@ -0,0 +1,659 @@
+/*
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 The Nodevin Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package nodes
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fiftysixcrypto/nodevin/internal/lockfile"
+	"github.com/fiftysixcrypto/nodevin/internal/logger"
+	"github.com/fiftysixcrypto/nodevin/internal/state"
+	"github.com/fiftysixcrypto/nodevin/internal/utils"
+	"github.com/fiftysixcrypto/nodevin/pkg/docker"
+	"github.com/fiftysixcrypto/nodevin/pkg/docker/compose"
+	"github.com/spf13/cobra"
+)
+
+// Data directories are searched this deep for the paths an incompatibility affects
+const backupSearchDepth = 3
+
+const backupDirPrefix = "nodevin-backup-"
+
+// Shared by upgrade and downgrade, so they are package variables rather than viper keys
+var (
+	switchToVersion string
+	switchService   string
+	switchBackup    bool
+	switchReindex   bool
+	switchForce     bool
+)
+
+var upgradeCmd = &cobra.Command{
+	Use:   "upgrade <network>",
+	Short: "Switch a deployment to a newer version of its software",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return switchVersion(args[0], true)
+	},
+}
+
+var downgradeCmd = &cobra.Command{
+	Use:   "downgrade <network>",
+	Short: "Switch a deployment to an older version of its software",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return switchVersion(args[0], false)
+	},
+}
+
+// Switches a service of a recorded deployment to --to, checking known data-format incompatibilities first
+func switchVersion(arg string, upgrade bool) error {
+	network, err := resolveNetworkArg(arg)
+	if err != nil {
+		return err
+	}
+
+	deploymentName := getDeploymentName(network)
+	deployment, exists, err := state.Get(deploymentName)
+	if err != nil {
+		return fmt.Errorf("failed to read nodevin state: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("no recorded deployment %s, start it with `%s start %s` first", deploymentName, utils.GetNodevinExecutable(), arg)
+	}
+
+	service, err := findSwitchService(deployment, switchService)
+	if err != nil {
+		return err
+	}
+
+	repository := lockfile.Repository(service.Image)
+	targetImage := repository + ":" + switchToVersion
+
+	currentVersion, err := resolveImageVersion(service.Image, service.ContainerName)
+	if err != nil {
+		return fmt.Errorf("failed to determine the version %s runs: %w", service.ContainerName, err)
+	}
+	targetVersion, err := resolveImageVersion(targetImage, "")
+	if err != nil {
+		if !switchForce {
+			return fmt.Errorf("failed to resolve %s: %w (use --force to switch anyway)", targetImage, err)
+		}
+		logger.LogError(err.Error())
+	}
+
+	command := "upgrade"
+	if !upgrade {
+		command = "downgrade"
+	}
+
+	if currentVersion != nil && targetVersion != nil {
+		switch comparison := compareVersions(targetVersion, currentVersion); {
+		case comparison == 0:
+			logger.LogInfo(fmt.Sprintf("%s already runs %s.", service.ContainerName, formatVersion(currentVersion)))
+			return nil
+		case comparison < 0 && upgrade:
+			return fmt.Errorf("%s is older than the running %s, use `%s downgrade %s --to %s`", switchToVersion, formatVersion(currentVersion), utils.GetNodevinExecutable(), arg, switchToVersion)
+		case comparison > 0 && !upgrade:
+			return fmt.Errorf("%s is newer than the running %s, use `%s upgrade %s --to %s`", switchToVersion, formatVersion(currentVersion), utils.GetNodevinExecutable(), arg, switchToVersion)
+		}
+	} else if !switchForce {
+		return fmt.Errorf("cannot compare the running version of %s with %s, use --force to %s anyway", service.ContainerName, switchToVersion, command)
+	}
+
+	var incompatibilities []versionIncompatibility
+	if currentVersion != nil && targetVersion != nil {
+		incompatibilities = affectingIncompatibilities(findIncompatibilities(repository, currentVersion, targetVersion), dataRoots(service.DataDir))
+		logger.LogInfo(fmt.Sprintf("Switching %s from %s to %s", service.ContainerName, formatVersion(currentVersion), formatVersion(targetVersion)))
+	} else {
+		logger.LogInfo(fmt.Sprintf("Switching %s from %s to %s", service.ContainerName, service.Image, targetImage))
+	}
+
+	backup, reindex, err := chooseRemedies(incompatibilities)
+	if err != nil {
+		return err
+	}
+
+	return applyVersionSwitch(deployment, service, targetImage, currentVersion, incompatibilities, backup, reindex)
+}
+
+// Returns the service of a deployment to switch: its node, or the one whose network is name (ex: ord)
+func findSwitchService(deployment state.Deployment, name string) (state.Service, error) {
+	if name == "" {
+		if node, exists := deploymentNodeService(deployment); exists {
+			return node, nil
+		}
+		return state.Service{}, fmt.Errorf("deployment %s has no node service", deployment.Name)
+	}
+
+	for _, service := range deployment.Services {
+		if base, _ := utils.SplitNetworkVariant(service.Network); service.Network == name || base == name {
+			return service, nil
+		}
+	}
+	return state.Service{}, fmt.Errorf("deployment %s has no %s service", deployment.Name, name)
+}
+
+// Returns the version an image runs: its tag if it is one, else NODE_VERSION of the running
+// container, else the versioned tag in the registry with the same digest (ex: for latest)
+func resolveImageVersion(image string, containerName string) ([]int, error) {
+	if _, tag := splitImageTag(image); !strings.Contains(image, "@") {
+		if version, ok := parseVersion(tag); ok {
+			return version, nil
+		}
+	}
+
+	if containerName != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), dockerInspectTimeout)
+		nodeVersion, err := getNodeVersionFromEnv(ctx, containerName)
+		cancel()
+		if err == nil {
+			if version, ok := parseVersion(nodeVersion); ok {
+				return version, nil
+			}
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), registryCheckTimeout)
+	defer cancel()
+
+	digest := ""
+	if strings.Contains(image, "@") {
+		digest = docker.DigestOf(image)
+	} else {
+		remoteDigest, err := docker.GetRemoteImageDigest(ctx, image)
+		if err != nil {
+			return nil, err
+		}
+		digest = remoteDigest
+	}
+
+	tags, err := docker.ListRemoteImageTags(ctx, lockfile.Repository(image), 0)
+	if err != nil {
+		return nil, err
+	}
+	for _, tag := range tags {
+		if tag.Digest != digest {
+			continue
+		}
+		if version, ok := parseVersion(tag.Name); ok {
+			return version, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no version tag shares the digest of %s", image)
+}
+
+// Decides whether to back up and reindex from the flags, asking when they are not given
+func chooseRemedies(incompatibilities []versionIncompatibility) (bool, bool, error) {
+	if len(incompatibilities) == 0 {
+		return switchBackup, false, nil
+	}
+
+	fmt.Println("")
+	fmt.Println("This switch crosses known data-format changes:")
+	for _, incompatibility := range incompatibilities {
+		fmt.Printf("  - %s\n    Required: %s\n", incompatibility.Description, incompatibility.Remedy)
+	}
+	fmt.Println("")
+
+	backup, reindex := switchBackup, switchReindex
+	if !backup && !reindex && isInteractive() {
+		backup = askYesNo("Back up the affected data before switching?")
+		reindex = askYesNo("Apply the required reindex or resync?")
+	}
+
+	if !reindex && !switchForce {
+		return false, false, fmt.Errorf("the node cannot start on its current data, pass --reindex (and --backup to keep a copy) or --force to switch anyway")
+	}
+
+	return backup, reindex, nil
+}
+
+// Reports whether stdin is a terminal (/dev/null is a character device too)
+func isInteractive() bool {
+	info, err := os.Stdin.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	devNull, err := os.Stat(os.DevNull)
+	return err != nil || !os.SameFile(info, devNull)
+}
+
+func askYesNo(question string) bool {
+	reader := bufio.NewReader(os.Stdin)
+	fmt.Printf("%s ('y'/'n'): ", question)
+	input, _ := reader.ReadString('\n')
+	return strings.TrimSpace(strings.ToLower(input)) == "y"
+}
+
+func applyVersionSwitch(deployment state.Deployment, service state.Service, targetImage string, currentVersion []int, incompatibilities []versionIncompatibility, backup bool, reindex bool) error {
+	composeFilePath, err := filepath.Abs(deployment.ComposeFile)
+	if err != nil {
+		return fmt.Errorf("failed to resolve compose file path: %w", err)
+	}
+	originalCompose, err := os.ReadFile(composeFilePath)
+	if err != nil {
+		return fmt.Errorf("failed to read compose file: %w", err)
+	}
+
+	// Pull first so the deployment is only down for the switch itself
+	logger.LogInfo("Pulling " + targetImage + "...")
+	ctx, cancel := context.WithTimeout(context.Background(), dockerPullTimeout)
+	err = docker.PullRemoteImage(ctx, targetImage)
+	cancel()
+	if err != nil {
+		return fmt.Errorf("failed to pull %s: %w", targetImage, err)
+	}
+
+	before := takeHealthSnapshot(deployment)
+	stopDeploymentGracefully(deployment, composeFilePath)
+
+	var paths []string
+	for _, incompatibility := range incompatibilities {
+		paths = append(paths, incompatibility.Paths...)
+	}
+	roots := dataRoots(service.DataDir)
+	affected := findDataPaths(roots, paths)
+
+	if backup {
+		label := "unknown"
+		if currentVersion != nil {
+			label = formatVersion(currentVersion)
+		}
+		if err := backupDataPaths(service.DataDir, roots, affected, label); err != nil {
+			logger.LogError("Backup failed, restarting on the previous version: " + err.Error())
+			restartPreviousVersion(deployment.Name, composeFilePath, originalCompose)
+			return err
+		}
+	}
+
+	var reindexArgs []string
+	if reindex {
+		reindexArgs, err = applyRemedies(incompatibilities, affected)
+		if err != nil {
+			logger.LogError("Removing the affected data failed, restarting on the previous version: " + err.Error())
+			restartPreviousVersion(deployment.Name, composeFilePath, originalCompose)
+			return err
+		}
+	}
+
+	err = compose.EditComposeFile(composeFilePath, func(composeFile *compose.ComposeFile) error {
+		// Init containers run the image of the service they prepare
+		var previousImage string
+		for name, composeService := range composeFile.Services {
+			if composeService.ContainerName != service.ContainerName {
+				continue
+			}
+			previousImage = composeService.Image
+			composeService.Image = targetImage
+			if len(reindexArgs) > 0 {
+				composeService.Command = strings.TrimSpace(composeService.Command + " " + strings.Join(reindexArgs, " "))
+			}
+			composeFile.Services[name] = composeService
+		}
+		if previousImage == "" {
+			return fmt.Errorf("%s is not in %s", service.ContainerName, composeFilePath)
+		}
+
+		for name, composeService := range composeFile.Services {
+			if composeService.Labels[docker.LabelRole] == docker.RoleInit && composeService.Image == previousImage {
+				composeService.Image = targetImage
+				composeFile.Services[name] = composeService
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		restartPreviousVersion(deployment.Name, composeFilePath, originalCompose)
+		return err
+	}
+
+	logger.LogInfo(fmt.Sprintf("Starting %s on %s...", deployment.Name, targetImage))
+	if err := runDockerCommand(composeUpTimeout, "docker-compose", "-f", composeFilePath, "up", "-d"); err != nil {
+		logger.LogError(fmt.Sprintf("Failed to start %s on %s: %v", deployment.Name, targetImage, err))
+		restartPreviousVersion(deployment.Name, composeFilePath, originalCompose)
+		return fmt.Errorf("failed to start on %s, restarted on %s", targetImage, service.Image)
+	}
+
+	// The reindex runs once; later restarts must not start it again
+	if len(reindexArgs) > 0 {
+		if err := dropReindexArgs(deployment.Name, composeFilePath, service.ContainerName, reindexArgs); err != nil {
+			logger.LogError(err.Error())
+		}
+	}
+
+	// After a data-format change the node reindexes or resyncs for hours, so it cannot pass the health gate
+	if len(incompatibilities) == 0 {
+		logger.LogInfo(fmt.Sprintf("Waiting up to %s for %s to become healthy...", updateHealthTimeout, deployment.Name))
+		if err := waitForHealthy(deployment, before, updateHealthTimeout); err != nil {
+			logger.LogError(fmt.Sprintf("%s failed its health check on %s: %v", deployment.Name, targetImage, err))
+			stopDeploymentGracefully(deployment, composeFilePath)
+			restartPreviousVersion(deployment.Name, composeFilePath, originalCompose)
+			return fmt.Errorf("health check failed on %s, restarted on %s", targetImage, service.Image)
+		}
+	} else if reindex {
+		logger.LogInfo(fmt.Sprintf("%s is rebuilding its data, follow it with `%s logs %s`", service.ContainerName, utils.GetNodevinExecutable(), deployment.Name))
+	}
+
+	recordSwitchedImage(deployment.Name, service, targetImage)
+
+	logger.LogInfo(fmt.Sprintf("Switched %s to %s", service.ContainerName, targetImage))
+	return nil
+}
+
+// Restores the compose file written before the switch and starts the deployment on it
+func restartPreviousVersion(deploymentName string, composeFilePath string, originalCompose []byte) {
+	if err := os.WriteFile(composeFilePath, originalCompose, 0644); err != nil {
+		logger.LogError("Failed to restore compose file: " + err.Error())
+		return
+	}
+	if err := runDockerCommand(composeUpTimeout, "docker-compose", "-f", composeFilePath, "up", "-d"); err != nil {
+		logger.LogError(fmt.Sprintf("Failed to restart %s: %v", deploymentName, err))
+	}
+}
+
+// Removes the data a resync or index rebuild replaces and returns the node arguments of a reindex
+func applyRemedies(incompatibilities []versionIncompatibility, affected map[string][]string) ([]string, error) {
+	var strongest *versionIncompatibility
+	for i := range incompatibilities {
+		if strongest == nil || incompatibilities[i].Remedy > strongest.Remedy {
+			strongest = &incompatibilities[i]
+		}
+	}
+	if strongest == nil {
+		return nil, nil
+	}
+
+	switch strongest.Remedy {
+	case remedyReindexChainstate:
+		return []string{"-reindex-chainstate"}, nil
+	case remedyReindex:
+		return []string{"-reindex"}, nil
+	}
+
+	for _, name := range strongest.Paths {
+		for _, path := range affected[name] {
+			logger.LogInfo("Removing " + path + "...")
+			if err := os.RemoveAll(path); err != nil {
+				return nil, fmt.Errorf("failed to remove %s: %w", path, err)
+			}
+		}
+	}
+	return nil, nil
+}
+
+// Returns the directories holding a service's data by label: its data dir ("") and any
+// separate --blocks-dir ("blocks") or --index-dir ("index") it was started with
+func dataRoots(dataDir string) map[string]string {
+	roots := map[string]string{}
+	if dataDir == "" {
+		return roots
+	}
+	roots[""] = dataDir
+
+	extraPaths, err := utils.ReadDataLocations(dataDir)
+	if err != nil {
+		logger.LogError(err.Error())
+		return roots
+	}
+	for label, path := range extraPaths {
+		roots[label] = path
+	}
+
+	return roots
+}
+
+// Finds the files and directories named in names below each data root
+func findDataPaths(roots map[string]string, names []string) map[string][]string {
+	found := map[string][]string{}
+	if len(names) == 0 {
+		return found
+	}
+
+	wanted := map[string]bool{}
+	for _, name := range names {
+		wanted[name] = true
+	}
+
+	for _, root := range roots {
+		rootDepth := strings.Count(filepath.Clean(root), string(os.PathSeparator))
+		filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if entry.IsDir() && strings.HasPrefix(entry.Name(), backupDirPrefix) {
+				return filepath.SkipDir
+			}
+
+			if path != root && wanted[entry.Name()] {
+				found[entry.Name()] = append(found[entry.Name()], path)
+				if entry.IsDir() {
+					return filepath.SkipDir
+				}
+			}
+
+			if entry.IsDir() && strings.Count(path, string(os.PathSeparator))-rootDepth >= backupSearchDepth {
+				return filepath.SkipDir
+			}
+			return nil
+		})
+	}
+
+	return found
+}
+
+// Copies the affected paths into dataDir/nodevin-backup-<version>-<time>, keeping their layout relative
+// to their data root. Paths under a separate root go below its label, ex: blocks/blocks/blk00000.dat.
+func backupDataPaths(dataDir string, roots map[string]string, affected map[string][]string, version string) error {
+	if len(affected) == 0 {
+		logger.LogInfo("No affected data found to back up.")
+		return nil
+	}
+
+	backupDir := filepath.Join(dataDir, fmt.Sprintf("%s%s-%s", backupDirPrefix, version, time.Now().UTC().Format("20060102-150405")))
+	logger.LogInfo("Backing up affected data to " + backupDir + "...")
+
+	for _, paths := range affected {
+		for _, path := range paths {
+			relative, err := backupRelativePath(roots, path)
+			if err != nil {
+				return err
+			}
+			if err := copyPath(path, filepath.Join(backupDir, relative)); err != nil {
+				return fmt.Errorf("failed to back up %s: %w", path, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Returns where path goes inside a backup: below the label of the data root containing it
+func backupRelativePath(roots map[string]string, path string) (string, error) {
+	for label, root := range roots {
+		relative, err := filepath.Rel(root, path)
+		if err == nil && relative != ".." && !strings.HasPrefix(relative, ".."+string(os.PathSeparator)) {
+			return filepath.Join(label, relative), nil
+		}
+	}
+	return "", fmt.Errorf("%s is not in a data directory of the service", path)
+}
+
+func copyPath(source string, destination string) error {
+	return filepath.WalkDir(source, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relative, err := filepath.Rel(source, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(destination, relative)
+
+		if entry.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		return copyFile(path, target)
+	})
+}
+
+func copyFile(source string, destination string) error {
+	if err := os.MkdirAll(filepath.Dir(destination), 0755); err != nil {
+		return err
+	}
+
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(destination, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// Removes the reindex arguments from the node's compose service and recreates its container
+// without them, since the running container keeps the command it was created with and Docker
+// restarts it with that command (ex: after a host reboot). The node records that it is
+// reindexing before its RPC starts answering and resumes the reindex on its own after that.
+func dropReindexArgs(deploymentName string, composeFilePath string, containerName string, reindexArgs []string) error {
+	serviceName, err := removeCommandArgs(composeFilePath, containerName, reindexArgs)
+	if err != nil {
+		return err
+	}
+
+	logger.LogInfo(fmt.Sprintf("Waiting up to %s for %s to start its reindex...", updateHealthTimeout, containerName))
+	deadline := time.Now().Add(updateHealthTimeout)
+	for {
+		if _, err := getNodeBlockHeight(deploymentName); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%s did not answer RPC within %s, it still runs with %s until you run `docker-compose -f %s up -d --force-recreate %s`", containerName, updateHealthTimeout, strings.Join(reindexArgs, " "), composeFilePath, serviceName)
+		}
+		time.Sleep(healthPollInterval)
+	}
+
+	logger.LogInfo(fmt.Sprintf("Recreating %s without %s...", containerName, strings.Join(reindexArgs, " ")))
+	timeout := fmt.Sprintf("%d", int(gracefulStopTimeout.Seconds()))
+	if err := runDockerCommand(gracefulStopTimeout+composeUpTimeout, "docker-compose", "-f", composeFilePath, "up", "-d", "--no-deps", "--force-recreate", "-t", timeout, serviceName); err != nil {
+		return fmt.Errorf("failed to recreate %s without %s: %w", containerName, strings.Join(reindexArgs, " "), err)
+	}
+	return nil
+}
+
+// Removes one-off arguments (ex: -reindex) from a service command in the compose file,
+// returning the name of the service
+func removeCommandArgs(composeFilePath string, containerName string, args []string) (string, error) {
+	var serviceName string
+	err := compose.EditComposeFile(composeFilePath, func(composeFile *compose.ComposeFile) error {
+		for name, composeService := range composeFile.Services {
+			if composeService.ContainerName != containerName {
+				continue
+			}
+			serviceName = name
+			fields := strings.Fields(composeService.Command)
+			kept := fields[:0]
+			for _, field := range fields {
+				remove := false
+				for _, arg := range args {
+					remove = remove || field == arg
+				}
+				if !remove {
+					kept = append(kept, field)
+				}
+			}
+			composeService.Command = strings.Join(kept, " ")
+			composeFile.Services[name] = composeService
+		}
+		if serviceName == "" {
+			return fmt.Errorf("%s is not in %s", containerName, composeFilePath)
+		}
+		return nil
+	})
+	return serviceName, err
+}
+
+// Records the image a service was switched to, keeping the previous digest for reference
+func recordSwitchedImage(deploymentName string, switched state.Service, image string) {
+	ctx, cancel := context.WithTimeout(context.Background(), dockerInspectTimeout)
+	digest, err := docker.GetImageDigest(ctx, image)
+	cancel()
+	if err != nil {
+		logger.LogError(fmt.Sprintf("Failed to find digest of image %s: %v", image, err))
+	}
+
+	_, err = state.Update(deploymentName, func(deployment *state.Deployment) {
+		deployment.Status = state.StatusRunning
+		for i, service := range deployment.Services {
+			if service.Image != switched.Image {
+				continue
+			}
+			deployment.Services[i].Image = image
+			deployment.Services[i].PreviousImageDigest = service.ImageDigest
+			deployment.Services[i].ImageDigest = digest
+		}
+	})
+	if err != nil {
+		logger.LogError("Failed to update nodevin state: " + err.Error())
+		return
+	}
+
+	if err := lockDeploymentImages(deploymentName); err != nil {
+		logger.LogError("Failed to update nodevin.lock: " + err.Error())
+	}
+}
+
+func init() {
+	for _, cmd := range []*cobra.Command{upgradeCmd, downgradeCmd} {
+		cmd.Flags().StringVar(&switchToVersion, "to", "", "Version (image tag) to switch to, ex: 27.1")
+		cmd.Flags().StringVar(&switchService, "service", "", "Service to switch, ex: ord (default the node)")
+		cmd.Flags().BoolVar(&switchBackup, "backup", false, "Back up data affected by a data-format change before switching")
+		cmd.Flags().BoolVar(&switchReindex, "reindex", false, "Reindex, resync or rebuild the index when a data-format change requires it")
+		cmd.Flags().BoolVar(&switchForce, "force", false, "Switch even if the version cannot be verified or its data is incompatible")
+		cmd.MarkFlagRequired("to")
+		addRPCFlags(cmd.Flags())
+	}
+}
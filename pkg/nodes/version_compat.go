@@ -0,0 +1,220 @@
+/*
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 The Nodevin Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package nodes
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// How a data-format incompatibility is resolved when switching versions with --reindex
+type remedy int
+
+const (
+	// Restart the node with -reindex-chainstate (the chainstate is rebuilt from the block files)
+	remedyReindexChainstate remedy = iota
+	// Restart the node with -reindex (the block index and chainstate are rebuilt from the block files)
+	remedyReindex
+	// Remove the chain data, the node downloads the chain again
+	remedyResync
+	// Remove the ord index, ord rebuilds it on start
+	remedyRebuildIndex
+)
+
+func (r remedy) String() string {
+	switch r {
+	case remedyReindexChainstate:
+		return "reindex the chainstate (-reindex-chainstate)"
+	case remedyReindex:
+		return "reindex (-reindex)"
+	case remedyResync:
+		return "remove the chain data and sync again"
+	default:
+		return "remove and rebuild the ord index"
+	}
+}
+
+// A known data-format change between two releases of a node's software
+type versionIncompatibility struct {
+	// Image repositories the change applies to, without namespace (ex: bitcoin-core)
+	Software []string
+	// First version writing the new format
+	Version string
+	// Downgrades from Version (or later) to before it are affected. Otherwise any switch
+	// between different release lines (ex: ord 0.19 and 0.20) is.
+	DowngradeOnly bool
+	Description   string
+	Remedy        remedy
+	// Data directories or files (by name) the change affects, backed up with --backup
+	Paths []string
+	// Reports whether the existing data is affected, given the Paths found in it. Nil means it always is.
+	Affects func(found map[string][]string) bool
+}
+
+var versionIncompatibilities = []versionIncompatibility{
+	{
+		Software:      []string{"bitcoin-core"},
+		Version:       "0.15.0",
+		DowngradeOnly: true,
+		Description:   "Bitcoin Core 0.15 changed the chainstate database format; earlier versions cannot read it.",
+		Remedy:        remedyReindexChainstate,
+		Paths:         []string{"chainstate"},
+	},
+	{
+		Software:      []string{"bitcoin-core"},
+		Version:       "0.17.0",
+		DowngradeOnly: true,
+		Description:   "Bitcoin Core 0.17 moved the transaction index to indexes/txindex; earlier versions need a full reindex.",
+		Remedy:        remedyReindex,
+		Paths:         []string{"chainstate", "indexes"},
+	},
+	{
+		Software:      []string{"bitcoin-core"},
+		Version:       "28.0",
+		DowngradeOnly: true,
+		Description:   "Bitcoin Core 28.0 obfuscates the block files of new data directories (blocksxor); earlier versions cannot read them, not even to reindex.",
+		Remedy:        remedyResync,
+		Paths:         []string{"blocks", "chainstate", "indexes"},
+		Affects:       blocksObfuscated,
+	},
+	{
+		Software:      []string{"litecoin-core"},
+		Version:       "0.21.2",
+		DowngradeOnly: true,
+		Description:   "Litecoin Core 0.21.2 added MWEB data to the block index and chainstate; earlier versions need a full reindex.",
+		Remedy:        remedyReindex,
+		Paths:         []string{"chainstate", "indexes"},
+	},
+	{
+		Software:    []string{"ord", "ord-litecoin"},
+		Description: "ord changes its index schema between minor releases and refuses to open an index written by another one.",
+		Remedy:      remedyRebuildIndex,
+		Paths:       []string{"index.redb"},
+	},
+}
+
+// Returns the known incompatibilities of switching a repository (ex: fiftysix/bitcoin-core) between two versions
+func findIncompatibilities(repository string, from []int, to []int) []versionIncompatibility {
+	software := repository[strings.LastIndex(repository, "/")+1:]
+
+	var found []versionIncompatibility
+	for _, incompatibility := range versionIncompatibilities {
+		applies := false
+		for _, name := range incompatibility.Software {
+			applies = applies || name == software
+		}
+		if !applies {
+			continue
+		}
+
+		if incompatibility.DowngradeOnly {
+			boundary, _ := parseVersion(incompatibility.Version)
+			if compareVersions(from, boundary) >= 0 && compareVersions(to, boundary) < 0 {
+				found = append(found, incompatibility)
+			}
+		} else if releaseLine(from) != releaseLine(to) {
+			found = append(found, incompatibility)
+		}
+	}
+	return found
+}
+
+// Drops the incompatibilities the data under roots is not affected by
+func affectingIncompatibilities(incompatibilities []versionIncompatibility, roots map[string]string) []versionIncompatibility {
+	var affecting []versionIncompatibility
+	for _, incompatibility := range incompatibilities {
+		if incompatibility.Affects != nil && !incompatibility.Affects(findDataPaths(roots, incompatibility.Paths)) {
+			continue
+		}
+		affecting = append(affecting, incompatibility)
+	}
+	return affecting
+}
+
+// Reports whether any blocks directory has a non-zero obfuscation key. Bitcoin Core 28.0 only
+// generates one for block directories it creates, data upgraded from before keeps a zero key.
+func blocksObfuscated(found map[string][]string) bool {
+	for _, blocksDir := range found["blocks"] {
+		key, err := os.ReadFile(filepath.Join(blocksDir, "xor.dat"))
+		if err != nil {
+			continue
+		}
+		for _, b := range key {
+			if b != 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Parses a version tag such as 27.0, v0.20.1 or 0.21.2.1 (not latest or edge)
+func parseVersion(tag string) ([]int, bool) {
+	tag = strings.TrimPrefix(strings.ToLower(tag), "v")
+	if tag == "" {
+		return nil, false
+	}
+
+	var parts []int
+	for _, part := range strings.Split(tag, ".") {
+		number, err := strconv.Atoi(part)
+		if err != nil || number < 0 {
+			return nil, false
+		}
+		parts = append(parts, number)
+	}
+	return parts, true
+}
+
+// Compares two parsed versions, treating missing parts as zero (27 == 27.0)
+func compareVersions(a []int, b []int) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// Returns the release line (major.minor) of a version, ex: 0.20 for 0.20.1
+func releaseLine(version []int) string {
+	line := []int{0, 0}
+	copy(line, version)
+	return formatVersion(line)
+}
+
+func formatVersion(version []int) string {
+	parts := make([]string, len(version))
+	for i, part := range version {
+		parts[i] = strconv.Itoa(part)
+	}
+	return strings.Join(parts, ".")
+}
@@ -0,0 +1,211 @@
+/*
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 The Nodevin Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package nodes
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/docker/go-units"
+	"github.com/fiftysixcrypto/nodevin/internal/lockfile"
+	"github.com/fiftysixcrypto/nodevin/internal/logger"
+	"github.com/fiftysixcrypto/nodevin/internal/output"
+	"github.com/fiftysixcrypto/nodevin/internal/state"
+	"github.com/fiftysixcrypto/nodevin/internal/utils"
+	"github.com/fiftysixcrypto/nodevin/pkg/docker"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var versionsCmd = &cobra.Command{
+	Use:   "versions <network>",
+	Short: "List the available versions of a network's software and which are pulled or running",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		report, err := collectVersions(args[0])
+		if err != nil {
+			return err
+		}
+
+		if output.IsStructured() {
+			return output.Print(report)
+		}
+
+		displayVersions(report)
+		return nil
+	},
+}
+
+// VersionsReport is the output of `nodevin versions` in json or yaml mode
+type VersionsReport struct {
+	Network  string         `json:"network" yaml:"network"`
+	Image    string         `json:"image" yaml:"image"`
+	Versions []VersionEntry `json:"versions" yaml:"versions"`
+}
+
+// VersionEntry is one tag of a network's image
+type VersionEntry struct {
+	Tag       string    `json:"tag" yaml:"tag"`
+	Digest    string    `json:"digest" yaml:"digest"`
+	UpdatedAt time.Time `json:"updated_at" yaml:"updated_at"`
+	SizeBytes int64     `json:"size_bytes" yaml:"size_bytes"`
+	Pulled    bool      `json:"pulled" yaml:"pulled"`
+	// Deployments running this tag, or another tag with the same digest
+	Running []string `json:"running" yaml:"running"`
+}
+
+func collectVersions(arg string) (VersionsReport, error) {
+	network, err := resolveNetworkArg(arg)
+	if err != nil {
+		return VersionsReport{}, err
+	}
+
+	repository, err := getNetworkRepository(network)
+	if err != nil {
+		return VersionsReport{}, err
+	}
+
+	report := VersionsReport{Network: utils.JoinNetworkInstance(getSoftwareNetwork(network), utils.GetInstance()), Image: repository, Versions: []VersionEntry{}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), registryCheckTimeout)
+	tags, err := docker.ListRemoteImageTags(ctx, repository, viper.GetInt("versions-limit"))
+	cancel()
+	if err != nil {
+		return report, fmt.Errorf("failed to list versions of %s: %w", repository, err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), dockerListTimeout)
+	localTags, localDigests, err := docker.ListLocalImageTags(ctx, repository)
+	cancel()
+	if err != nil {
+		logger.LogError("Failed to list local Docker images: " + err.Error())
+	}
+
+	runningByTag, runningByDigest := runningImageVersions(repository)
+
+	for _, tag := range tags {
+		entry := VersionEntry{
+			Tag:       tag.Name,
+			Digest:    tag.Digest,
+			UpdatedAt: tag.LastUpdated,
+			SizeBytes: tag.SizeBytes,
+			Pulled:    localTags[tag.Name] || localDigests[tag.Digest],
+			Running:   []string{},
+		}
+
+		seen := map[string]bool{}
+		for _, name := range append(runningByTag[tag.Name], runningByDigest[tag.Digest]...) {
+			if !seen[name] {
+				seen[name] = true
+				entry.Running = append(entry.Running, name)
+			}
+		}
+
+		report.Versions = append(report.Versions, entry)
+	}
+
+	return report, nil
+}
+
+// Returns the image repository a network runs: --image, the recorded deployment's, or the default
+func getNetworkRepository(network string) (string, error) {
+	if image := viper.GetString("image"); image != "" {
+		return lockfile.Repository(image), nil
+	}
+
+	if deployment, exists, err := state.Get(getDeploymentName(network)); err == nil && exists {
+		if node, hasNode := deploymentNodeService(deployment); hasNode {
+			return lockfile.Repository(node.Image), nil
+		}
+	}
+
+	repository, exists := utils.GetNetworkImageRepository(getSoftwareNetwork(network))
+	if !exists {
+		return "", fmt.Errorf("unsupported network: %s", network)
+	}
+	return repository, nil
+}
+
+// Maps the tags and digests of a repository to the running deployments using them
+func runningImageVersions(repository string) (map[string][]string, map[string][]string) {
+	byTag := map[string][]string{}
+	byDigest := map[string][]string{}
+
+	for _, deployment := range listDeployments() {
+		if deployment.Status != state.StatusRunning {
+			continue
+		}
+		for _, service := range deployment.Services {
+			if lockfile.Repository(service.Image) != repository {
+				continue
+			}
+			if _, tag := splitImageTag(service.Image); tag != "" && !strings.Contains(service.Image, "@") {
+				byTag[tag] = append(byTag[tag], deployment.Name)
+			}
+			if strings.Contains(service.ImageDigest, "@") {
+				digest := docker.DigestOf(service.ImageDigest)
+				byDigest[digest] = append(byDigest[digest], deployment.Name)
+			}
+		}
+	}
+
+	return byTag, byDigest
+}
+
+func displayVersions(report VersionsReport) {
+	fmt.Printf("-- Versions of %s (%s):\n\n", report.Network, report.Image)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', tabwriter.Debug)
+	fmt.Fprintln(w, "| TAG\t UPDATED\t SIZE\t PULLED\t RUNNING")
+
+	for _, entry := range report.Versions {
+		updated := "-"
+		if !entry.UpdatedAt.IsZero() {
+			updated = units.HumanDuration(time.Since(entry.UpdatedAt)) + " ago"
+		}
+
+		pulled := "-"
+		if entry.Pulled {
+			pulled = "yes"
+		}
+
+		fmt.Fprintf(w, "| %s\t %s\t %s\t %s\t %s\n",
+			entry.Tag,
+			updated,
+			utils.GetSizeDescription(entry.SizeBytes),
+			pulled,
+			valueOrDash(strings.Join(entry.Running, ", ")),
+		)
+	}
+	w.Flush()
+
+	fmt.Println("")
+	fmt.Printf("Switch versions with `%s upgrade %s --to <tag>` or `%s downgrade %s --to <tag>`\n", utils.GetNodevinExecutable(), report.Network, utils.GetNodevinExecutable(), report.Network)
+}
+
+func init() {
+	versionsCmd.Flags().String("image", "", "Docker image to list the tags of (image name -- default: the network's image)")
+	bindOnRun(versionsCmd.Flags(), "image")
+	versionsCmd.Flags().Int("limit", 50, "Maximum number of tags to list, most recently updated first")
+	viper.BindPFlag("versions-limit", versionsCmd.Flags().Lookup("limit"))
+}
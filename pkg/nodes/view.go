@@ -1,130 +1,178 @@
 package nodes
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"os"
-	"os/exec"
-	"path/filepath"
 	"strings"
+	"sync"
 	"text/tabwriter"
 
 	"github.com/fiftysixcrypto/nodevin/internal/logger"
+	"github.com/fiftysixcrypto/nodevin/internal/output"
 	"github.com/fiftysixcrypto/nodevin/internal/utils"
+	"github.com/fiftysixcrypto/nodevin/pkg/docker"
 	"github.com/spf13/cobra"
 )
 
 var viewCmd = &cobra.Command{
 	Use:   "view",
 	Short: "View your Nodevin in a fun and artistic way!",
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		nodeSizes := fetchNodeSizes()
-		nodeStats := fetchNodeStats()
+		nodeStats, err := fetchNodeStats()
+		if err != nil {
+			return err
+		}
+
+		if output.IsStructured() {
+			for i := range nodeStats {
+				nodeStats[i].SizeBytes = nodeSizes[nodeStats[i].Network]
+			}
+			return output.Print(ViewReport{Nodes: nodeStats})
+		}
+
 		displayNodevinArt(nodeSizes, nodeStats)
+		return nil
 	},
 }
 
+// ViewReport is the output of `nodevin view` in json or yaml mode
+type ViewReport struct {
+	Nodes []NodeData `json:"nodes" yaml:"nodes"`
+}
+
 type NodeData struct {
-	Network     string `json:"network"`
-	Name        string `json:"name"`
-	Uptime      int    `json:"uptime"`
-	Peers       int    `json:"peers"`
-	LatestBlock int    `json:"latest_block"`
+	Network     string   `json:"network" yaml:"network"`
+	Name        string   `json:"name" yaml:"name"`
+	Uptime      int      `json:"uptime" yaml:"uptime"`
+	Peers       int      `json:"peers" yaml:"peers"`
+	LatestBlock int      `json:"latest_block" yaml:"latest_block"`
+	SizeBytes   int64    `json:"size_bytes" yaml:"size_bytes"`
+	Timeouts    []string `json:"timeouts,omitempty" yaml:"timeouts,omitempty"`
 }
 
 func fetchNodeSizes() map[string]int64 {
-	nodevinDataDir, err := utils.GetNodevinDataDir()
-	if err != nil {
-		logger.LogError("Failed to find Nodevin data directory: " + err.Error())
-		return nil
-	}
-
 	networks := utils.GetAllSupportedNetworks()
 	if networks == "" {
-		fmt.Println("No data found.")
 		return nil
 	}
 
+	var mu sync.Mutex
+	var wg sync.WaitGroup
 	nodeSizes := make(map[string]int64)
 	for _, network := range strings.Split(networks, ", ") {
-		containerName, exists := utils.GetDefaultLocalMappedContainerName(network)
-		if !exists {
-			logger.LogError("Unsupported blockchain network: " + network)
+		locations, err := utils.GetNetworkDataLocations(network)
+		if err != nil {
+			logger.LogError("Failed to find data locations for " + network + ": " + err.Error())
 			continue
 		}
 
-		networkDir := filepath.Join(nodevinDataDir, containerName)
-		size, err := getDirectorySize(networkDir)
-		if err == nil {
-			nodeSizes[network] = size
+		// Sum every directory the network uses (data, blocks, index)
+		for _, location := range locations {
+			wg.Add(1)
+			go func(network string, path string) {
+				defer wg.Done()
+				size, err := collectWithTimeout(directorySizeTimeout, func(ctx context.Context) (int64, error) {
+					return getDirectorySize(path)
+				})
+				if err == nil {
+					mu.Lock()
+					nodeSizes[network] += size
+					mu.Unlock()
+				}
+			}(network, location.Path)
 		}
 	}
+	wg.Wait()
+
 	return nodeSizes
 }
 
-func fetchNodeStats() []NodeData {
-	args := []string{"ps", "--format", "{{json .}}"}
-	cmd := exec.Command("docker", args...)
-	output, err := cmd.CombinedOutput()
+func fetchNodeStats() ([]NodeData, error) {
+	containers, err := listNodevinContainers(docker.RoleNode)
 	if err != nil {
-		logger.LogError("Failed to fetch Docker container information: " + err.Error())
-		return nil
+		return nil, fmt.Errorf("failed to fetch Docker container information: %w", err)
 	}
 
-	containers := strings.Split(string(output), "\n")
-	var nodes []NodeData
-	for _, containerJSON := range containers {
-		if strings.TrimSpace(containerJSON) == "" {
-			continue
-		}
-		var container ContainerInfo
-		if err := json.Unmarshal([]byte(containerJSON), &container); err != nil {
-			logger.LogError("Failed to parse container JSON: " + err.Error())
-			continue
-		}
-		if !utils.IsSupportedExtendedInfoSoftware(container.Names) {
-			continue
+	var selected []managedContainer
+	for _, container := range containers {
+		if utils.IsSupportedExtendedInfoNetwork(container.Network) {
+			selected = append(selected, container)
 		}
+	}
 
-		peers := getPeers(container.Names)
-		localLatestBlock, _ := getLatestBlocks(container.Names)
-		nodes = append(nodes, NodeData{
-			Network:     getSoftwareNetworkName(container.Names),
-			Name:        container.Names,
-			Uptime:      extractUptime(container.Status),
-			Peers:       peers,
-			LatestBlock: localLatestBlock,
+	nodes := make([]NodeData, len(selected))
+	var wg sync.WaitGroup
+	for i, container := range selected {
+		wg.Add(1)
+		go func(i int, container managedContainer) {
+			defer wg.Done()
+			nodes[i] = fetchNodeData(container)
+		}(i, container)
+	}
+	wg.Wait()
+
+	return nodes, nil
+}
+
+func fetchNodeData(container managedContainer) NodeData {
+	node := NodeData{
+		Network: container.Network,
+		Name:    container.Names,
+		Uptime:  extractUptime(container.Status),
+	}
+
+	var peersErr, latestBlockErr error
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		node.Peers, peersErr = collectWithTimeout(nodeRPCTimeout, func(ctx context.Context) (int, error) {
+			return getPeers(ctx, container.Target())
 		})
+	}()
+	go func() {
+		defer wg.Done()
+		node.LatestBlock, latestBlockErr = collectWithTimeout(nodeRPCTimeout, func(ctx context.Context) (int, error) {
+			return getLocalLatestBlock(ctx, container.Target())
+		})
+	}()
+	wg.Wait()
+
+	if errors.Is(peersErr, errCollectTimeout) {
+		node.Timeouts = append(node.Timeouts, "peers")
+	}
+	if errors.Is(latestBlockErr, errCollectTimeout) {
+		node.Timeouts = append(node.Timeouts, "latest_block")
 	}
-	return nodes
+
+	return node
+}
+
+func (node NodeData) metricDescription(source string, value int) string {
+	for _, timeout := range node.Timeouts {
+		if timeout == source {
+			return "timeout"
+		}
+	}
+	return fmt.Sprintf("%d", value)
 }
 
 func getNodevinName(network string) string {
-	switch strings.ToLower(network) {
-	case "bitcoin-core":
+	switch strings.SplitN(strings.ToLower(network), "-", 2)[0] {
+	case "bitcoin":
 		return "Bitvin"
-	case "litecoin-core":
+	case "litecoin":
 		return "Litevin"
-	case "dogecoin-core":
+	case "dogecoin":
 		return "Dogevin"
 	default:
 		return "Nodevin"
 	}
 }
 
-func getSoftwareNetworkName(softwareName string) string {
-	switch softwareName {
-	case "bitcoin-core":
-		return "bitcoin"
-	case "litecoin-core":
-		return "litecoin"
-	case "dogecoin-core":
-		return "dogecoin"
-	default:
-		return ""
-	}
-}
-
 func extractUptime(status string) int {
 	if strings.Contains(status, "days") {
 		var days int
@@ -145,7 +193,7 @@ func displayNodevinArt(nodeSizes map[string]int64, nodes []NodeData) {
 
 	for _, node := range nodes {
 		nodeSize := nodeSizes[node.Network]
-		vinName := getNodevinName(node.Name)
+		vinName := getNodevinName(node.Network)
 		fmt.Printf("\n%s Status:\n\n", vinName)
 
 		var art string
@@ -186,8 +234,12 @@ func displayNodeDetails(node NodeData, size int64) {
 	fmt.Fprintln(w, "| METRIC\t VALUE")
 	fmt.Fprintf(w, "| Network\t %s\n", node.Network)
 	fmt.Fprintf(w, "| Uptime\t %d days\n", node.Uptime)
-	fmt.Fprintf(w, "| Peers\t %d\n", node.Peers)
-	fmt.Fprintf(w, "| Latest Block\t %d\n", node.LatestBlock)
+	fmt.Fprintf(w, "| Peers\t %s\n", node.metricDescription("peers", node.Peers))
+	fmt.Fprintf(w, "| Latest Block\t %s\n", node.metricDescription("latest_block", node.LatestBlock))
 	fmt.Fprintf(w, "| Size\t %s\n", utils.GetSizeDescription(size))
 	w.Flush()
 }
+
+func init() {
+	addRPCFlags(viewCmd.Flags())
+}
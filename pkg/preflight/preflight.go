@@ -0,0 +1,340 @@
+/*
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 The Nodevin Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package preflight
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/docker/go-units"
+	"github.com/fiftysixcrypto/nodevin/internal/sizeindex"
+	"github.com/fiftysixcrypto/nodevin/internal/system"
+	"github.com/fiftysixcrypto/nodevin/internal/utils"
+	"github.com/spf13/viper"
+)
+
+const (
+	StatusOK   = "ok"
+	StatusWarn = "warn"
+	StatusFail = "fail"
+)
+
+// Minimum free inodes for a chain data directory (block files plus LevelDB tables)
+const minFreeInodes = 100000
+
+// Share of a full node's data kept with the chainstate when block files live elsewhere
+const chainstateShareOfData = 0.1
+
+// Check is the result of a single preflight inspection.
+type Check struct {
+	Name    string `json:"name" yaml:"name"`
+	Status  string `json:"status" yaml:"status"`
+	Message string `json:"message" yaml:"message"`
+}
+
+// Report collects every check run before starting a node.
+type Report struct {
+	Checks []Check `json:"checks" yaml:"checks"`
+}
+
+// StorageTarget is a directory that will receive node data and the space it needs.
+type StorageTarget struct {
+	Label         string
+	Path          string
+	RequiredBytes int64
+}
+
+// Requirements describes what a node start needs from the host.
+type Requirements struct {
+	Targets        []StorageTarget
+	CPULimit       string
+	MemLimit       string
+	CPUReservation string
+	MemReservation string
+}
+
+func (r *Report) add(name, status, message string) {
+	r.Checks = append(r.Checks, Check{Name: name, Status: status, Message: message})
+}
+
+// Failed reports whether any check blocks the start.
+func (r Report) Failed() bool {
+	for _, check := range r.Checks {
+		if check.Status == StatusFail {
+			return true
+		}
+	}
+	return false
+}
+
+// Print writes the report as a table.
+func (r Report) Print() {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', tabwriter.Debug)
+	fmt.Fprintln(w, "| CHECK\t STATUS\t DETAILS")
+	for _, check := range r.Checks {
+		fmt.Fprintf(w, "| %s\t %s\t %s\n", check.Name, strings.ToUpper(check.Status), check.Message)
+	}
+	w.Flush()
+}
+
+// Run inspects disk space, filesystems, inodes, memory and CPUs against the requirements.
+func Run(req Requirements) Report {
+	report := Report{}
+
+	checkStorage(&report, req.Targets)
+	checkMemory(&report, req.MemLimit, req.MemReservation)
+	checkCPU(&report, req.CPULimit, req.CPUReservation)
+
+	return report
+}
+
+func checkStorage(report *Report, targets []StorageTarget) {
+	// Targets on the same filesystem share its free space
+	type filesystemUsage struct {
+		stats    system.DiskStats
+		paths    []string
+		required int64
+	}
+	var order []string
+	usageByDevice := make(map[string]*filesystemUsage)
+
+	for _, target := range targets {
+		name := fmt.Sprintf("disk (%s)", target.Label)
+
+		existingPath := nearestExistingPath(target.Path)
+		stats, err := system.GetDiskStats(existingPath)
+		if err != nil {
+			report.add(name, StatusWarn, "Unable to inspect filesystem: "+err.Error())
+			continue
+		}
+
+		if reason, unsupported := system.IsUnsupportedFilesystem(stats.FSType); unsupported {
+			report.add(fmt.Sprintf("filesystem (%s)", target.Label), StatusFail, fmt.Sprintf("%s is on a %s (%s), which is known to corrupt LevelDB chain data", target.Path, reason, stats.FSType))
+		} else if reason, discouraged := system.IsDiscouragedFilesystem(stats.FSType); discouraged {
+			report.add(fmt.Sprintf("filesystem (%s)", target.Label), StatusWarn, fmt.Sprintf("%s is on a %s (%s), which can be slow or unreliable for chain data", target.Path, reason, stats.FSType))
+		} else if stats.FSType != "" {
+			report.add(fmt.Sprintf("filesystem (%s)", target.Label), StatusOK, fmt.Sprintf("%s (%s)", stats.FSType, target.Path))
+		}
+
+		if stats.TotalInodes > 0 {
+			if stats.FreeInodes < minFreeInodes {
+				report.add(fmt.Sprintf("inodes (%s)", target.Label), StatusFail, fmt.Sprintf("only %d free inodes, at least %d recommended", stats.FreeInodes, minFreeInodes))
+			} else {
+				report.add(fmt.Sprintf("inodes (%s)", target.Label), StatusOK, fmt.Sprintf("%d free", stats.FreeInodes))
+			}
+		}
+
+		// Without a device id (unsupported platforms) every path is checked on its own
+		device := stats.Device
+		if device == "" {
+			device = existingPath
+		}
+		usage, exists := usageByDevice[device]
+		if !exists {
+			usage = &filesystemUsage{stats: stats}
+			usageByDevice[device] = usage
+			order = append(order, device)
+		}
+		usage.paths = append(usage.paths, target.Path)
+		usage.required += remainingBytes(target)
+	}
+
+	for _, device := range order {
+		usage := usageByDevice[device]
+		name := "disk space (" + strings.Join(usage.paths, ", ") + ")"
+		available := int64(usage.stats.AvailableBytes)
+
+		if usage.required <= 0 {
+			report.add(name, StatusOK, fmt.Sprintf("%s free, no known size requirement", utils.GetSizeDescription(available)))
+		} else if available < usage.required {
+			report.add(name, StatusFail, fmt.Sprintf("%s free, %s required", utils.GetSizeDescription(available), utils.GetSizeDescription(usage.required)))
+		} else {
+			report.add(name, StatusOK, fmt.Sprintf("%s free, %s required", utils.GetSizeDescription(available), utils.GetSizeDescription(usage.required)))
+		}
+	}
+}
+
+// Data already on disk counts towards the requirement (ex: restarting a synced node). It is
+// sized from the cached size index, so starting a synced node does not walk its whole chain.
+func remainingBytes(target StorageTarget) int64 {
+	if target.RequiredBytes <= 0 {
+		return 0
+	}
+
+	existing, err := sizeindex.DirectorySize(target.Path)
+	if err != nil {
+		return target.RequiredBytes
+	}
+
+	remaining := target.RequiredBytes - existing
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+func checkMemory(report *Report, memLimit, memReservation string) {
+	totalMemory, err := system.GetTotalMemory()
+	if err != nil {
+		report.add("memory", StatusWarn, "Unable to inspect memory: "+err.Error())
+		return
+	}
+
+	for _, requested := range []struct {
+		name  string
+		value string
+	}{{"mem-limit", memLimit}, {"mem-reservation", memReservation}} {
+		if requested.value == "" {
+			continue
+		}
+
+		bytes, err := ParseMemory(requested.value)
+		if err != nil {
+			report.add(requested.name, StatusFail, err.Error())
+			continue
+		}
+
+		if uint64(bytes) > totalMemory {
+			report.add(requested.name, StatusFail, fmt.Sprintf("%s requested but host only has %s", utils.GetSizeDescription(bytes), utils.GetSizeDescription(int64(totalMemory))))
+		} else {
+			report.add(requested.name, StatusOK, fmt.Sprintf("%s of %s", utils.GetSizeDescription(bytes), utils.GetSizeDescription(int64(totalMemory))))
+		}
+	}
+
+	if memLimit == "" && memReservation == "" {
+		report.add("memory", StatusOK, utils.GetSizeDescription(int64(totalMemory))+" total")
+	}
+}
+
+func checkCPU(report *Report, cpuLimit, cpuReservation string) {
+	cpuCount := system.GetCPUCount()
+
+	for _, requested := range []struct {
+		name  string
+		value string
+	}{{"cpu-limit", cpuLimit}, {"cpu-reservation", cpuReservation}} {
+		if requested.value == "" {
+			continue
+		}
+
+		cpus, err := strconv.ParseFloat(requested.value, 64)
+		if err != nil || cpus <= 0 {
+			report.add(requested.name, StatusFail, fmt.Sprintf("invalid CPU amount: %s", requested.value))
+			continue
+		}
+
+		if cpus > float64(cpuCount) {
+			report.add(requested.name, StatusFail, fmt.Sprintf("%s CPUs requested but host only has %d", requested.value, cpuCount))
+		} else {
+			report.add(requested.name, StatusOK, fmt.Sprintf("%s of %d CPUs", requested.value, cpuCount))
+		}
+	}
+
+	if cpuLimit == "" && cpuReservation == "" {
+		report.add("cpu", StatusOK, fmt.Sprintf("%d CPUs", cpuCount))
+	}
+}
+
+// ParseMemory converts a docker memory amount (ex: 512m, 4g) to bytes.
+func ParseMemory(value string) (int64, error) {
+	amount, err := units.RAMInBytes(strings.TrimSpace(value))
+	if err != nil || amount <= 0 {
+		return 0, fmt.Errorf("invalid memory amount: %s", value)
+	}
+
+	return amount, nil
+}
+
+// Walks up from path until an existing directory is found, so targets that
+// have not been created yet are measured on the filesystem they will land on
+func nearestExistingPath(path string) string {
+	current := path
+	for {
+		if _, err := os.Stat(current); err == nil {
+			return current
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return current
+		}
+		current = parent
+	}
+}
+
+// Setting and label of the separate directory software keeps most of its data in, ex: raw
+// block files under --blocks-dir for bitcoin. Other software ignores both settings.
+var separateDataDirs = map[string]struct {
+	setting string
+	label   string
+}{
+	"bitcoin":      {"blocks-dir", "blocks"},
+	"litecoin":     {"blocks-dir", "blocks"},
+	"ord":          {"index-dir", "index"},
+	"ord-litecoin": {"index-dir", "index"},
+}
+
+// StorageTargets builds the storage targets for a network and its sidecars, splitting
+// requirements across --blocks-dir and --index-dir when they are set for software that uses them.
+func StorageTargets(network string, sidecars []string) ([]StorageTarget, error) {
+	nodevinDataDir, err := utils.GetNodevinDataDir()
+	if err != nil {
+		return nil, err
+	}
+
+	var targets []StorageTarget
+
+	for _, software := range append([]string{network}, sidecars...) {
+		containerName, exists := utils.GetDefaultLocalMappedContainerName(software)
+		if !exists {
+			return nil, fmt.Errorf("unsupported blockchain network: %s", software)
+		}
+		dataSize, _ := utils.GetNetworkRequiredDataSize(software)
+		localPath := filepath.Join(nodevinDataDir, utils.InstanceName(containerName))
+
+		separateDir := ""
+		separateLabel := ""
+		base, _ := utils.SplitNetworkVariant(software)
+		if separate, exists := separateDataDirs[base]; exists {
+			separateDir, separateLabel = viper.GetString(separate.setting), separate.label
+		}
+
+		if separateDir == "" {
+			targets = append(targets, StorageTarget{Label: software, Path: localPath, RequiredBytes: int64(dataSize)})
+			continue
+		}
+
+		resolvedDir, err := utils.ResolveLocalPath(separateDir)
+		if err != nil {
+			return nil, err
+		}
+
+		// Block files (or the ord index) make up most of the data
+		localShare := int64(float64(dataSize) * chainstateShareOfData)
+		targets = append(targets,
+			StorageTarget{Label: software, Path: localPath, RequiredBytes: localShare},
+			StorageTarget{Label: software + " " + separateLabel, Path: filepath.Join(resolvedDir, utils.InstanceName(containerName)), RequiredBytes: int64(dataSize) - localShare},
+		)
+	}
+
+	return targets, nil
+}
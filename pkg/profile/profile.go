@@ -0,0 +1,255 @@
+/*
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 The Nodevin Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package profile
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fiftysixcrypto/nodevin/internal/system"
+)
+
+const (
+	ProfileAuto     = "auto"
+	ProfileLow      = "low"
+	ProfileStandard = "standard"
+	ProfileServer   = "server"
+
+	PhaseInitialSync = "initial-sync"
+	PhaseSynced      = "synced"
+)
+
+const (
+	MB = 1 << 20
+	GB = 1 << 30
+)
+
+// Name of the file (inside a network's data directory) recording the applied profile
+const profileFileName = ".nodevin-profile.json"
+
+// Memory kept free inside the container for the daemon itself, peers and indexes
+const daemonOverheadMB = 512
+
+// Settings are the docker resource limits and daemon tuning chosen for a node.
+type Settings struct {
+	Profile        string    `json:"profile" yaml:"profile"`
+	Phase          string    `json:"phase" yaml:"phase"`
+	CPULimit       string    `json:"cpu_limit" yaml:"cpu_limit"`
+	MemLimit       string    `json:"mem_limit" yaml:"mem_limit"`
+	CPUReservation string    `json:"cpu_reservation" yaml:"cpu_reservation"`
+	MemReservation string    `json:"mem_reservation" yaml:"mem_reservation"`
+	DBCacheMB      int       `json:"dbcache_mb" yaml:"dbcache_mb"`
+	Par            int       `json:"par" yaml:"par"`
+	MaxMempoolMB   int       `json:"maxmempool_mb" yaml:"maxmempool_mb"`
+	HostCPUs       int       `json:"host_cpus" yaml:"host_cpus"`
+	HostMemory     uint64    `json:"host_memory" yaml:"host_memory"`
+	RotationalDisk bool      `json:"rotational_disk" yaml:"rotational_disk"`
+	AppliedAt      time.Time `json:"applied_at" yaml:"applied_at"`
+}
+
+// Hardware is the host information used to size a profile.
+type Hardware struct {
+	CPUs       int
+	Memory     uint64
+	Rotational bool
+}
+
+// Validates a --profile value.
+func IsValidProfile(name string) bool {
+	switch name {
+	case ProfileAuto, ProfileLow, ProfileStandard, ProfileServer:
+		return true
+	default:
+		return false
+	}
+}
+
+// Detects the host hardware relevant to sizing, using dataDir to find the disk.
+func DetectHardware(dataDir string) Hardware {
+	hardware := Hardware{CPUs: system.GetCPUCount()}
+
+	if memory, err := system.GetTotalMemory(); err == nil {
+		hardware.Memory = memory
+	}
+
+	if rotational, err := system.IsRotational(dataDir); err == nil {
+		hardware.Rotational = rotational
+	}
+
+	return hardware
+}
+
+// Picks a concrete profile for auto based on the host memory and cores.
+func autoProfile(hardware Hardware) string {
+	switch {
+	case hardware.Memory == 0 || hardware.Memory < 4*GB || hardware.CPUs < 2:
+		return ProfileLow
+	case hardware.Memory < 16*GB || hardware.CPUs < 8:
+		return ProfileStandard
+	default:
+		return ProfileServer
+	}
+}
+
+// Resolve chooses docker resource limits and daemon parameters together for a
+// profile. During initial sync a larger dbcache is used, especially on
+// rotational disks where flushing the UTXO set is expensive.
+func Resolve(name string, hardware Hardware, initialSync bool) (Settings, error) {
+	if !IsValidProfile(name) {
+		return Settings{}, fmt.Errorf("unknown profile: %s (expected auto, low, standard or server)", name)
+	}
+
+	if name == ProfileAuto {
+		name = autoProfile(hardware)
+	}
+
+	cpus := hardware.CPUs
+	if cpus < 1 {
+		cpus = 1
+	}
+	hostMemoryMB := int(hardware.Memory / MB)
+
+	settings := Settings{
+		Profile:        name,
+		Phase:          PhaseSynced,
+		HostCPUs:       cpus,
+		HostMemory:     hardware.Memory,
+		RotationalDisk: hardware.Rotational,
+		AppliedAt:      time.Now().UTC(),
+	}
+	if initialSync {
+		settings.Phase = PhaseInitialSync
+	}
+
+	var cpuLimit float64
+	var memLimitMB, syncedDBCacheMB, initialDBCacheMB int
+
+	switch name {
+	case ProfileLow:
+		cpuLimit = 1
+		memLimitMB = minInt(2048, hostMemoryMB/2)
+		syncedDBCacheMB, initialDBCacheMB = 300, 450
+		settings.Par = 1
+		settings.MaxMempoolMB = 100
+	case ProfileStandard:
+		cpuLimit = float64(maxInt(1, cpus/2))
+		memLimitMB = hostMemoryMB / 2
+		syncedDBCacheMB, initialDBCacheMB = 1024, 4096
+		settings.Par = maxInt(1, cpus/2)
+		settings.MaxMempoolMB = 300
+	case ProfileServer:
+		cpuLimit = float64(maxInt(1, cpus-1))
+		memLimitMB = hostMemoryMB * 3 / 4
+		syncedDBCacheMB, initialDBCacheMB = 4096, 16384
+		settings.Par = maxInt(1, cpus-1)
+		settings.MaxMempoolMB = 1000
+	}
+
+	// Solid state disks handle frequent UTXO flushes well, so a smaller IBD cache is enough
+	if !hardware.Rotational {
+		initialDBCacheMB = maxInt(syncedDBCacheMB, initialDBCacheMB/2)
+	}
+
+	settings.DBCacheMB = syncedDBCacheMB
+	if initialSync {
+		settings.DBCacheMB = initialDBCacheMB
+	}
+
+	// Keep the daemon caches inside the container memory limit
+	if memLimitMB > 0 {
+		available := memLimitMB - settings.MaxMempoolMB - daemonOverheadMB
+		if available < 100 {
+			available = 100
+		}
+		if settings.DBCacheMB > available {
+			settings.DBCacheMB = available
+		}
+
+		settings.MemLimit = fmt.Sprintf("%dm", memLimitMB)
+		settings.MemReservation = fmt.Sprintf("%dm", minInt(memLimitMB, settings.DBCacheMB+daemonOverheadMB))
+	}
+
+	settings.CPULimit = strings.TrimSuffix(fmt.Sprintf("%.1f", cpuLimit), ".0")
+	settings.CPUReservation = "0.5"
+
+	return settings, nil
+}
+
+// Returns the daemon arguments for bitcoin-core style nodes.
+func (s Settings) DaemonArgs() string {
+	return fmt.Sprintf("-dbcache=%d -par=%d -maxmempool=%d", s.DBCacheMB, s.Par, s.MaxMempoolMB)
+}
+
+// Records the applied profile next to a network's data, or removes a stale record when settings is nil.
+func Record(localPath string, settings *Settings) error {
+	profileFile := filepath.Join(localPath, profileFileName)
+
+	if settings == nil {
+		if err := os.Remove(profileFile); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove profile record: %w", err)
+		}
+		return nil
+	}
+
+	data, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode profile record: %w", err)
+	}
+
+	if err := os.WriteFile(profileFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write profile record: %w", err)
+	}
+
+	return nil
+}
+
+// Returns the profile recorded for the data at localPath, if any.
+func Read(localPath string) (*Settings, error) {
+	data, err := os.ReadFile(filepath.Join(localPath, profileFileName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read profile record: %w", err)
+	}
+
+	var settings Settings
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return nil, fmt.Errorf("failed to parse profile record: %w", err)
+	}
+
+	return &settings, nil
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,119 @@
+/*
+// SPDX-License-Identifier: Apache-2.0
+//
+// Copyright 2024 The Nodevin Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package update
+
+import (
+	"strconv"
+	"strings"
+)
+
+// A parsed release version, ex: v0.2.0 or 0.2.0-rc.1
+type semver struct {
+	Major, Minor, Patch int
+	Prerelease          string
+}
+
+// Parses a version with an optional leading v, ignoring build metadata (+...)
+func parseSemver(version string) (semver, bool) {
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if i := strings.Index(version, "+"); i >= 0 {
+		version = version[:i]
+	}
+
+	var parsed semver
+	if i := strings.Index(version, "-"); i >= 0 {
+		parsed.Prerelease = version[i+1:]
+		version = version[:i]
+	}
+
+	parts := strings.Split(version, ".")
+	if len(parts) != 3 {
+		return semver{}, false
+	}
+
+	numbers := make([]int, 3)
+	for i, part := range parts {
+		number, err := strconv.Atoi(part)
+		if err != nil || number < 0 {
+			return semver{}, false
+		}
+		numbers[i] = number
+	}
+	parsed.Major, parsed.Minor, parsed.Patch = numbers[0], numbers[1], numbers[2]
+
+	return parsed, true
+}
+
+// Compares two versions by semver precedence, a release is newer than its prereleases
+func compareSemver(a semver, b semver) int {
+	for _, pair := range [][2]int{{a.Major, b.Major}, {a.Minor, b.Minor}, {a.Patch, b.Patch}} {
+		if pair[0] != pair[1] {
+			if pair[0] < pair[1] {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	switch {
+	case a.Prerelease == b.Prerelease:
+		return 0
+	case a.Prerelease == "":
+		return 1
+	case b.Prerelease == "":
+		return -1
+	}
+	return comparePrerelease(a.Prerelease, b.Prerelease)
+}
+
+// Compares dot-separated prerelease identifiers, numeric ones numerically and below alphanumeric ones
+func comparePrerelease(a string, b string) int {
+	left, right := strings.Split(a, "."), strings.Split(b, ".")
+
+	for i := 0; i < len(left) && i < len(right); i++ {
+		x, xErr := strconv.Atoi(left[i])
+		y, yErr := strconv.Atoi(right[i])
+
+		switch {
+		case xErr == nil && yErr == nil:
+			if x != y {
+				if x < y {
+					return -1
+				}
+				return 1
+			}
+		case xErr == nil:
+			return -1
+		case yErr == nil:
+			return 1
+		default:
+			if c := strings.Compare(left[i], right[i]); c != 0 {
+				return c
+			}
+		}
+	}
+
+	switch {
+	case len(left) < len(right):
+		return -1
+	case len(left) > len(right):
+		return 1
+	}
+	return 0
+}
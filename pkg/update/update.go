@@ -19,83 +19,393 @@
 package update
 
 import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
-	"os/exec"
+	"path/filepath"
 	"runtime"
+	"strings"
+	"time"
 
-	"github.com/fiftysixcrypto/nodevin/internal/logger"
+	"github.com/fiftysixcrypto/nodevin/internal/version"
 )
 
 const (
-	updateURL      = "https://github.com/fiftysixcrypto/nodevin/releases/latest/download/nodevin"
-	currentVersion = "1.0.0"
+	latestReleaseURL = "https://api.github.com/repos/fiftysixcrypto/nodevin/releases/latest"
+	releaseTimeout   = 30 * time.Second
+	downloadTimeout  = 10 * time.Minute
+
+	// The replaced executable is kept next to the new one for `nodevin update --rollback`
+	previousSuffix = ".previous"
+
+	// Largest executable accepted from a release archive
+	maxExecutableSize = 512 << 20
 )
 
-func CheckForUpdates() (bool, error) {
-	resp, err := http.Get("https://api.github.com/repos/fiftysixcrypto/nodevin/releases/latest")
+// Release is a published nodevin release on GitHub
+type Release struct {
+	TagName string  `json:"tag_name"`
+	Assets  []Asset `json:"assets"`
+}
+
+// Asset is a file attached to a release
+type Asset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+// Returns the latest release and whether it is newer than the running version
+func CheckForUpdates() (*Release, bool, error) {
+	current, ok := parseSemver(version.Version)
+	if !ok {
+		return nil, false, fmt.Errorf("cannot parse running version %s", version.Version)
+	}
+
+	release, err := fetchLatestRelease()
 	if err != nil {
-		return false, err
+		return nil, false, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return false, fmt.Errorf("failed to fetch update info: %s", resp.Status)
+	latest, ok := parseSemver(release.TagName)
+	if !ok {
+		return nil, false, fmt.Errorf("cannot parse release version %s", release.TagName)
 	}
 
-	var release struct {
-		TagName string `json:"tag_name"`
+	return release, compareSemver(latest, current) > 0, nil
+}
+
+func fetchLatestRelease() (*Release, error) {
+	resp, err := httpGet(latestReleaseURL, releaseTimeout)
+	if err != nil {
+		return nil, err
 	}
+	defer resp.Body.Close()
+
+	var release Release
 	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
-		return false, err
+		return nil, fmt.Errorf("failed to parse release info: %w", err)
 	}
+	return &release, nil
+}
 
-	if release.TagName == currentVersion {
-		return false, nil
+// Returns the name of the release archive for this platform, ex: nodevin-macos-arm64-v0.2.0.tar.gz
+func assetName(tag string) string {
+	osName := runtime.GOOS
+	if osName == "darwin" {
+		osName = "macos"
 	}
 
-	logger.LogInfo(fmt.Sprintf("New version available: %s", release.TagName))
-	if err := downloadUpdate(); err != nil {
-		return false, fmt.Errorf("failed to download update: %w", err)
+	extension := ".tar.gz"
+	if runtime.GOOS == "windows" {
+		extension = ".zip"
 	}
 
-	return true, nil
+	return fmt.Sprintf("nodevin-%s-%s-%s%s", osName, runtime.GOARCH, tag, extension)
 }
 
-func downloadUpdate() error {
-	resp, err := http.Get(updateURL)
+func (r *Release) findAsset(name string) (Asset, bool) {
+	for _, asset := range r.Assets {
+		if asset.Name == name {
+			return asset, true
+		}
+	}
+	return Asset{}, false
+}
+
+// Downloads the release archive for this platform, verifies it against the published
+// .sha256 and extracts the executable next to the running one. Returns its path.
+func DownloadUpdate(release *Release) (string, error) {
+	name := assetName(release.TagName)
+	archiveAsset, exists := release.findAsset(name)
+	if !exists {
+		return "", fmt.Errorf("release %s has no %s asset for this platform", release.TagName, name)
+	}
+	checksumAsset, exists := release.findAsset(name + ".sha256")
+	if !exists {
+		return "", fmt.Errorf("release %s has no checksum for %s, refusing to install it", release.TagName, name)
+	}
+
+	expected, err := fetchChecksum(checksumAsset.URL)
 	if err != nil {
-		return err
+		return "", err
+	}
+
+	archive, err := os.CreateTemp("", "nodevin-update-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create download file: %w", err)
+	}
+	defer os.Remove(archive.Name())
+	defer archive.Close()
+
+	resp, err := httpGet(archiveAsset.URL, downloadTimeout)
+	if err != nil {
+		return "", err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to fetch update: %s", resp.Status)
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(archive, hash), resp.Body); err != nil {
+		return "", fmt.Errorf("failed to download %s: %w", name, err)
+	}
+
+	if actual := hex.EncodeToString(hash.Sum(nil)); actual != expected {
+		return "", fmt.Errorf("checksum mismatch for %s: expected %s, got %s", name, expected, actual)
+	}
+
+	executable, err := executablePath()
+	if err != nil {
+		return "", err
+	}
+
+	// Extract into the executable's directory so the final rename stays on one filesystem
+	newExecutable, err := os.CreateTemp(filepath.Dir(executable), ".nodevin-update-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to write to %s: %w", filepath.Dir(executable), err)
+	}
+
+	if strings.HasSuffix(name, ".zip") {
+		err = extractZipExecutable(archive, newExecutable)
+	} else {
+		err = extractTarExecutable(archive, newExecutable)
+	}
+	if closeErr := newExecutable.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(newExecutable.Name(), 0755)
+	}
+	if err != nil {
+		os.Remove(newExecutable.Name())
+		return "", fmt.Errorf("failed to extract %s: %w", name, err)
+	}
+
+	return newExecutable.Name(), nil
+}
+
+// Reads the hex digest from a .sha256 file in `shasum -a 256` format
+func fetchChecksum(url string) (string, error) {
+	resp, err := httpGet(url, releaseTimeout)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if err != nil {
+		return "", fmt.Errorf("failed to download checksum: %w", err)
+	}
+
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 || len(fields[0]) != sha256.Size*2 {
+		return "", fmt.Errorf("malformed checksum file")
+	}
+	if _, err := hex.DecodeString(fields[0]); err != nil {
+		return "", fmt.Errorf("malformed checksum file")
+	}
+
+	return strings.ToLower(fields[0]), nil
+}
+
+// Reports whether an archive entry is the nodevin executable (named nodevin in every release archive)
+func isExecutableEntry(name string) bool {
+	base := filepath.Base(filepath.ToSlash(name))
+	return base == "nodevin" || base == "nodevin.exe"
+}
+
+func extractTarExecutable(archive *os.File, out io.Writer) error {
+	if _, err := archive.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	gz, err := gzip.NewReader(archive)
+	if err != nil {
+		return err
 	}
+	defer gz.Close()
 
-	out, err := os.Create("nodevin_new")
+	reader := tar.NewReader(gz)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return fmt.Errorf("archive has no nodevin executable")
+		}
+		if err != nil {
+			return err
+		}
+		if header.Typeflag != tar.TypeReg || !isExecutableEntry(header.Name) {
+			continue
+		}
+		return copyExecutable(out, reader)
+	}
+}
+
+func extractZipExecutable(archive *os.File, out io.Writer) error {
+	info, err := archive.Stat()
 	if err != nil {
 		return err
 	}
-	defer out.Close()
 
-	_, err = io.Copy(out, resp.Body)
+	reader, err := zip.NewReader(archive, info.Size())
 	if err != nil {
 		return err
 	}
 
+	for _, file := range reader.File {
+		if file.FileInfo().IsDir() || !isExecutableEntry(file.Name) {
+			continue
+		}
+		entry, err := file.Open()
+		if err != nil {
+			return err
+		}
+		defer entry.Close()
+		return copyExecutable(out, entry)
+	}
+	return fmt.Errorf("archive has no nodevin executable")
+}
+
+func copyExecutable(out io.Writer, in io.Reader) error {
+	written, err := io.Copy(out, io.LimitReader(in, maxExecutableSize+1))
+	if err != nil {
+		return err
+	}
+	if written > maxExecutableSize {
+		return fmt.Errorf("executable is larger than %d bytes", maxExecutableSize)
+	}
 	return nil
 }
 
-func ApplyUpdate() error {
-	var cmd *exec.Cmd
+// Replaces the running executable with newExecutable, keeping the current one as <executable>.previous
+func ApplyUpdate(newExecutable string) error {
+	executable, err := executablePath()
+	if err != nil {
+		return err
+	}
+	previous := executable + previousSuffix
+
+	// Windows cannot overwrite a running executable but can rename it
 	if runtime.GOOS == "windows" {
-		cmd = exec.Command("cmd", "/C", "move /Y nodevin_new nodevin.exe")
-	} else {
-		cmd = exec.Command("sh", "-c", "mv nodevin_new nodevin && chmod +x nodevin")
+		os.Remove(previous)
+		if err := os.Rename(executable, previous); err != nil {
+			return fmt.Errorf("failed to keep previous executable: %w", err)
+		}
+		if err := os.Rename(newExecutable, executable); err != nil {
+			os.Rename(previous, executable)
+			return fmt.Errorf("failed to replace executable: %w", err)
+		}
+		return nil
+	}
+
+	if err := copyFileAtomic(executable, previous); err != nil {
+		return fmt.Errorf("failed to keep previous executable: %w", err)
+	}
+	if err := os.Rename(newExecutable, executable); err != nil {
+		return fmt.Errorf("failed to replace executable: %w", err)
+	}
+	return nil
+}
+
+// Swaps the running executable with the one kept by the last update, so a second rollback undoes the first
+func Rollback() error {
+	executable, err := executablePath()
+	if err != nil {
+		return err
+	}
+	previous := executable + previousSuffix
+
+	if _, err := os.Stat(previous); os.IsNotExist(err) {
+		return fmt.Errorf("no previous executable found at %s", previous)
+	} else if err != nil {
+		return err
+	}
+
+	swap := executable + ".rollback"
+
+	if runtime.GOOS == "windows" {
+		os.Remove(swap)
+		if err := os.Rename(executable, swap); err != nil {
+			return fmt.Errorf("failed to move current executable: %w", err)
+		}
+	} else if err := copyFileAtomic(executable, swap); err != nil {
+		return fmt.Errorf("failed to keep current executable: %w", err)
+	}
+
+	if err := os.Rename(previous, executable); err != nil {
+		if runtime.GOOS == "windows" {
+			os.Rename(swap, executable)
+		} else {
+			os.Remove(swap)
+		}
+		return fmt.Errorf("failed to restore previous executable: %w", err)
+	}
+
+	return os.Rename(swap, previous)
+}
+
+// Returns the path of the running executable with symlinks resolved
+func executablePath() (string, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("failed to find nodevin executable: %w", err)
+	}
+	resolved, err := filepath.EvalSymlinks(executable)
+	if err != nil {
+		return "", fmt.Errorf("failed to find nodevin executable: %w", err)
+	}
+	return resolved, nil
+}
+
+// Copies source to destination through a temporary file, so destination is never partially written
+func copyFileAtomic(source string, destination string) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.CreateTemp(filepath.Dir(destination), ".nodevin-copy-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(out.Name())
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(out.Name(), 0755); err != nil {
+		return err
+	}
+
+	return os.Rename(out.Name(), destination)
+}
+
+func httpGet(url string, timeout time.Duration) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "nodevin/"+version.Version)
+
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("failed to fetch %s: %s", url, resp.Status)
 	}
-	return cmd.Run()
+	return resp, nil
 }
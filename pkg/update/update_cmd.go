@@ -19,7 +19,12 @@
 package update
 
 import (
+	"fmt"
+	"os"
+
 	"github.com/fiftysixcrypto/nodevin/internal/logger"
+	"github.com/fiftysixcrypto/nodevin/internal/utils"
+	"github.com/fiftysixcrypto/nodevin/internal/version"
 	"github.com/spf13/cobra"
 )
 
@@ -27,48 +32,71 @@ var (
 	UpdateCmd = updateCmd
 )
 
+var (
+	checkOnly bool
+	rollback  bool
+)
+
 var updateCmd = &cobra.Command{
-	Use:   "update [target]",
+	Use:   "update",
 	Short: "Update Nodevin software or Docker images",
-	Args:  cobra.MaximumNArgs(1),
+	Args:  cobra.NoArgs,
 	Run: func(cmd *cobra.Command, args []string) {
-		if len(args) == 0 {
-			// Update Nodevin software
-			CommandCheckForUpdatesWorkflow()
-		} else if args[0] == "docker" {
-			// Update Docker images
-			CommandCheckAndUpdateDockerImagesWorkflow()
-		} else {
-			logger.LogError("Invalid target specified. Use 'nodevin update' or 'nodevin update docker'.")
+		if rollback {
+			CommandRollbackWorkflow()
+			return
 		}
+
+		// Update Nodevin software
+		CommandCheckForUpdatesWorkflow(checkOnly)
 	},
 }
 
-func CommandCheckForUpdatesWorkflow() {
+func CommandCheckForUpdatesWorkflow(checkOnly bool) {
 	logger.LogInfo("Checking for nodevin updates...")
 
-	updateNeeded, err := CheckForUpdates()
+	release, updateAvailable, err := CheckForUpdates()
 	if err != nil {
 		logger.LogError("Failed to check for updates: " + err.Error())
 		return
 	}
-	if updateNeeded {
-		logger.LogInfo("Update downloaded. Applying update...")
-		if err := ApplyUpdate(); err != nil {
-			logger.LogError("Failed to apply update: " + err.Error())
-			return
-		}
-		logger.LogInfo("Update applied successfully. Please restart the application.")
-	} else {
-		logger.LogInfo("nodevin is up to date.")
+	if !updateAvailable {
+		logger.LogInfo(fmt.Sprintf("nodevin is up to date (v%s).", version.Version))
+		return
 	}
+
+	logger.LogInfo(fmt.Sprintf("New version available: %s (running v%s)", release.TagName, version.Version))
+	if checkOnly {
+		logger.LogInfo(fmt.Sprintf("Run `%s update` to install it.", utils.GetNodevinExecutable()))
+		return
+	}
+
+	logger.LogInfo("Downloading and verifying " + assetName(release.TagName) + "...")
+	newExecutable, err := DownloadUpdate(release)
+	if err != nil {
+		logger.LogError("Failed to download update: " + err.Error())
+		return
+	}
+
+	logger.LogInfo("Update verified. Applying update...")
+	if err := ApplyUpdate(newExecutable); err != nil {
+		os.Remove(newExecutable)
+		logger.LogError("Failed to apply update: " + err.Error())
+		return
+	}
+	logger.LogInfo(fmt.Sprintf("Updated nodevin to %s. Run `%s update --rollback` to return to v%s.", release.TagName, utils.GetNodevinExecutable(), version.Version))
 }
 
-func CommandCheckAndUpdateDockerImagesWorkflow() {
-	logger.LogInfo("Checking for Docker image updates...")
-	if err := CheckAndUpdateDockerImages(); err != nil {
-		logger.LogError("Failed to check/update Docker images: " + err.Error())
+func CommandRollbackWorkflow() {
+	if err := Rollback(); err != nil {
+		logger.LogError("Failed to roll back: " + err.Error())
 		return
 	}
-	logger.LogInfo("Image updates complete.")
+	logger.LogInfo("Restored the previous nodevin executable.")
+}
+
+func init() {
+	updateCmd.Flags().BoolVar(&checkOnly, "check", false, "Only report whether a newer nodevin release is available")
+	updateCmd.Flags().BoolVar(&rollback, "rollback", false, "Restore the nodevin executable replaced by the last update")
+	updateCmd.MarkFlagsMutuallyExclusive("check", "rollback")
 }